@@ -0,0 +1,121 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/rwx-research/captain-cli/internal/cli"
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/targetedretries"
+)
+
+type bisectArgs struct {
+	results string
+	test    string
+}
+
+func configureBisectCmd(rootCmd *cobra.Command, cliArgs *CliArgs) {
+	var bArgs bisectArgs
+
+	bisectCmd := &cobra.Command{
+		Use:   "bisect [flags] --suite-id=<suite> --results=<path> --test=<test> <args>",
+		Short: "Finds the tests that a failing test depends on",
+		Long: "'captain bisect' finds the smallest set of tests that need to run before a test in order for it to " +
+			"fail. It repeatedly runs subsets of the tests that ran before the failing test, using the retry command " +
+			"template to select individual tests or the partition command template to select test files.\n\n" +
+			"Bisecting assumes that the test framework runs the tests in the given order, with the failing test last. " +
+			"Please disable any random ordering (e.g. RSpec's --order defined or pytest -p no:randomly) in the " +
+			"commands. Captain warns when the test results show that the tests ran in a different order.",
+		Example: "" +
+			"  captain bisect your-project-rspec --results tmp/captain.json --test './spec/a_spec.rb[1:2]' \\\n" +
+			"    --test-results tmp/rspec.json --retry-command 'bundle exec rspec --format json " +
+			"--out tmp/rspec.json {{ tests }}'",
+		PreRunE: initCLIService(cliArgs, noProviderRequired),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			err := func() error {
+				cfg, err := getConfig(cmd)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+
+				captain, err := cli.GetService(cmd)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+
+				bisectConfig := cli.BisectConfig{
+					Args:                     cliArgs.RootCliArgs.positionalArgs,
+					ResultsPath:              bArgs.results,
+					SubstitutionsByFramework: targetedretries.SubstitutionsByFramework,
+					SuiteID:                  cliArgs.RootCliArgs.suiteID,
+					Test:                     bArgs.test,
+				}
+
+				if suiteConfig, ok := cfg.TestSuites[cliArgs.RootCliArgs.suiteID]; ok {
+					bisectConfig.PartitionCommandTemplate = suiteConfig.Partition.Command
+					bisectConfig.PartitionDelimiter = suiteConfig.Partition.Delimiter
+					bisectConfig.Quiet = suiteConfig.Output.Quiet
+					bisectConfig.RetryCommandTemplate = suiteConfig.Retries.Command
+					bisectConfig.TestResultsFileGlob = expandTestResultsPath(suiteConfig.Results.Path)
+				}
+
+				err = captain.Bisect(cmd.Context(), bisectConfig)
+				if _, ok := errors.AsConfigurationError(err); !ok {
+					cmd.SilenceUsage = true
+				}
+
+				return errors.WithStack(err)
+			}()
+			if err != nil {
+				return errors.WithDecoration(err)
+			}
+			return nil
+		},
+	}
+
+	bisectCmd.Flags().StringVar(
+		&bArgs.results,
+		"results",
+		"",
+		"the RWX v1 test results of the run in which the test failed (e.g. as written by the 'rwx-v1-json' reporter)",
+	)
+
+	bisectCmd.Flags().StringVar(&bArgs.test, "test", "", "the ID or name of the failing test")
+
+	bisectCmd.Flags().StringVar(
+		&cliArgs.testResults,
+		"test-results",
+		"",
+		"a filepath to the test results written by every bisection step - supports globs for multiple result files",
+	)
+
+	bisectCmd.Flags().StringVar(
+		&cliArgs.retryCommandTemplate,
+		"retry-command",
+		"",
+		"the command that runs a subset of your tests, used to bisect individual tests",
+	)
+
+	bisectCmd.Flags().StringVar(
+		&cliArgs.partitionCommandTemplate,
+		"partition-command",
+		"",
+		"the command that runs a subset of your test files, used to bisect test files when there is no retry command",
+	)
+
+	bisectCmd.Flags().StringVar(
+		&cliArgs.partitionDelimiter,
+		"partition-delimiter",
+		" ",
+		"The delimiter used to separate test files in the partition command.",
+	)
+
+	bisectCmd.Flags().BoolVarP(
+		&cliArgs.quiet,
+		"quiet",
+		"q",
+		false,
+		"disables most default output",
+	)
+
+	rootCmd.AddCommand(bisectCmd)
+}
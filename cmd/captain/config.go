@@ -63,7 +63,10 @@ const (
 	captainDirectory    = ".captain"
 	configFileName      = "config"
 	flakesFileName      = "flakes.yaml"
+	partitionsFileName  = "partitions.yaml"
 	quarantinesFileName = "quarantines.yaml"
+	resultsFileName     = "results.json"
+	testTimingsFileName = "test-timings.yaml"
 	timingsFileName     = "timings.yaml"
 )
 
@@ -186,6 +189,9 @@ func InitConfig(cmd *cobra.Command, cliArgs CliArgs) (cfg Config, err error) {
 		}
 	}
 
+	// Flags from the config file apply to every test suite, only the ones from the command line are checked
+	singleSuiteFlags := changedSingleSuiteFlags(cmd)
+
 	for name, value := range cfg.Flags {
 		if err := cmd.Flags().Set(name, fmt.Sprintf("%v", value)); err != nil {
 			return cfg, errors.Wrap(err, fmt.Sprintf("unable to set flag %q", name))
@@ -196,17 +202,36 @@ func InitConfig(cmd *cobra.Command, cliArgs CliArgs) (cfg Config, err error) {
 		return cfg, errors.Wrap(err, "unable to parse environment variables")
 	}
 
-	if _, ok := cfg.TestSuites[cliArgs.RootCliArgs.suiteID]; !ok {
-		if cfg.TestSuites == nil {
-			cfg.TestSuites = make(map[string]cli.SuiteConfig)
-		}
+	suiteIDs, err := selectedSuiteIDs(cfg, cliArgs)
+	if err != nil {
+		return cfg, err
+	}
 
-		cfg.TestSuites[cliArgs.RootCliArgs.suiteID] = cli.SuiteConfig{}
+	if len(suiteIDs) > 1 && len(singleSuiteFlags) > 0 {
+		return cfg, errors.NewConfigurationError(
+			"Unsupported flags for several test suites",
+			fmt.Sprintf(
+				"%s configure a single test suite and can't be applied to every test suite selected by --all or --suites.",
+				strings.Join(singleSuiteFlags, ", "),
+			),
+			"Please set these options per test suite in the 'test-suites' section of the config file.",
+		)
 	}
 
 	cfg = bindRootCmdFlags(cfg, cliArgs.RootCliArgs)
-	cfg = bindFrameworkFlags(cfg, cliArgs.frameworkParams, cliArgs.RootCliArgs.suiteID)
-	cfg = bindRunCmdFlags(cfg, cliArgs, cmd)
+
+	for _, suiteID := range suiteIDs {
+		if _, ok := cfg.TestSuites[suiteID]; !ok {
+			if cfg.TestSuites == nil {
+				cfg.TestSuites = make(map[string]cli.SuiteConfig)
+			}
+
+			cfg.TestSuites[suiteID] = cli.SuiteConfig{}
+		}
+
+		cfg = bindFrameworkFlags(cfg, cliArgs.frameworkParams, suiteID)
+		cfg = bindRunCmdFlags(cfg, cliArgs, cmd, suiteID)
+	}
 
 	if err = setConfigContext(cmd, cfg); err != nil {
 		return cfg, errors.WithStack(err)
@@ -214,3 +239,54 @@ func InitConfig(cmd *cobra.Command, cliArgs CliArgs) (cfg Config, err error) {
 
 	return cfg, nil
 }
+
+// singleSuiteFlags describe how a single test suite runs, so they can't be shared by several test suites
+var singleSuiteFlags = []string{
+	"command",
+	"test-results",
+	"language",
+	"framework",
+	"reporter",
+	"retries",
+	"flaky-retries",
+	"quarantined-test-retries",
+	"max-tests-to-retry",
+	"retry-command",
+	"pre-retry",
+	"post-retry",
+	"retry-batch-size",
+	"retry-concurrency",
+	"repeat",
+	"min-tests",
+	"max-test-drop-percent",
+	"intermediate-artifacts-path",
+	"additional-artifact-paths",
+	"parallel",
+	"partition-command",
+	"partition-delimiter",
+	"partition-estimate-by-file-size",
+	"partition-globs",
+	"partition-index",
+	"partition-packages",
+	"partition-round-robin",
+	"partition-split-tests",
+	"partition-sticky",
+	"partition-sticky-threshold",
+	"partition-strategy",
+	"partition-timings-from",
+	"partition-total",
+	"partition-trim-prefix",
+	"partition-weights",
+}
+
+// changedSingleSuiteFlags returns the single suite flags that were set on the command line
+func changedSingleSuiteFlags(cmd *cobra.Command) []string {
+	changed := make([]string, 0)
+	for _, name := range singleSuiteFlags {
+		if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+			changed = append(changed, "--"+name)
+		}
+	}
+
+	return changed
+}
@@ -2,6 +2,7 @@ package main_test
 
 import (
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 
@@ -173,4 +174,64 @@ var _ = Describe("InitConfig", func() {
 			Expect(cfg.ProvidersEnv.Generic.CommitMessage).To(Equal("print env"))
 		})
 	})
+
+	Context("when selecting several test suites", func() {
+		var configFilePath string
+
+		BeforeEach(func() {
+			configFilePath = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+			Expect(os.WriteFile(configFilePath, []byte(
+				"test-suites:\n"+
+					"  jest:\n"+
+					"    command: npx jest\n"+
+					"  rspec:\n"+
+					"    command: bundle exec rspec\n"+
+					"    retries:\n"+
+					"      attempts: 2\n",
+			), 0o600)).To(Succeed())
+
+			Expect(captain.AddFlags(cmd, &cliArgs)).To(Succeed())
+			Expect(cmd.PersistentFlags().Set("config-file", configFilePath)).To(Succeed())
+		})
+
+		It("binds the command line flags to every suite with --all", func() {
+			Expect(cmd.Flags().Set("all", "true")).To(Succeed())
+			Expect(cmd.PersistentFlags().Set("quiet", "true")).To(Succeed())
+
+			cfg, err := captain.InitConfig(cmd, cliArgs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg.TestSuites).To(HaveLen(2))
+			Expect(cfg.TestSuites["jest"].Command).To(Equal("npx jest"))
+			Expect(cfg.TestSuites["jest"].Output.Quiet).To(BeTrue())
+			Expect(cfg.TestSuites["rspec"].Output.Quiet).To(BeTrue())
+			Expect(cfg.TestSuites["rspec"].Retries.Attempts).To(Equal(2))
+		})
+
+		It("rejects flags that configure a single suite", func() {
+			Expect(cmd.Flags().Set("all", "true")).To(Succeed())
+			Expect(cmd.Flags().Set("retries", "3")).To(Succeed())
+			Expect(cmd.Flags().Set("test-results", "results.json")).To(Succeed())
+
+			_, err := captain.InitConfig(cmd, cliArgs)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unsupported flags for several test suites"))
+		})
+
+		It("allows flags that configure a single suite when only one suite is selected", func() {
+			Expect(cmd.Flags().Set("suites", "rspec")).To(Succeed())
+			Expect(cmd.Flags().Set("retries", "3")).To(Succeed())
+
+			cfg, err := captain.InitConfig(cmd, cliArgs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg.TestSuites["rspec"].Retries.Attempts).To(Equal(3))
+		})
+
+		It("rejects unknown suites", func() {
+			Expect(cmd.Flags().Set("suites", "jest,cypress")).To(Succeed())
+
+			_, err := captain.InitConfig(cmd, cliArgs)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unknown test suite"))
+		})
+	})
 })
@@ -90,57 +90,9 @@ func initCliServiceWithConfig(
 	cmd *cobra.Command, cfg Config, suiteID string, providerValidator func(providers.Provider) error,
 ) error {
 	err := func() error {
-		if suiteID == "" {
-			return errors.NewConfigurationError("Invalid suite-id", "The suite ID is empty.", "")
-		}
-
-		if invalidSuiteIDRegexp.Match([]byte(suiteID)) {
-			return errors.NewConfigurationError(
-				"Invalid suite-id",
-				"A suite ID can only contain alphanumeric characters, `_` and `-`.",
-				"Please make sure that the ID doesn't contain any special characters.",
-			)
-		}
-
-		logger := logging.NewProductionLogger()
-		if cfg.Output.Debug {
-			logger = logging.NewDebugLogger()
-		}
-
-		apiClient, err := makeAPIClient(cfg, providerValidator, logger, suiteID)
+		captain, err := newCLIService(cfg, suiteID, providerValidator)
 		if err != nil {
-			return errors.Wrap(err, "unable to create API client")
-		}
-
-		recipes, err := getRecipes()
-		if err != nil {
-			return errors.Wrap(err, "unable to retrieve test identity recipes")
-		}
-
-		var parseConfig parsing.Config
-		if suiteConfig, ok := cfg.TestSuites[suiteID]; ok {
-			parseConfig = parsing.Config{
-				ProvidedFrameworkKind:     suiteConfig.Results.Framework,
-				ProvidedFrameworkLanguage: suiteConfig.Results.Language,
-				FailOnDuplicateTestID:     suiteConfig.FailOnDuplicateTestID,
-				MutuallyExclusiveParsers:  mutuallyExclusiveParsers,
-				FrameworkParsers:          frameworkParsers,
-				GenericParsers:            genericParsers,
-				Logger:                    logger,
-				IdentityRecipes:           recipes,
-			}
-		}
-
-		if err := parseConfig.Validate(); err != nil {
-			return errors.Wrap(err, "invalid parser config")
-		}
-
-		captain := cli.Service{
-			API:         apiClient,
-			Log:         logger,
-			FileSystem:  fs.Local{},
-			TaskRunner:  exec.Local{},
-			ParseConfig: parseConfig,
+			return err
 		}
 
 		if err := cli.SetService(cmd, captain); err != nil {
@@ -155,6 +107,67 @@ func initCliServiceWithConfig(
 	return nil
 }
 
+// newCLIService constructs the `captain` CLI service for a single test suite.
+func newCLIService(cfg Config, suiteID string, providerValidator func(providers.Provider) error) (cli.Service, error) {
+	if suiteID == "" {
+		return cli.Service{}, errors.NewConfigurationError("Invalid suite-id", "The suite ID is empty.", "")
+	}
+
+	if invalidSuiteIDRegexp.Match([]byte(suiteID)) {
+		return cli.Service{}, errors.NewConfigurationError(
+			"Invalid suite-id",
+			"A suite ID can only contain alphanumeric characters, `_` and `-`.",
+			"Please make sure that the ID doesn't contain any special characters.",
+		)
+	}
+
+	logger := newLogger(cfg)
+
+	apiClient, err := makeAPIClient(cfg, providerValidator, logger, suiteID)
+	if err != nil {
+		return cli.Service{}, errors.Wrap(err, "unable to create API client")
+	}
+
+	recipes, err := getRecipes()
+	if err != nil {
+		return cli.Service{}, errors.Wrap(err, "unable to retrieve test identity recipes")
+	}
+
+	var parseConfig parsing.Config
+	if suiteConfig, ok := cfg.TestSuites[suiteID]; ok {
+		parseConfig = parsing.Config{
+			ProvidedFrameworkKind:     suiteConfig.Results.Framework,
+			ProvidedFrameworkLanguage: suiteConfig.Results.Language,
+			FailOnDuplicateTestID:     suiteConfig.FailOnDuplicateTestID,
+			MutuallyExclusiveParsers:  mutuallyExclusiveParsers,
+			FrameworkParsers:          frameworkParsers,
+			GenericParsers:            genericParsers,
+			Logger:                    logger,
+			IdentityRecipes:           recipes,
+		}
+	}
+
+	if err := parseConfig.Validate(); err != nil {
+		return cli.Service{}, errors.Wrap(err, "invalid parser config")
+	}
+
+	return cli.Service{
+		API:         apiClient,
+		Log:         logger,
+		FileSystem:  fs.Local{},
+		TaskRunner:  exec.Local{},
+		ParseConfig: parseConfig,
+	}, nil
+}
+
+func newLogger(cfg Config) *zap.SugaredLogger {
+	if cfg.Output.Debug {
+		return logging.NewDebugLogger()
+	}
+
+	return logging.NewProductionLogger()
+}
+
 // unsafeInitParsingOnly initializes an incomplete `captain` CLI service. This service is sufficient for running
 // `captain parse`, but not for any other operation.
 // It is considered unsafe since the captain CLI service might still expect a configured API at one point.
@@ -284,5 +297,33 @@ func makeAPIClient(
 		)
 	}
 
-	return wrapError(local.NewClient(fs.Local{}, flakesFilePath, quarantinesFilePath, timingsFilePath))
+	// The stored test results, test timings & partition assignments are only written once a run updates them, so
+	// there is nothing to create upfront
+	resultsFilePath := filepath.Join(filepath.Dir(timingsFilePath), resultsFileName)
+	testTimingsFilePath := filepath.Join(filepath.Dir(timingsFilePath), testTimingsFileName)
+	partitionsFilePath := filepath.Join(filepath.Dir(timingsFilePath), partitionsFileName)
+
+	timingHistory := local.TimingHistory{
+		Window:        local.DefaultTimingWindow,
+		MaxUnseenRuns: local.DefaultTimingMaxUnseenRuns,
+	}
+	if suiteConfig, ok := cfg.TestSuites[suiteID]; ok {
+		if suiteConfig.Timings.Window > 0 {
+			timingHistory.Window = suiteConfig.Timings.Window
+		}
+		if suiteConfig.Timings.MaxUnseenRuns != nil {
+			timingHistory.MaxUnseenRuns = *suiteConfig.Timings.MaxUnseenRuns
+		}
+	}
+
+	return wrapError(local.NewClient(
+		fs.Local{},
+		flakesFilePath,
+		quarantinesFilePath,
+		timingsFilePath,
+		resultsFilePath,
+		testTimingsFilePath,
+		partitionsFilePath,
+		timingHistory,
+	))
 }
@@ -52,6 +52,10 @@ func main() {
 		os.Exit(1)
 	}
 
+	configureQueueCmd(rootCmd, &cliArgs)
+	configureBisectCmd(rootCmd, &cliArgs)
+	configureRerunCmd(rootCmd, &cliArgs)
+
 	mergeCmd := createMergeCommand(&cliArgs)
 	rootCmd.AddCommand(mergeCmd)
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -12,10 +13,20 @@ import (
 )
 
 type partitionArgs struct {
-	nodes      config.PartitionNodes
-	delimiter  string
-	roundRobin bool
-	trimPrefix string
+	nodes        config.PartitionNodes
+	delimiter    string
+	dryRun       bool
+	dryRunFormat string
+	estimateSize bool
+	explain      bool
+	packages     bool
+	roundRobin   bool
+	sticky       bool
+	stickyLimit  float64
+	strategy     string
+	timingsFrom  []string
+	trimPrefix   string
+	weights      string
 }
 
 func configurePartitionCmd(rootCmd *cobra.Command, cliArgs *CliArgs) error {
@@ -70,16 +81,16 @@ func configurePartitionCmd(rootCmd *cobra.Command, cliArgs *CliArgs) error {
 					pArgs.nodes.Total = provider.PartitionNodes.Total
 				}
 
-				return initCliServiceWithConfig(cmd, cfg, cliArgs.RootCliArgs.suiteID, func(p providers.Provider) error {
-					if p.CommitSha == "" {
-						return errors.NewConfigurationError(
-							"Missing commit SHA",
-							"Captain requires a commit SHA in order to track test runs correctly.",
-							"You can specify the SHA by using the --sha flag or the CAPTAIN_SHA environment variable",
-						)
-					}
-					return nil
-				})
+				if pArgs.weights == "" {
+					pArgs.weights = provider.PartitionWeights
+				}
+
+				pArgs.nodes.Weights, err = config.ParsePartitionWeights(pArgs.weights)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+
+				return initCliServiceWithConfig(cmd, cfg, cliArgs.RootCliArgs.suiteID, requireCommitSha)
 			}()
 			if err != nil {
 				return errors.WithDecoration(err)
@@ -94,12 +105,21 @@ func configurePartitionCmd(rootCmd *cobra.Command, cliArgs *CliArgs) error {
 				return errors.WithStack(err)
 			}
 			err = captain.Partition(cmd.Context(), cli.PartitionConfig{
-				SuiteID:        cliArgs.RootCliArgs.suiteID,
-				TestFilePaths:  args,
-				PartitionNodes: pArgs.nodes,
-				Delimiter:      pArgs.delimiter,
-				RoundRobin:     pArgs.roundRobin,
-				TrimPrefix:     pArgs.trimPrefix,
+				SuiteID:            cliArgs.RootCliArgs.suiteID,
+				TestFilePaths:      args,
+				PartitionNodes:     pArgs.nodes,
+				Delimiter:          pArgs.delimiter,
+				DryRun:             pArgs.dryRun,
+				DryRunFormat:       pArgs.dryRunFormat,
+				EstimateByFileSize: pArgs.estimateSize,
+				Explain:            pArgs.explain,
+				Packages:           pArgs.packages,
+				RoundRobin:         pArgs.roundRobin,
+				Sticky:             pArgs.sticky,
+				StickyThreshold:    &pArgs.stickyLimit,
+				Strategy:           cli.PartitionStrategy(pArgs.strategy),
+				TimingsFrom:        pArgs.timingsFrom,
+				TrimPrefix:         pArgs.trimPrefix,
 			})
 			return errors.WithStack(err)
 		},
@@ -123,6 +143,53 @@ func configurePartitionCmd(rootCmd *cobra.Command, cliArgs *CliArgs) error {
 		"the delimiter used to separate partitioned files.\n"+
 			"It can also be set using the env var CAPTAIN_DELIMITER.")
 
+	partitionCmd.Flags().BoolVar(
+		&pArgs.dryRun,
+		"dry-run",
+		false,
+		"prints the test files of the partition together with their expected runtime instead of only the test files",
+	)
+
+	partitionCmd.Flags().StringVar(
+		&pArgs.dryRunFormat,
+		"dry-run-format",
+		"text",
+		"the format in which --dry-run prints the partition, either 'text' or 'json'",
+	)
+
+	partitionCmd.Flags().BoolVar(
+		&pArgs.estimateSize,
+		"estimate-by-file-size",
+		false,
+		"scales the estimated timings of test files without historical timings by their file size. By default, they're\n"+
+			"estimated at the median timing of the test files with timings",
+	)
+
+	partitionCmd.Flags().BoolVar(
+		&pArgs.explain,
+		"explain",
+		false,
+		"prints every partition as JSON together with its expected runtime and imbalance, e.g. to compare strategies",
+	)
+
+	partitionCmd.Flags().BoolVar(
+		&pArgs.packages,
+		"packages",
+		false,
+		"partitions Go packages instead of test files. The arguments are then package patterns like ./... and the\n"+
+			"partition consists of the import paths of the matching packages with tests, e.g. for 'go test'",
+	)
+
+	partitionCmd.Flags().StringVar(
+		&pArgs.strategy,
+		"strategy",
+		"",
+		fmt.Sprintf(
+			"the strategy used to assign test files to partitions, one of %v (default 'greedy')",
+			cli.PartitionStrategyNames(),
+		),
+	)
+
 	partitionCmd.Flags().BoolVar(
 		&pArgs.roundRobin,
 		"round-robin",
@@ -131,6 +198,39 @@ func configurePartitionCmd(rootCmd *cobra.Command, cliArgs *CliArgs) error {
 			" evenly balance the partitions.",
 	)
 
+	partitionCmd.Flags().BoolVar(
+		&pArgs.sticky,
+		"sticky",
+		false,
+		"keeps test files on the partition they were assigned to previously, so that caches of a partition stay warm.\n"+
+			"The partitions are only rebalanced once their imbalance goes over --sticky-threshold. Only works in OSS mode",
+	)
+
+	partitionCmd.Flags().Float64Var(
+		&pArgs.stickyLimit,
+		"sticky-threshold",
+		10,
+		"the percentage by which the slowest sticky partition may take longer than the average partition before the\n"+
+			"partitions are rebalanced",
+	)
+
+	partitionCmd.Flags().StringVar(
+		&pArgs.weights,
+		"weights",
+		"",
+		"the relative capacities of the partition nodes as a comma-separated list, e.g. 1,1,4 if the third node is\n"+
+			"four times as fast as the others. Every partition then takes a share of the runtime that is proportional\n"+
+			"to its weight. It can also be set using the env var CAPTAIN_PARTITION_WEIGHTS.",
+	)
+
+	partitionCmd.Flags().StringArrayVar(
+		&pArgs.timingsFrom,
+		"timings-from",
+		[]string{},
+		"a glob of test results files, e.g. JUnit XML artifacts of earlier runs, to read the test file timings from\n"+
+			"instead of Captain. It can be passed multiple times",
+	)
+
 	partitionCmd.Flags().StringVar(
 		&pArgs.trimPrefix,
 		"trim-prefix",
@@ -138,6 +238,159 @@ func configurePartitionCmd(rootCmd *cobra.Command, cliArgs *CliArgs) error {
 		"A prefix to trim from the beginning of local test file paths when comparing them to historical timing data.",
 	)
 
+	partitionCmd.AddCommand(newPartitionVerifyCmd(cliArgs))
 	rootCmd.AddCommand(partitionCmd)
 	return nil
 }
+
+type partitionVerifyArgs struct {
+	nodes        config.PartitionNodes
+	packages     bool
+	resultsGlobs []string
+	roundRobin   bool
+	strategy     string
+	timingsFrom  []string
+	trimPrefix   string
+	weights      string
+}
+
+// newPartitionVerifyCmd returns the "verify" sub-command of "partition"
+func newPartitionVerifyCmd(cliArgs *CliArgs) *cobra.Command {
+	var vArgs partitionVerifyArgs
+
+	partitionVerifyCmd := &cobra.Command{
+		Use:   "verify [flags] --suite-id=<suite> --total=<total> --results=<path> <args>",
+		Short: "Verifies that every test file of a partitioned test suite ran exactly once",
+		Long: "'captain partition verify' recalculates the partitions of a test suite and compares them with the test " +
+			"results of all partitions. It fails if any test file didn't run, e.g. because a partition was dropped, or " +
+			"if a test file ran on more than one partition. Test files without any tests count as not having run.\n\n" +
+			"The partitions are only recalculated correctly with the same options and timings as the partitioned run.",
+		Example: "" +
+			"  captain partition verify your-project-rspec --total 2 --results 'merged/*.json' spec/**/*_spec.rb",
+		Args: cobra.MinimumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			err := func() error {
+				if err := extractSuiteIDFromPositionalArgs(&cliArgs.RootCliArgs, args); err != nil {
+					return err
+				}
+
+				cfg, err := InitConfig(cmd, *cliArgs)
+				if err != nil {
+					return err
+				}
+
+				provider, err := cfg.ProvidersEnv.MakeProvider()
+				if err != nil {
+					return errors.Wrap(err, "failed to construct provider")
+				}
+
+				if vArgs.nodes.Total < 0 {
+					vArgs.nodes.Total = provider.PartitionNodes.Total
+				}
+
+				if vArgs.weights == "" {
+					vArgs.weights = provider.PartitionWeights
+				}
+
+				vArgs.nodes.Weights, err = config.ParsePartitionWeights(vArgs.weights)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+
+				return initCliServiceWithConfig(cmd, cfg, cliArgs.RootCliArgs.suiteID, requireCommitSha)
+			}()
+			if err != nil {
+				return errors.WithDecoration(err)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			captain, err := cli.GetService(cmd)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			err = captain.VerifyPartitions(cmd.Context(), cli.PartitionVerifyConfig{
+				PartitionConfig: cli.PartitionConfig{
+					SuiteID:        cliArgs.RootCliArgs.suiteID,
+					TestFilePaths:  cliArgs.RootCliArgs.positionalArgs,
+					PartitionNodes: vArgs.nodes,
+					Packages:       vArgs.packages,
+					RoundRobin:     vArgs.roundRobin,
+					Strategy:       cli.PartitionStrategy(vArgs.strategy),
+					TimingsFrom:    vArgs.timingsFrom,
+					TrimPrefix:     vArgs.trimPrefix,
+				},
+				ResultsGlobs: vArgs.resultsGlobs,
+			})
+			return errors.WithStack(err)
+		},
+	}
+
+	partitionVerifyCmd.Flags().IntVar(&vArgs.nodes.Total, "total", -1, "the total number of partitions")
+
+	partitionVerifyCmd.Flags().StringArrayVar(
+		&vArgs.resultsGlobs,
+		"results",
+		[]string{},
+		"a glob of the test results files of the partitions. It can be passed multiple times",
+	)
+
+	partitionVerifyCmd.Flags().BoolVar(
+		&vArgs.packages,
+		"packages",
+		false,
+		"verifies the partitions of Go packages instead of test files",
+	)
+
+	partitionVerifyCmd.Flags().BoolVar(
+		&vArgs.roundRobin,
+		"round-robin",
+		false,
+		"whether the test files were naively round robined across partitions",
+	)
+
+	partitionVerifyCmd.Flags().StringVar(
+		&vArgs.strategy,
+		"strategy",
+		"",
+		fmt.Sprintf("the strategy that the partitions were calculated with, one of %v", cli.PartitionStrategyNames()),
+	)
+
+	partitionVerifyCmd.Flags().StringArrayVar(
+		&vArgs.timingsFrom,
+		"timings-from",
+		[]string{},
+		"a glob of test results files that the test file timings were read from instead of Captain",
+	)
+
+	partitionVerifyCmd.Flags().StringVar(
+		&vArgs.trimPrefix,
+		"trim-prefix",
+		"",
+		"A prefix to trim from the beginning of local test file paths when comparing them to the test results.",
+	)
+
+	partitionVerifyCmd.Flags().StringVar(
+		&vArgs.weights,
+		"weights",
+		"",
+		"the comma-separated weights of the partition nodes that the partitions were calculated with",
+	)
+
+	addShaFlag(partitionVerifyCmd, &cliArgs.GenericProvider.Sha)
+
+	return partitionVerifyCmd
+}
+
+// requireCommitSha validates that the provider knows the commit SHA, which the timings of test files are looked up by
+func requireCommitSha(p providers.Provider) error {
+	if p.CommitSha == "" {
+		return errors.NewConfigurationError(
+			"Missing commit SHA",
+			"Captain requires a commit SHA in order to track test runs correctly.",
+			"You can specify the SHA by using the --sha flag or the CAPTAIN_SHA environment variable",
+		)
+	}
+	return nil
+}
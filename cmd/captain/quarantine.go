@@ -87,7 +87,7 @@ func AddQuarantineFlags(rootCmd *cobra.Command, cliArgs *CliArgs) {
 					}
 				}
 
-				err = captain.RunSuite(cmd.Context(), runConfig)
+				err = ignoreShortCircuit(captain, captain.RunSuite(cmd.Context(), runConfig))
 				if _, ok := errors.AsConfigurationError(err); !ok {
 					cmd.SilenceUsage = true
 				}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/rwx-research/captain-cli/internal/cli"
+	"github.com/rwx-research/captain-cli/internal/errors"
+)
+
+type queueArgs struct {
+	address    string
+	batchSize  int
+	trimPrefix string
+}
+
+func configureQueueCmd(rootCmd *cobra.Command, cliArgs *CliArgs) {
+	var qArgs queueArgs
+
+	// queueServeCmd is the "serve" sub-command of "queue".
+	queueServeCmd := &cobra.Command{
+		Use:   "serve [flags] --suite-id=<suite> <args>",
+		Short: "Hands out test files in batches to nodes that pull them with 'captain run --queue-url'",
+		Long: "'captain queue serve' runs a coordinator that hands out the test files of a test suite, slowest first, " +
+			"to whichever node asks for more work next. Unlike a static partitioning, nodes that are faster than " +
+			"expected take over test files from slower ones.\n\n" +
+			"Every node runs the test files of its batches with the partition command and merges their test results. " +
+			"Batches are not acknowledged: a test file counts as done once it's handed out, so the test files of a " +
+			"node that dies in the middle of a batch are not run by any other node.\n\n" +
+			"The coordinator keeps running until it's interrupted.",
+		Example: "" +
+			"  captain queue serve your-project-rspec --address 0.0.0.0:8123 spec/**/*_spec.rb\n" +
+			"  captain run your-project-rspec --queue-url http://coordinator:8123 " +
+			"--partition-command \"bundle exec rspec {{ testFiles }}\"",
+		Args:    cobra.MinimumNArgs(1),
+		PreRunE: initCLIService(cliArgs, requireCommitSha),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			captain, err := cli.GetService(cmd)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			err = captain.ServeQueue(cmd.Context(), cli.QueueConfig{
+				SuiteID:       cliArgs.RootCliArgs.suiteID,
+				TestFilePaths: cliArgs.RootCliArgs.positionalArgs,
+				TrimPrefix:    qArgs.trimPrefix,
+				Address:       qArgs.address,
+				BatchSize:     qArgs.batchSize,
+			})
+			return errors.WithStack(err)
+		},
+	}
+
+	queueServeCmd.Flags().StringVar(
+		&qArgs.address,
+		"address",
+		"127.0.0.1:8123",
+		"the address to listen on. Use 0.0.0.0 as the host to accept connections from other machines",
+	)
+
+	queueServeCmd.Flags().IntVar(
+		&qArgs.batchSize,
+		"batch-size",
+		1,
+		"the number of test files that a node receives at once",
+	)
+
+	queueServeCmd.Flags().StringVar(
+		&qArgs.trimPrefix,
+		"trim-prefix",
+		"",
+		"A prefix to trim from the beginning of local test file paths when comparing them to historical timing data.",
+	)
+
+	addShaFlag(queueServeCmd, &cliArgs.GenericProvider.Sha)
+
+	// queueCmd represents the "queue" sub-command itself
+	queueCmd := &cobra.Command{
+		Use:   "queue",
+		Short: "Distributes test files dynamically across nodes",
+	}
+
+	queueCmd.AddCommand(queueServeCmd)
+	rootCmd.AddCommand(queueCmd)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/rwx-research/captain-cli/internal/cli"
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/targetedretries"
+)
+
+func configureRerunCmd(rootCmd *cobra.Command, cliArgs *CliArgs) {
+	rerunCmd := &cobra.Command{
+		Use:   "rerun [flags] <suite>",
+		Short: "Reruns the tests that failed in the most recent run",
+		Long: "'captain rerun' reruns the tests that failed in the most recent run of a suite, using the suite's retry " +
+			"command. It reads the test results stored under '.captain/<suite>/' by 'captain run --update-stored-results' " +
+			"and updates them with the outcome of the rerun, so that every rerun only runs the tests that are still " +
+			"failing.",
+		Example: "" +
+			"  captain run your-project-rspec --update-stored-results\n" +
+			"  captain rerun your-project-rspec",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: initCLIService(cliArgs, noProviderRequired),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			err := func() error {
+				cfg, err := getConfig(cmd)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+
+				captain, err := cli.GetService(cmd)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+
+				rerunConfig := cli.RerunConfig{
+					SubstitutionsByFramework: targetedretries.SubstitutionsByFramework,
+					SuiteID:                  cliArgs.RootCliArgs.suiteID,
+				}
+
+				if suiteConfig, ok := cfg.TestSuites[cliArgs.RootCliArgs.suiteID]; ok {
+					rerunConfig.AttemptTimeout = suiteConfig.Retries.AttemptTimeout
+					rerunConfig.FailOnMisconfiguredRetry = suiteConfig.Retries.FailOnMisconfiguration
+					rerunConfig.PostRetryCommands = suiteConfig.Retries.PostRetryCommands
+					rerunConfig.PreRetryCommands = suiteConfig.Retries.PreRetryCommands
+					rerunConfig.Quiet = suiteConfig.Output.Quiet
+					rerunConfig.RetryBatchSize = suiteConfig.Retries.BatchSize
+					rerunConfig.RetryCommandTemplate = suiteConfig.Retries.Command
+					rerunConfig.RetryConcurrency = suiteConfig.Retries.Concurrency
+					rerunConfig.TerminationGracePeriod = suiteConfig.TerminationGracePeriod
+					rerunConfig.TestResultsFileGlob = expandTestResultsPath(suiteConfig.Results.Path)
+				}
+
+				err = captain.Rerun(cmd.Context(), rerunConfig)
+				if _, ok := errors.AsConfigurationError(err); !ok {
+					cmd.SilenceUsage = true
+				}
+
+				return errors.WithStack(err)
+			}()
+			if err != nil {
+				return errors.WithDecoration(err)
+			}
+			return nil
+		},
+	}
+
+	rerunCmd.Flags().StringVar(
+		&cliArgs.testResults,
+		"test-results",
+		"",
+		"a filepath to the test results written by the retry command - supports globs for multiple result files",
+	)
+
+	rerunCmd.Flags().StringVar(
+		&cliArgs.retryCommandTemplate,
+		"retry-command",
+		"",
+		"the command that will be run to execute the failed tests, defaults to the suite's 'retries.command'",
+	)
+
+	rerunCmd.Flags().BoolVarP(
+		&cliArgs.quiet,
+		"quiet",
+		"q",
+		false,
+		"disables most default output",
+	)
+
+	rootCmd.AddCommand(rerunCmd)
+}
@@ -4,14 +4,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 
 	"github.com/rwx-research/captain-cli/internal/cli"
 	"github.com/rwx-research/captain-cli/internal/config"
 	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/exec"
 	"github.com/rwx-research/captain-cli/internal/mint"
 	"github.com/rwx-research/captain-cli/internal/providers"
 	"github.com/rwx-research/captain-cli/internal/reporting"
@@ -20,7 +24,13 @@ import (
 )
 
 type CliArgs struct {
+	allSuites                 bool
+	attemptTimeout            time.Duration
 	command                   string
+	deleteStaleTestResults    bool
+	dryRun                    bool
+	dryRunFormat              string
+	dryRunResults             string
 	testResults               string
 	failOnUploadError         bool
 	failOnDuplicateTestID     bool
@@ -29,25 +39,44 @@ type CliArgs struct {
 	flakyRetries              int
 	intermediateArtifactsPath string
 	additionalArtifactPaths   []string
+	maxTestDropPercent        float64
 	maxTestsToRetry           string
+	minTests                  int
 	postRetryCommands         []string
 	preRetryCommands          []string
 	printSummary              bool
 	quiet                     bool
+	repeat                    int
 	reporters                 []string
 	Retries                   int
 	retryCommandTemplate      string
+	retryBatchSize            int
+	retryConcurrency          int
+	runTimeout                time.Duration
+	suites                    []string
+	suiteConcurrency          int
+	terminationGracePeriod    time.Duration
 	updateStoredResults       bool
 	GenericProvider           providers.GenericEnv
 	frameworkParams           frameworkParams
 	RootCliArgs               rootCliArgs
 	partitionIndex            int
 	partitionTotal            int
+	partitionWeights          string
 	partitionDelimiter        string
 	partitionCommandTemplate  string
 	partitionGlobs            []string
 	partitionRoundRobin       bool
 	partitionTrimPrefix       string
+	partitionSplitTests       bool
+	partitionEstimateSize     bool
+	partitionPackages         bool
+	parallel                  int
+	partitionSticky           bool
+	partitionStickyThreshold  float64
+	partitionStrategy         string
+	partitionTimingsFrom      []string
+	queueURL                  string
 	quarantinedTestRetries    int
 }
 
@@ -55,146 +84,34 @@ func createRunCmd(cliArgs *CliArgs) *cobra.Command {
 	return &cobra.Command{
 		Use:   "run [flags] --suite-id=<suite> <args>",
 		Short: "Execute a build- or test-suite",
-		Long:  "'captain run' can be used to execute a build- or test-suite and optionally upload the resulting artifacts.",
+		Long: "'captain run' can be used to execute a build- or test-suite and optionally upload the resulting artifacts.\n" +
+			"With --all or --suites, it runs several test suites of the config file and reports on all of them.",
 		Example: `  captain run --suite-id="your-project-rake" -c "bundle exec rake"` + "\n" +
-			`  captain run --suite-id="your-project-jest" --test-results "jest-result.json" -c jest`,
-		PreRunE: initCLIService(cliArgs, providers.Validate),
+			`  captain run --suite-id="your-project-jest" --test-results "jest-result.json" -c jest` + "\n" +
+			`  captain run --suites="your-project-rake,your-project-jest" --suite-concurrency 2`,
+		PreRunE: initRunCLIService(cliArgs),
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			err := func() error {
-				args := cliArgs.RootCliArgs.positionalArgs
-
-				reporterFuncs := make(map[string]cli.Reporter)
-
 				cfg, err := getConfig(cmd)
 				if err != nil {
 					return errors.WithStack(err)
 				}
 
+				if cliArgs.runsMultipleSuites() {
+					return runMultipleSuites(cmd, cfg, *cliArgs)
+				}
+
 				captain, err := cli.GetService(cmd)
 				if err != nil {
 					return errors.WithStack(err)
 				}
 
-				var runConfig cli.RunConfig
-				if suiteConfig, ok := cfg.TestSuites[cliArgs.RootCliArgs.suiteID]; ok {
-					for name, path := range suiteConfig.Output.Reporters {
-						switch name {
-						case "rwx-v1-json":
-							reporterFuncs[path] = reporting.WriteJSONSummary
-						case "junit-xml":
-							reporterFuncs[path] = reporting.WriteJUnitSummary
-						case "markdown-summary":
-							reporterFuncs[path] = reporting.WriteMarkdownSummary
-						case "github-step-summary":
-							stepSummaryPath := os.Getenv("GITHUB_STEP_SUMMARY")
-							if stepSummaryPath == "" {
-								captain.Log.Debug(
-									"Skipping configuration of the 'github-step-summary' reporter " +
-										"(the 'GITHUB_STEP_SUMMARY' environment variable is not set).",
-								)
-								continue
-							}
-
-							reporterFuncs[stepSummaryPath] = reporting.WriteMarkdownSummary
-						default:
-							return errors.NewConfigurationError(
-								fmt.Sprintf("Unknown reporter %q", name),
-								"Available reporters are 'rwx-v1-json', 'junit-xml', 'markdown-summary', and 'github-step-summary'.",
-								"",
-							)
-						}
-					}
-
-					rwxTestResultsDir := os.Getenv("RWX_TEST_RESULTS")
-					if rwxTestResultsDir != "" {
-						if _, err := os.Stat(rwxTestResultsDir); err != nil { //nolint:gosec // path from trusted env var
-							captain.Log.Warnf("RWX_TEST_RESULTS directory does not exist: %s", rwxTestResultsDir)
-						} else {
-							rwxOutputPath := filepath.Join(rwxTestResultsDir, cliArgs.RootCliArgs.suiteID+".json")
-
-							rwxAbsPath, err := filepath.Abs(rwxOutputPath)
-							if err != nil {
-								captain.Log.Warnf("Unable to resolve absolute path for RWX_TEST_RESULTS output: %s", err.Error())
-							} else {
-								isDuplicate := false
-								for existingPath := range reporterFuncs {
-									existingAbsPath, err := filepath.Abs(existingPath)
-									if err == nil && existingAbsPath == rwxAbsPath {
-										isDuplicate = true
-										break
-									}
-								}
-
-								if !isDuplicate {
-									reporterFuncs[rwxOutputPath] = reporting.WriteJSONSummary
-								}
-							}
-						}
-					}
-
-					partitionIndex := cliArgs.partitionIndex
-					partitionTotal := cliArgs.partitionTotal
-					provider, err := cfg.ProvidersEnv.MakeProvider()
-					if err != nil {
-						return errors.Wrap(err, "failed to construct provider")
-					}
-
-					if partitionIndex < 0 {
-						partitionIndex = provider.PartitionNodes.Index
-					}
-
-					if partitionTotal < 0 {
-						partitionTotal = provider.PartitionNodes.Total
-					}
-
-					if suiteConfig.Retries.MaxTests == "" && suiteConfig.Retries.MaxTestsLegacyName != "" {
-						suiteConfig.Retries.MaxTests = suiteConfig.Retries.MaxTestsLegacyName
-					}
-
-					runConfig = cli.RunConfig{
-						Args:                      args,
-						CloudOrganizationSlug:     "deep_link",
-						Command:                   suiteConfig.Command,
-						FailOnUploadError:         suiteConfig.FailOnUploadError,
-						FailOnMisconfiguredRetry:  suiteConfig.Retries.FailOnMisconfiguration,
-						FailRetriesFast:           suiteConfig.Retries.FailFast,
-						FlakyRetries:              suiteConfig.Retries.FlakyAttempts,
-						IntermediateArtifactsPath: suiteConfig.Retries.IntermediateArtifactsPath,
-						AdditionalArtifactPaths:   suiteConfig.Retries.AdditionalArtifactPaths,
-						MaxTestsToRetry:           suiteConfig.Retries.MaxTests,
-						PostRetryCommands:         suiteConfig.Retries.PostRetryCommands,
-						PreRetryCommands:          suiteConfig.Retries.PreRetryCommands,
-						PrintSummary:              suiteConfig.Output.PrintSummary,
-						Quiet:                     suiteConfig.Output.Quiet,
-						Reporters:                 reporterFuncs,
-						Retries:                   suiteConfig.Retries.Attempts,
-						RetryCommandTemplate:      suiteConfig.Retries.Command,
-						QuarantinedTestRetries:    suiteConfig.Retries.QuarantinedAttempts,
-						SubstitutionsByFramework:  targetedretries.SubstitutionsByFramework,
-						SuiteID:                   cliArgs.RootCliArgs.suiteID,
-						TestResultsFileGlob:       os.ExpandEnv(suiteConfig.Results.Path),
-						UpdateStoredResults:       cliArgs.updateStoredResults,
-						UploadResults:             true,
-						PartitionCommandTemplate:  suiteConfig.Partition.Command,
-						PartitionConfig: cli.PartitionConfig{
-							SuiteID:       cliArgs.RootCliArgs.suiteID,
-							TestFilePaths: suiteConfig.Partition.Globs,
-							PartitionNodes: config.PartitionNodes{
-								Index: partitionIndex,
-								Total: partitionTotal,
-							},
-							Delimiter:  suiteConfig.Partition.Delimiter,
-							RoundRobin: suiteConfig.Partition.RoundRobin,
-							TrimPrefix: suiteConfig.Partition.TrimPrefix,
-						},
-						PartitionRoundRobin:         suiteConfig.Partition.RoundRobin,
-						PartitionTrimPrefix:         suiteConfig.Partition.TrimPrefix,
-						WriteRetryFailedTestsAction: mint.IsMint(),
-						DidRetryFailedTestsInMint:   mint.DidRetryFailedTests(),
-					}
+				runConfig, err := newRunConfig(cfg, *cliArgs, cliArgs.RootCliArgs.suiteID, captain.Log)
+				if err != nil {
+					return err
 				}
 
-				err = captain.RunSuite(cmd.Context(), runConfig)
+				err = ignoreShortCircuit(captain, captain.RunSuite(cmd.Context(), runConfig))
 				if _, ok := errors.AsConfigurationError(err); !ok {
 					cmd.SilenceUsage = true
 				}
@@ -209,6 +126,296 @@ func createRunCmd(cliArgs *CliArgs) *cobra.Command {
 	}
 }
 
+// initRunCLIService initializes the `captain` CLI service for a single test suite. When running several test suites,
+// only the configuration is initialized, the services for every suite are constructed once the command runs.
+func initRunCLIService(cliArgs *CliArgs) func(*cobra.Command, []string) error {
+	initSingleSuite := initCLIService(cliArgs, providers.Validate)
+
+	return func(cmd *cobra.Command, args []string) error {
+		if !cliArgs.runsMultipleSuites() {
+			return initSingleSuite(cmd, args)
+		}
+
+		err := func() error {
+			if cliArgs.allSuites && len(cliArgs.suites) > 0 {
+				return errors.NewConfigurationError(
+					"Conflicting test suite selection",
+					"Both --all and --suites were set.",
+					"Please either run all test suites with --all or select some of them with --suites.",
+				)
+			}
+
+			if cliArgs.queueURL != "" {
+				return errors.NewConfigurationError(
+					"Conflicting test suite selection",
+					"A queue URL can't be combined with --all or --suites, as a queue hands out the test files of a single "+
+						"test suite.",
+					"Please run a single test suite when pulling test files from a queue.",
+				)
+			}
+
+			if cmd.Flags().Changed("suite-id") || len(args) > 0 {
+				return errors.NewConfigurationError(
+					"Conflicting test suite selection",
+					"A suite ID can't be combined with --all or --suites.",
+					"Please either run a single test suite or select several of them with --all or --suites.",
+				)
+			}
+
+			// A suite ID from the environment doesn't apply when selecting the suites explicitly
+			cliArgs.RootCliArgs.suiteID = ""
+			cliArgs.RootCliArgs.positionalArgs = args
+
+			_, err := InitConfig(cmd, *cliArgs)
+			return errors.WithStack(err)
+		}()
+		if err != nil {
+			return errors.WithDecoration(err)
+		}
+		return nil
+	}
+}
+
+// runMultipleSuites runs every test suite selected by --all or --suites with its own configuration & service.
+func runMultipleSuites(cmd *cobra.Command, cfg Config, cliArgs CliArgs) error {
+	suiteIDs, err := selectedSuiteIDs(cfg, cliArgs)
+	if err != nil {
+		return err
+	}
+
+	suiteRuns := make([]cli.SuiteRun, 0, len(suiteIDs))
+	for _, suiteID := range suiteIDs {
+		captain, err := newCLIService(cfg, suiteID, providers.Validate)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("unable to initialize test suite %q", suiteID))
+		}
+
+		runConfig, err := newRunConfig(cfg, cliArgs, suiteID, captain.Log)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("unable to configure test suite %q", suiteID))
+		}
+
+		suiteRuns = append(suiteRuns, cli.SuiteRun{Service: captain, Config: runConfig})
+	}
+
+	cmd.SilenceUsage = true
+
+	summary := cli.Service{Log: newLogger(cfg), TaskRunner: exec.Local{}}
+	return errors.WithStack(summary.RunSuites(cmd.Context(), suiteRuns, cliArgs.suiteConcurrency))
+}
+
+// selectedSuiteIDs returns the IDs of the test suites to run. These are either all configured suites (--all), the ones
+// passed to --suites, or otherwise the single suite ID from the command line or environment.
+func selectedSuiteIDs(cfg Config, cliArgs CliArgs) ([]string, error) {
+	if cliArgs.allSuites {
+		suiteIDs := make([]string, 0, len(cfg.TestSuites))
+		for suiteID := range cfg.TestSuites {
+			suiteIDs = append(suiteIDs, suiteID)
+		}
+		sort.Strings(suiteIDs)
+
+		if len(suiteIDs) == 0 {
+			return nil, errors.NewConfigurationError(
+				"No test suites configured",
+				"--all runs every test suite of the config file, however the config file doesn't define any.",
+				"Please add your test suites to the 'test-suites' section of the config file.",
+			)
+		}
+
+		return suiteIDs, nil
+	}
+
+	if len(cliArgs.suites) > 0 {
+		suiteIDs := make([]string, 0, len(cliArgs.suites))
+		for _, suiteID := range cliArgs.suites {
+			if _, ok := cfg.TestSuites[suiteID]; !ok {
+				return nil, errors.NewConfigurationError(
+					"Unknown test suite",
+					fmt.Sprintf("The test suite %q is not defined in the config file.", suiteID),
+					"Please make sure that --suites only references test suites from the 'test-suites' section of the "+
+						"config file.",
+				)
+			}
+
+			if !slices.Contains(suiteIDs, suiteID) {
+				suiteIDs = append(suiteIDs, suiteID)
+			}
+		}
+
+		return suiteIDs, nil
+	}
+
+	return []string{cliArgs.RootCliArgs.suiteID}, nil
+}
+
+func (cliArgs CliArgs) runsMultipleSuites() bool {
+	return cliArgs.allSuites || len(cliArgs.suites) > 0
+}
+
+// newRunConfig constructs the run configuration of a single test suite.
+func newRunConfig(cfg Config, cliArgs CliArgs, suiteID string, log *zap.SugaredLogger) (cli.RunConfig, error) {
+	var runConfig cli.RunConfig
+	reporterFuncs := make(map[string]cli.Reporter)
+
+	if suiteConfig, ok := cfg.TestSuites[suiteID]; ok {
+		for name, path := range suiteConfig.Output.Reporters {
+			switch name {
+			case "rwx-v1-json":
+				reporterFuncs[path] = reporting.WriteJSONSummary
+			case "junit-xml":
+				reporterFuncs[path] = reporting.WriteJUnitSummary
+			case "markdown-summary":
+				reporterFuncs[path] = reporting.WriteMarkdownSummary
+			case "github-step-summary":
+				stepSummaryPath := os.Getenv("GITHUB_STEP_SUMMARY")
+				if stepSummaryPath == "" {
+					log.Debug(
+						"Skipping configuration of the 'github-step-summary' reporter " +
+							"(the 'GITHUB_STEP_SUMMARY' environment variable is not set).",
+					)
+					continue
+				}
+
+				reporterFuncs[stepSummaryPath] = reporting.WriteMarkdownSummary
+			default:
+				return runConfig, errors.NewConfigurationError(
+					fmt.Sprintf("Unknown reporter %q", name),
+					"Available reporters are 'rwx-v1-json', 'junit-xml', 'markdown-summary', and 'github-step-summary'.",
+					"",
+				)
+			}
+		}
+
+		rwxTestResultsDir := os.Getenv("RWX_TEST_RESULTS")
+		if rwxTestResultsDir != "" {
+			if _, err := os.Stat(rwxTestResultsDir); err != nil { //nolint:gosec // path from trusted env var
+				log.Warnf("RWX_TEST_RESULTS directory does not exist: %s", rwxTestResultsDir)
+			} else {
+				rwxOutputPath := filepath.Join(rwxTestResultsDir, suiteID+".json")
+
+				rwxAbsPath, err := filepath.Abs(rwxOutputPath)
+				if err != nil {
+					log.Warnf("Unable to resolve absolute path for RWX_TEST_RESULTS output: %s", err.Error())
+				} else {
+					isDuplicate := false
+					for existingPath := range reporterFuncs {
+						existingAbsPath, err := filepath.Abs(existingPath)
+						if err == nil && existingAbsPath == rwxAbsPath {
+							isDuplicate = true
+							break
+						}
+					}
+
+					if !isDuplicate {
+						reporterFuncs[rwxOutputPath] = reporting.WriteJSONSummary
+					}
+				}
+			}
+		}
+
+		partitionIndex := cliArgs.partitionIndex
+		partitionTotal := cliArgs.partitionTotal
+		provider, err := cfg.ProvidersEnv.MakeProvider()
+		if err != nil {
+			return runConfig, errors.Wrap(err, "failed to construct provider")
+		}
+
+		if partitionIndex < 0 {
+			partitionIndex = provider.PartitionNodes.Index
+		}
+
+		if partitionTotal < 0 {
+			partitionTotal = provider.PartitionNodes.Total
+		}
+
+		// Weights are irrelevant without partitioning, so invalid ones shouldn't get in the way of a regular run
+		var partitionWeights []float64
+		if partitionTotal > 1 {
+			rawPartitionWeights := cliArgs.partitionWeights
+			if rawPartitionWeights == "" {
+				rawPartitionWeights = provider.PartitionWeights
+			}
+
+			partitionWeights, err = config.ParsePartitionWeights(rawPartitionWeights)
+			if err != nil {
+				return runConfig, errors.WithStack(err)
+			}
+		}
+
+		if suiteConfig.Retries.MaxTests == "" && suiteConfig.Retries.MaxTestsLegacyName != "" {
+			suiteConfig.Retries.MaxTests = suiteConfig.Retries.MaxTestsLegacyName
+		}
+
+		runConfig = cli.RunConfig{
+			Args:                       cliArgs.RootCliArgs.positionalArgs,
+			AttemptTimeout:             suiteConfig.Retries.AttemptTimeout,
+			CloudOrganizationSlug:      "deep_link",
+			Command:                    suiteConfig.Command,
+			DeleteStaleTestResults:     suiteConfig.Results.DeleteStale,
+			DryRun:                     cliArgs.dryRun,
+			DryRunFormat:               cliArgs.dryRunFormat,
+			DryRunResultsPath:          cliArgs.dryRunResults,
+			FailOnUploadError:          suiteConfig.FailOnUploadError,
+			FailOnMisconfiguredRetry:   suiteConfig.Retries.FailOnMisconfiguration,
+			FailRetriesFast:            suiteConfig.Retries.FailFast,
+			FlakyRetries:               suiteConfig.Retries.FlakyAttempts,
+			IntermediateArtifactsPath:  suiteConfig.Retries.IntermediateArtifactsPath,
+			AdditionalArtifactPaths:    suiteConfig.Retries.AdditionalArtifactPaths,
+			MaxTestDropPercent:         suiteConfig.Results.MaxTestDropPercent,
+			MaxTestsToRetry:            suiteConfig.Retries.MaxTests,
+			MinTests:                   suiteConfig.Results.MinTests,
+			PostRetryCommands:          suiteConfig.Retries.PostRetryCommands,
+			PreRetryCommands:           suiteConfig.Retries.PreRetryCommands,
+			PrintSummary:               suiteConfig.Output.PrintSummary,
+			Quiet:                      suiteConfig.Output.Quiet,
+			Repeat:                     cliArgs.repeat,
+			Reporters:                  reporterFuncs,
+			Retries:                    suiteConfig.Retries.Attempts,
+			RetryBatchSize:             suiteConfig.Retries.BatchSize,
+			RetryCommandTemplate:       suiteConfig.Retries.Command,
+			RetryConcurrency:           suiteConfig.Retries.Concurrency,
+			RetryNeverIfMessageMatches: suiteConfig.Retries.NeverIfMessageMatches,
+			RetryOnlyIfMessageMatches:  suiteConfig.Retries.OnlyIfMessageMatches,
+			RunTimeout:                 suiteConfig.RunTimeout,
+			QuarantinedTestRetries:     suiteConfig.Retries.QuarantinedAttempts,
+			SubstitutionsByFramework:   targetedretries.SubstitutionsByFramework,
+			SuiteID:                    suiteID,
+			TerminationGracePeriod:     suiteConfig.TerminationGracePeriod,
+			TestResultsFileGlob:        expandTestResultsPath(suiteConfig.Results.Path),
+			UpdateStoredResults:        cliArgs.updateStoredResults,
+			UploadResults:              true,
+			PartitionCommandTemplate:   suiteConfig.Partition.Command,
+			PartitionConfig: cli.PartitionConfig{
+				SuiteID:       suiteID,
+				TestFilePaths: suiteConfig.Partition.Globs,
+				PartitionNodes: config.PartitionNodes{
+					Index:   partitionIndex,
+					Total:   partitionTotal,
+					Weights: partitionWeights,
+				},
+				Delimiter:          suiteConfig.Partition.Delimiter,
+				RoundRobin:         suiteConfig.Partition.RoundRobin,
+				TrimPrefix:         suiteConfig.Partition.TrimPrefix,
+				SplitTests:         suiteConfig.Partition.SplitTests,
+				Strategy:           cli.PartitionStrategy(suiteConfig.Partition.Strategy),
+				EstimateByFileSize: suiteConfig.Partition.EstimateByFileSize,
+				Packages:           suiteConfig.Partition.Packages,
+				Sticky:             suiteConfig.Partition.Sticky,
+				StickyThreshold:    suiteConfig.Partition.StickyThreshold,
+				TimingsFrom:        suiteConfig.Partition.TimingsFrom,
+			},
+			PartitionRoundRobin:         suiteConfig.Partition.RoundRobin,
+			PartitionTrimPrefix:         suiteConfig.Partition.TrimPrefix,
+			Parallel:                    suiteConfig.Partition.Parallel,
+			QueueURL:                    cliArgs.queueURL,
+			WriteRetryFailedTestsAction: mint.IsMint(),
+			DidRetryFailedTestsInMint:   mint.DidRetryFailedTests(),
+		}
+	}
+
+	return runConfig, nil
+}
+
 func AddFlags(runCmd *cobra.Command, cliArgs *CliArgs) error {
 	runCmd.Flags().StringVarP(
 		&cliArgs.command,
@@ -218,6 +425,29 @@ func AddFlags(runCmd *cobra.Command, cliArgs *CliArgs) error {
 		"the command to run",
 	)
 
+	runCmd.Flags().BoolVar(
+		&cliArgs.allSuites,
+		"all",
+		false,
+		"run all test suites of the config file, each with its own test results, retries and reporters",
+	)
+
+	runCmd.Flags().StringSliceVar(
+		&cliArgs.suites,
+		"suites",
+		[]string{},
+		"a comma-separated list of test suites of the config file to run, each with its own test results, retries and "+
+			"reporters (e.g. --suites rspec,jest)",
+	)
+
+	runCmd.Flags().IntVar(
+		&cliArgs.suiteConcurrency,
+		"suite-concurrency",
+		1,
+		"the maximum number of test suites to run at the same time when running several test suites with --all or "+
+			"--suites",
+	)
+
 	runCmd.Flags().StringVar(
 		&cliArgs.testResults,
 		"test-results",
@@ -225,6 +455,30 @@ func AddFlags(runCmd *cobra.Command, cliArgs *CliArgs) error {
 		"a filepath to a test result - supports globs for multiple result files",
 	)
 
+	runCmd.Flags().BoolVar(
+		&cliArgs.deleteStaleTestResults,
+		"delete-stale-test-results",
+		false,
+		"delete any files matching the test results path before running the tests. Otherwise, test results files "+
+			"that were last modified before the tests started are ignored with a warning",
+	)
+
+	runCmd.Flags().IntVar(
+		&cliArgs.minTests,
+		"min-tests",
+		0,
+		"fail the run when the test results contain fewer tests (e.g. because of focused tests). Set to 0 to disable "+
+			"the check. Test results files without any tests always fail the run",
+	)
+
+	runCmd.Flags().Float64Var(
+		&cliArgs.maxTestDropPercent,
+		"max-test-drop-percent",
+		0,
+		"fail the run when the number of tests dropped by more than this percentage compared to the test results\n"+
+			"stored by the previous run (e.g. --max-test-drop-percent 10). Requires the local backend",
+	)
+
 	runCmd.Flags().BoolVar(
 		&cliArgs.failOnUploadError,
 		"fail-on-upload-error",
@@ -250,7 +504,9 @@ func AddFlags(runCmd *cobra.Command, cliArgs *CliArgs) error {
 		&cliArgs.intermediateArtifactsPath,
 		"intermediate-artifacts-path",
 		"",
-		"the path to store intermediate artifacts under. Intermediate artifacts will be removed if not set.",
+		"the path to store intermediate artifacts under, including a log file with the output of every command. "+
+			"Intermediate artifacts will be removed if not set. As their output is also written to a log file, "+
+			"the commands no longer write to a terminal directly, which may turn off colors or interactive output.",
 	)
 
 	runCmd.Flags().StringArrayVar(
@@ -331,6 +587,77 @@ func AddFlags(runCmd *cobra.Command, cliArgs *CliArgs) error {
 		"number of retries for quarantined tests, similar to --flaky-retries. Set to 0 to disable retrying quarantined tests",
 	)
 
+	runCmd.Flags().BoolVar(
+		&cliArgs.dryRun,
+		"dry-run",
+		false,
+		"prints the commands that Captain would run instead of running them",
+	)
+
+	runCmd.Flags().StringVar(
+		&cliArgs.dryRunFormat,
+		"dry-run-format",
+		"text",
+		"the format in which --dry-run prints the commands, either 'text' or 'json'",
+	)
+
+	runCmd.Flags().StringVar(
+		&cliArgs.dryRunResults,
+		"dry-run-results",
+		"",
+		"the RWX v1 test results of a previous run (e.g. as written by the 'rwx-v1-json' reporter). If set, --dry-run "+
+			"also prints the commands of the first retry of the failed tests",
+	)
+
+	runCmd.Flags().IntVar(
+		&cliArgs.repeat,
+		"repeat",
+		0,
+		"if set, runs all tests N times in total (the first time using the test command, every other time using the "+
+			"retry command) and prints a flakiness report. Fails if any test failed in any of the runs",
+	)
+
+	runCmd.Flags().IntVar(
+		&cliArgs.retryBatchSize,
+		"retry-batch-size",
+		0,
+		"the maximum number of tests to retry in a single retry command. Larger retries are split into several retry "+
+			"commands. Retries all tests in as few commands as possible when 0",
+	)
+
+	runCmd.Flags().IntVar(
+		&cliArgs.retryConcurrency,
+		"retry-concurrency",
+		1,
+		"the maximum number of retry commands to run at the same time, in case a retry is split into several commands. "+
+			"Requires the test results path to reference $"+cli.RetryCommandIDEnvVar+" when greater than 1. "+
+			"Concurrent retry commands share one run of the pre- and post-retry commands and one move of the "+
+			"additional artifacts per retry",
+	)
+
+	runCmd.Flags().DurationVar(
+		&cliArgs.attemptTimeout,
+		"attempt-timeout",
+		0,
+		"the maximum duration of any single invocation of the test or retry command (e.g. --attempt-timeout 15m). "+
+			"Commands exceeding it are terminated, and any tests that did not report a result are marked as timed out",
+	)
+
+	runCmd.Flags().DurationVar(
+		&cliArgs.runTimeout,
+		"run-timeout",
+		0,
+		"the maximum duration of the entire run, including all retries (e.g. --run-timeout 1h). Once exceeded, the "+
+			"running command is terminated, no further retries are attempted and the results so far are reported",
+	)
+
+	runCmd.Flags().DurationVar(
+		&cliArgs.terminationGracePeriod,
+		"termination-grace-period",
+		0,
+		"how long a timed out command has to exit after receiving SIGTERM before it is killed (default 10s)",
+	)
+
 	runCmd.Flags().IntVar(
 		&cliArgs.partitionIndex,
 		"partition-index",
@@ -345,6 +672,15 @@ func AddFlags(runCmd *cobra.Command, cliArgs *CliArgs) error {
 		"The desired number of partitions. Any empty partitions will result in a noop.",
 	)
 
+	runCmd.Flags().StringVar(
+		&cliArgs.partitionWeights,
+		"partition-weights",
+		"",
+		"The relative capacities of the partition nodes as a comma-separated list, e.g. 1,1,4 if the third node is\n"+
+			"four times as fast as the others. Every partition then takes a share of the runtime that is proportional\n"+
+			"to its weight. It can also be set using the env var CAPTAIN_PARTITION_WEIGHTS.",
+	)
+
 	runCmd.Flags().StringVar(
 		&cliArgs.partitionDelimiter,
 		"partition-delimiter",
@@ -359,6 +695,14 @@ func AddFlags(runCmd *cobra.Command, cliArgs *CliArgs) error {
 		"Filepath globs used to identify the test files you wish to partition",
 	)
 
+	runCmd.Flags().StringArrayVar(
+		&cliArgs.partitionTimingsFrom,
+		"partition-timings-from",
+		[]string{},
+		"A glob of test results files, e.g. JUnit XML artifacts of earlier runs, to read the test file timings from\n"+
+			"instead of Captain. It can be passed multiple times",
+	)
+
 	runCmd.Flags().StringVar(
 		&cliArgs.partitionCommandTemplate,
 		"partition-command",
@@ -366,7 +710,8 @@ func AddFlags(runCmd *cobra.Command, cliArgs *CliArgs) error {
 		fmt.Sprintf(
 			"The command that will be run to execute a subset of your tests while partitioning\n"+
 				"(required if --partition-index or --partition-total is passed)\n"+
-				"Examples:\n  Custom: --partition-command \"%v\"",
+				"Examples:\n  Custom: --partition-command \"%v\"\n"+
+				"  With a file listing the test files: --partition-command \"bin/your-script {{ testFilesFile }}\"",
 			runpartition.DelimiterSubstitution{}.Example(),
 		),
 	)
@@ -386,6 +731,75 @@ func AddFlags(runCmd *cobra.Command, cliArgs *CliArgs) error {
 		"A prefix to trim from the beginning of local test file paths when comparing them to historical timing data.",
 	)
 
+	runCmd.Flags().StringVar(
+		&cliArgs.partitionStrategy,
+		"partition-strategy",
+		"",
+		fmt.Sprintf(
+			"The strategy used to assign test files to partitions, one of %v (default 'greedy')",
+			cli.PartitionStrategyNames(),
+		),
+	)
+
+	runCmd.Flags().StringVar(
+		&cliArgs.queueURL,
+		"queue-url",
+		os.Getenv("CAPTAIN_QUEUE_URL"),
+		"The URL of a 'captain queue serve' coordinator. Instead of running a fixed partition, Captain runs batches of\n"+
+			"test files from the queue with the --partition-command until the queue is drained.\n"+
+			"It can also be set using the env var CAPTAIN_QUEUE_URL.",
+	)
+
+	runCmd.Flags().BoolVar(
+		&cliArgs.partitionSplitTests,
+		"partition-split-tests",
+		false,
+		"Whether to split test files that take longer than a partition should take into their individual tests.\n"+
+			"The --partition-command then uses the keywords of the framework's retry command, e.g. {{ tests }} for\n"+
+			"RSpec and pytest, and requires --language and --framework to be set (only Jest, pytest and RSpec)",
+	)
+
+	runCmd.Flags().BoolVar(
+		&cliArgs.partitionEstimateSize,
+		"partition-estimate-by-file-size",
+		false,
+		"Whether to scale the estimated timings of test files without historical timings by their file size.\n"+
+			"By default, they're estimated at the median timing of the test files with timings",
+	)
+
+	runCmd.Flags().BoolVar(
+		&cliArgs.partitionPackages,
+		"partition-packages",
+		false,
+		"Whether to partition Go packages instead of test files. The --partition-globs are then package patterns like\n"+
+			"./... and the {{ testFiles }} of the --partition-command are the import paths of the packages with tests",
+	)
+
+	runCmd.Flags().IntVar(
+		&cliArgs.parallel,
+		"parallel",
+		0,
+		"The number of partitions to run at the same time on this machine. Every worker runs the --partition-command\n"+
+			"with its own $"+cli.WorkerIndexEnvVar+", which the test results path needs to reference",
+	)
+
+	runCmd.Flags().BoolVar(
+		&cliArgs.partitionSticky,
+		"partition-sticky",
+		false,
+		"Whether to keep test files on the partition they were assigned to previously, so that caches of a partition\n"+
+			"stay warm. The partitions are only rebalanced once their imbalance goes over --partition-sticky-threshold.\n"+
+			"Only works in OSS mode",
+	)
+
+	runCmd.Flags().Float64Var(
+		&cliArgs.partitionStickyThreshold,
+		"partition-sticky-threshold",
+		10,
+		"The percentage by which the slowest sticky partition may take longer than the average partition before the\n"+
+			"partitions are rebalanced",
+	)
+
 	runCmd.Flags().StringVar(&cliArgs.RootCliArgs.githubJobName, "github-job-name", "",
 		"the name of the current Github Job")
 	if err := runCmd.Flags().MarkDeprecated("github-job-name", "the value will be ignored"); err != nil {
@@ -414,8 +828,12 @@ func AddFlags(runCmd *cobra.Command, cliArgs *CliArgs) error {
 		fmt.Sprintf(
 			"the command that will be run to execute a subset of your tests while retrying "+
 				"(required if --retries or --flaky-retries is passed)\n"+
-				"Examples:\n  Custom: --retry-command \"%v\"\n%v",
+				"Examples:\n  Custom: --retry-command \"%v\"\n"+
+				"  With a file listing the tests: --retry-command \"%v\"\n"+
+				"    (one argument per line; pytest and Cucumber read it natively, other frameworks need a wrapper script)\n"+
+				"%v",
 			targetedretries.JSONSubstitution{}.Example(),
+			targetedretries.TestsFileSubstitution{}.Example(),
 			strings.Join(formattedSubstitutionExamples, "\n"),
 		),
 	)
@@ -435,8 +853,8 @@ func AddFlags(runCmd *cobra.Command, cliArgs *CliArgs) error {
 
 // this should be run _last_ as it has the highest precedence, and the assignments we make here overwrite settings
 // from other parts of the app (e.g. config files, env vars)
-func bindRunCmdFlags(cfg Config, cliArgs CliArgs, cmd *cobra.Command) Config {
-	if suiteConfig, ok := cfg.TestSuites[cliArgs.RootCliArgs.suiteID]; ok {
+func bindRunCmdFlags(cfg Config, cliArgs CliArgs, cmd *cobra.Command, suiteID string) Config {
+	if suiteConfig, ok := cfg.TestSuites[suiteID]; ok {
 		if cliArgs.command != "" {
 			suiteConfig.Command = cliArgs.command
 		}
@@ -457,6 +875,19 @@ func bindRunCmdFlags(cfg Config, cliArgs CliArgs, cmd *cobra.Command) Config {
 			suiteConfig.Results.Path = cliArgs.testResults
 		}
 
+		if cliArgs.deleteStaleTestResults {
+			suiteConfig.Results.DeleteStale = true
+		}
+
+		if cmd.Flags().Changed("min-tests") {
+			suiteConfig.Results.MinTests = cliArgs.minTests
+		}
+
+		if cmd.Flags().Changed("max-test-drop-percent") {
+			maxTestDropPercent := cliArgs.maxTestDropPercent
+			suiteConfig.Results.MaxTestDropPercent = &maxTestDropPercent
+		}
+
 		if len(cliArgs.postRetryCommands) != 0 {
 			suiteConfig.Retries.PostRetryCommands = cliArgs.postRetryCommands
 		}
@@ -483,6 +914,14 @@ func bindRunCmdFlags(cfg Config, cliArgs CliArgs, cmd *cobra.Command) Config {
 			suiteConfig.Retries.MaxTests = cliArgs.maxTestsToRetry
 		}
 
+		if cmd.Flags().Changed("retry-batch-size") {
+			suiteConfig.Retries.BatchSize = cliArgs.retryBatchSize
+		}
+
+		if cmd.Flags().Changed("retry-concurrency") {
+			suiteConfig.Retries.Concurrency = cliArgs.retryConcurrency
+		}
+
 		if cliArgs.printSummary {
 			suiteConfig.Output.PrintSummary = true
 		}
@@ -513,6 +952,18 @@ func bindRunCmdFlags(cfg Config, cliArgs CliArgs, cmd *cobra.Command) Config {
 			suiteConfig.Retries.Command = cliArgs.retryCommandTemplate
 		}
 
+		if cmd.Flags().Changed("attempt-timeout") {
+			suiteConfig.Retries.AttemptTimeout = cliArgs.attemptTimeout
+		}
+
+		if cmd.Flags().Changed("run-timeout") {
+			suiteConfig.RunTimeout = cliArgs.runTimeout
+		}
+
+		if cmd.Flags().Changed("termination-grace-period") {
+			suiteConfig.TerminationGracePeriod = cliArgs.terminationGracePeriod
+		}
+
 		if cliArgs.intermediateArtifactsPath != "" {
 			suiteConfig.Retries.IntermediateArtifactsPath = cliArgs.intermediateArtifactsPath
 		}
@@ -533,6 +984,10 @@ func bindRunCmdFlags(cfg Config, cliArgs CliArgs, cmd *cobra.Command) Config {
 			suiteConfig.Partition.Globs = cliArgs.partitionGlobs
 		}
 
+		if len(cliArgs.partitionTimingsFrom) != 0 {
+			suiteConfig.Partition.TimingsFrom = cliArgs.partitionTimingsFrom
+		}
+
 		if cmd.Flags().Changed("partition-round-robin") {
 			suiteConfig.Partition.RoundRobin = cliArgs.partitionRoundRobin
 		}
@@ -541,7 +996,35 @@ func bindRunCmdFlags(cfg Config, cliArgs CliArgs, cmd *cobra.Command) Config {
 			suiteConfig.Partition.TrimPrefix = cliArgs.partitionTrimPrefix
 		}
 
-		cfg.TestSuites[cliArgs.RootCliArgs.suiteID] = suiteConfig
+		if cliArgs.partitionStrategy != "" {
+			suiteConfig.Partition.Strategy = cliArgs.partitionStrategy
+		}
+
+		if cmd.Flags().Changed("partition-split-tests") {
+			suiteConfig.Partition.SplitTests = cliArgs.partitionSplitTests
+		}
+
+		if cmd.Flags().Changed("partition-estimate-by-file-size") {
+			suiteConfig.Partition.EstimateByFileSize = cliArgs.partitionEstimateSize
+		}
+
+		if cmd.Flags().Changed("partition-packages") {
+			suiteConfig.Partition.Packages = cliArgs.partitionPackages
+		}
+
+		if cmd.Flags().Changed("parallel") {
+			suiteConfig.Partition.Parallel = cliArgs.parallel
+		}
+
+		if cmd.Flags().Changed("partition-sticky") {
+			suiteConfig.Partition.Sticky = cliArgs.partitionSticky
+		}
+
+		if cmd.Flags().Changed("partition-sticky-threshold") {
+			suiteConfig.Partition.StickyThreshold = &cliArgs.partitionStickyThreshold
+		}
+
+		cfg.TestSuites[suiteID] = suiteConfig
 
 		cfg.ProvidersEnv.Generic = providers.MergeGeneric(cfg.ProvidersEnv.Generic, cliArgs.GenericProvider)
 	}
@@ -598,6 +1081,17 @@ func addGenericProviderFlags(cmd *cobra.Command, destination *providers.GenericE
 	)
 }
 
+// ignoreShortCircuit logs why a test suite stopped before running its tests. This isn't a failure, so the command
+// still succeeds.
+func ignoreShortCircuit(captain cli.Service, err error) error {
+	if shortCircuitErr, ok := errors.AsShortCircuitError(err); ok {
+		captain.Log.Warn(shortCircuitErr.Error())
+		return nil
+	}
+
+	return err
+}
+
 func addShaFlag(cmd *cobra.Command, destination *string) {
 	cmd.Flags().StringVar(
 		destination,
@@ -606,3 +1100,15 @@ func addShaFlag(cmd *cobra.Command, destination *string) {
 		"the git commit sha hash of the commit being built",
 	)
 }
+
+// expandTestResultsPath expands any environment variables in the test results path. References to the retry command ID
+// and the worker index are kept, as Captain resolves them separately for every command it runs.
+func expandTestResultsPath(path string) string {
+	return os.Expand(path, func(name string) string {
+		if name == cli.RetryCommandIDEnvVar || name == cli.WorkerIndexEnvVar {
+			return fmt.Sprintf("${%s}", name)
+		}
+
+		return os.Getenv(name)
+	})
+}
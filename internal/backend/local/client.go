@@ -2,9 +2,11 @@ package local
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -17,22 +19,38 @@ import (
 )
 
 type Client struct {
-	fs              fs.FileSystem
-	Flakes          []yaml.Node
-	flakesPath      string
-	Quarantines     []yaml.Node
-	quarantinesPath string
-	quarantinesTime time.Time
-	Timings         map[string]time.Duration
-	timingsPath     string
+	fs         fs.FileSystem
+	Flakes     []yaml.Node
+	flakesPath string
+	// PartitionAssignments are the partition assignments of the previous sticky partitioning
+	PartitionAssignments PartitionAssignments
+	partitionsPath       string
+	Quarantines          []yaml.Node
+	quarantinesPath      string
+	quarantinesTime      time.Time
+	resultsPath          string
+	testTimings          map[string]testing.TestTiming
+	testTimingsPath      string
+	Timings              map[string]FileTiming
+	timingHistory        TimingHistory
+	timingsPath          string
 }
 
-func NewClient(fileSystem fs.FileSystem, flakesPath, quarantinesPath, timingsPath string) (Client, error) {
+func NewClient(
+	fileSystem fs.FileSystem,
+	flakesPath, quarantinesPath, timingsPath, resultsPath, testTimingsPath, partitionsPath string,
+	timingHistory TimingHistory,
+) (Client, error) {
 	c := Client{
 		fs:              fileSystem,
 		flakesPath:      flakesPath,
+		partitionsPath:  partitionsPath,
 		quarantinesPath: quarantinesPath,
-		Timings:         make(map[string]time.Duration),
+		resultsPath:     resultsPath,
+		testTimings:     make(map[string]testing.TestTiming),
+		testTimingsPath: testTimingsPath,
+		Timings:         make(map[string]FileTiming),
+		timingHistory:   timingHistory,
 		timingsPath:     timingsPath,
 	}
 
@@ -98,9 +116,48 @@ func NewClient(fileSystem fs.FileSystem, flakesPath, quarantinesPath, timingsPat
 		return c, errors.WithStack(err)
 	}
 
+	if err := c.readTestTimings(); err != nil {
+		return c, err
+	}
+
+	partitionAssignments, err := c.readPartitionAssignments()
+	if err != nil {
+		return c, err
+	}
+	c.PartitionAssignments = partitionAssignments
+
 	return c, nil
 }
 
+// readTestTimings reads the timings of individual tests. These are only written once a run updates them, so a missing
+// file simply means that there are no test timings yet.
+func (c Client) readTestTimings() error {
+	if c.testTimingsPath == "" {
+		return nil
+	}
+
+	fd, err := c.fs.Open(c.testTimingsPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return errors.Wrap(err, fmt.Sprintf("unable to open %q", c.testTimingsPath))
+	}
+	defer fd.Close()
+
+	testTimings := make([]testing.TestTiming, 0)
+	if err := yaml.NewDecoder(fd).Decode(&testTimings); err != nil && !errors.Is(err, io.EOF) {
+		return errors.Wrap(err, fmt.Sprintf("unable to read %q", c.testTimingsPath))
+	}
+
+	for _, testTiming := range testTimings {
+		c.testTimings[testTiming.Key()] = testTiming
+	}
+
+	return nil
+}
+
 func (c Client) Flush() error {
 	write := func(filepath string, data any) error {
 		file, err := c.fs.OpenFile(filepath, os.O_WRONLY|os.O_TRUNC, 0)
@@ -127,16 +184,38 @@ func (c Client) Flush() error {
 func (c Client) GetTestTimingManifest(_ context.Context, _ string) ([]testing.TestFileTiming, error) {
 	testTimings := make([]testing.TestFileTiming, 0, len(c.Timings))
 
-	for file, duration := range c.Timings {
+	for file, fileTiming := range c.Timings {
 		testTimings = append(testTimings, testing.TestFileTiming{
 			Filepath: file,
-			Duration: duration,
+			Duration: fileTiming.Duration(),
 		})
 	}
 
 	return testTimings, nil
 }
 
+// GetTestTimings returns the timings of individual tests, sorted by their file path & name
+func (c Client) GetTestTimings(_ context.Context, _ string) ([]testing.TestTiming, error) {
+	return c.sortedTestTimings(), nil
+}
+
+func (c Client) sortedTestTimings() []testing.TestTiming {
+	testTimings := make([]testing.TestTiming, 0, len(c.testTimings))
+	for _, testTiming := range c.testTimings {
+		testTimings = append(testTimings, testTiming)
+	}
+
+	sort.Slice(testTimings, func(i, j int) bool {
+		if testTimings[i].Filepath != testTimings[j].Filepath {
+			return testTimings[i].Filepath < testTimings[j].Filepath
+		}
+
+		return testTimings[i].Key() < testTimings[j].Key()
+	})
+
+	return testTimings
+}
+
 func (c Client) GetRunConfiguration(_ context.Context, _ string) (backend.RunConfiguration, error) {
 	return makeRunConfiguration(c.Flakes, c.Quarantines, c.quarantinesTime)
 }
@@ -157,51 +236,169 @@ func (c Client) GetQuarantinedTests(_ context.Context, _ string) ([]backend.Test
 	return quarantinedTests, nil
 }
 
+// timingsUpdate determines how the timings are updated along with the stored test results
+type timingsUpdate int
+
+const (
+	// fullSuiteTimings are the timings of a run of the whole test suite, which ages the timings of missing test files
+	fullSuiteTimings timingsUpdate = iota
+	// partialSuiteTimings are the timings of a run of a part of the test suite, e.g. a single partition
+	partialSuiteTimings
+	// noTimings keeps the timings as they are
+	noTimings
+)
+
+// UpdateTestResults stores the test results of a run of the whole test suite and records their timings. Test files
+// that weren't part of the run age out of the timings eventually.
 func (c Client) UpdateTestResults(
 	_ context.Context,
 	_ string,
 	testResults v1.TestResults,
 ) ([]backend.TestResultsUploadResult, error) {
+	return c.updateTestResults(testResults, fullSuiteTimings)
+}
+
+// UpdatePartialTestResults stores the test results of a run that only covered a part of the test suite, e.g. a
+// partition or the batches from a queue. The timings of the other test files are kept as they are, as they ran
+// elsewhere.
+func (c Client) UpdatePartialTestResults(
+	_ context.Context,
+	_ string,
+	testResults v1.TestResults,
+) ([]backend.TestResultsUploadResult, error) {
+	return c.updateTestResults(testResults, partialSuiteTimings)
+}
+
+// StoreTestResults stores test results without recording their timings, e.g. stored test results that were updated
+// with the results of rerunning some of their tests.
+func (c Client) StoreTestResults(
+	_ context.Context,
+	_ string,
+	testResults v1.TestResults,
+) ([]backend.TestResultsUploadResult, error) {
+	return c.updateTestResults(testResults, noTimings)
+}
+
+func (c Client) updateTestResults(
+	testResults v1.TestResults,
+	update timingsUpdate,
+) ([]backend.TestResultsUploadResult, error) {
+	if update != noTimings {
+		if err := c.updateTimings(testResults, update); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.resultsPath != "" {
+		resultsFile, err := c.fs.Create(c.resultsPath)
+		if err != nil {
+			return nil, errors.NewSystemError("unable to open %q: %s", c.resultsPath, err)
+		}
+		defer resultsFile.Close()
+
+		if err := json.NewEncoder(resultsFile).Encode(testResults); err != nil {
+			return nil, errors.NewSystemError("unable to write to %q: %s", c.resultsPath, err)
+		}
+	}
+
+	originalPaths := make([]string, len(testResults.DerivedFrom))
+	for i, result := range testResults.DerivedFrom {
+		originalPaths[i] = result.OriginalFilePath
+	}
+
+	return []backend.TestResultsUploadResult{{
+		OriginalPaths: originalPaths,
+		Uploaded:      true,
+	}}, nil
+}
+
+func (c Client) updateTimings(testResults v1.TestResults, update timingsUpdate) error {
 	if c.Timings == nil {
-		c.Timings = make(map[string]time.Duration)
+		c.Timings = make(map[string]FileTiming)
 	}
 
 	newTimings := make(map[string]time.Duration)
 
 	for _, test := range testResults.Tests {
-		if test.Location != nil && test.Attempt.Duration != nil {
-			testDuration, ok := newTimings[test.Location.File]
+		timingPath, ok := testing.TimingPath(test)
+		if ok && test.Attempt.Duration != nil {
+			testDuration, ok := newTimings[timingPath]
 			if ok {
 				testDuration += *test.Attempt.Duration
 			} else {
 				testDuration = *test.Attempt.Duration
 			}
-			newTimings[test.Location.File] = testDuration
+			newTimings[timingPath] = testDuration
+		}
+	}
+
+	c.recordTimings(newTimings, update == fullSuiteTimings)
+
+	if c.testTimings == nil {
+		c.testTimings = make(map[string]testing.TestTiming)
+	}
+
+	newTestTimings := make(map[string]testing.TestTiming)
+
+	for _, test := range testResults.Tests {
+		testTiming, ok := testing.NewTestTiming(test)
+		if !ok {
+			continue
+		}
+
+		if previousTestTiming, ok := newTestTimings[testTiming.Key()]; ok {
+			testTiming.Duration += previousTestTiming.Duration
 		}
+		newTestTimings[testTiming.Key()] = testTiming
 	}
 
-	for file, duration := range newTimings {
-		c.Timings[file] = duration
+	for key, testTiming := range newTestTimings {
+		c.testTimings[key] = testTiming
 	}
 
 	timingsFile, err := c.fs.OpenFile(c.timingsPath, os.O_WRONLY|os.O_TRUNC, 0)
 	if err != nil {
-		return nil, errors.NewSystemError("unable to open %q: %s", c.timingsPath, err)
+		return errors.NewSystemError("unable to open %q: %s", c.timingsPath, err)
 	}
 	defer timingsFile.Close()
 
 	timingsEncoder := yaml.NewEncoder(timingsFile)
 	if err := timingsEncoder.Encode(c.Timings); err != nil {
-		return nil, errors.NewSystemError("unable to write to %q: %s", c.timingsPath, err)
+		return errors.NewSystemError("unable to write to %q: %s", c.timingsPath, err)
 	}
 
-	originalPaths := make([]string, len(testResults.DerivedFrom))
-	for i, result := range testResults.DerivedFrom {
-		originalPaths[i] = result.OriginalFilePath
+	if c.testTimingsPath != "" {
+		testTimingsFile, err := c.fs.Create(c.testTimingsPath)
+		if err != nil {
+			return errors.NewSystemError("unable to open %q: %s", c.testTimingsPath, err)
+		}
+		defer testTimingsFile.Close()
+
+		if err := yaml.NewEncoder(testTimingsFile).Encode(c.sortedTestTimings()); err != nil {
+			return errors.NewSystemError("unable to write to %q: %s", c.testTimingsPath, err)
+		}
 	}
 
-	return []backend.TestResultsUploadResult{{
-		OriginalPaths: originalPaths,
-		Uploaded:      true,
-	}}, nil
+	return nil
+}
+
+// GetTestResults returns the test results that were stored by the most recent update. The returned error wraps
+// `os.ErrNotExist` if no test results were stored yet.
+func (c Client) GetTestResults(_ context.Context, _ string) (*v1.TestResults, error) {
+	if c.resultsPath == "" {
+		return nil, errors.WithStack(os.ErrNotExist)
+	}
+
+	resultsFile, err := c.fs.Open(c.resultsPath)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("unable to open %q", c.resultsPath))
+	}
+	defer resultsFile.Close()
+
+	testResults := new(v1.TestResults)
+	if err := json.NewDecoder(resultsFile).Decode(testResults); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("unable to read %q", c.resultsPath))
+	}
+
+	return testResults, nil
 }
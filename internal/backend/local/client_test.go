@@ -2,6 +2,7 @@ package local_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -11,8 +12,10 @@ import (
 
 	"github.com/rwx-research/captain-cli/internal/backend"
 	"github.com/rwx-research/captain-cli/internal/backend/local"
+	"github.com/rwx-research/captain-cli/internal/errors"
 	"github.com/rwx-research/captain-cli/internal/fs"
 	"github.com/rwx-research/captain-cli/internal/mocks"
+	"github.com/rwx-research/captain-cli/internal/testing"
 	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -24,16 +27,21 @@ var _ = Describe("local backend client", func() {
 		flakesPath      = "flakes.yaml"
 		quarantinesPath = "quarantines.yaml"
 		timingsPath     = "timings.yaml"
+		resultsPath     = "results.json"
+		testTimingsPath = "test-timings.yaml"
+		partitionsPath  = "partitions.yaml"
 	)
 
 	var (
-		err                          error
-		client                       local.Client
-		fileSystem                   mocks.FileSystem
-		flakes, quarantines, timings mocks.File
+		err                                                error
+		client                                             local.Client
+		fileSystem                                         mocks.FileSystem
+		flakes, quarantines, timings, results, testTimings mocks.File
+		timingHistory                                      local.TimingHistory
 	)
 
 	BeforeEach(func() {
+		timingHistory = local.TimingHistory{Window: 3, MaxUnseenRuns: 2}
 		flakes.Reader = strings.NewReader("")
 		quarantines.Reader = strings.NewReader("")
 		timings.Reader = strings.NewReader("")
@@ -51,7 +59,9 @@ var _ = Describe("local backend client", func() {
 			}
 		}
 
-		client, err = local.NewClient(&fileSystem, flakesPath, quarantinesPath, timingsPath)
+		client, err = local.NewClient(
+			&fileSystem, flakesPath, quarantinesPath, timingsPath, resultsPath, testTimingsPath, partitionsPath, timingHistory,
+		)
 		Expect(err).ToNot(HaveOccurred())
 	})
 
@@ -62,13 +72,30 @@ var _ = Describe("local backend client", func() {
 			duration      time.Duration
 			testResults   v1.TestResults
 			uploadResults []backend.TestResultsUploadResult
+			partialRun    bool
+			storeOnly     bool
 		)
 
 		BeforeEach(func() {
+			partialRun = false
+			storeOnly = false
 			duration = time.Second * time.Duration(GinkgoRandomSeed())
 			flakes.Builder = new(strings.Builder)
 			quarantines.Builder = new(strings.Builder)
 			timings.Builder = new(strings.Builder)
+			results.Builder = new(strings.Builder)
+			testTimings.Builder = new(strings.Builder)
+
+			fileSystem.MockCreate = func(name string) (fs.File, error) {
+				switch name {
+				case resultsPath:
+					return &results, nil
+				case testTimingsPath:
+					return &testTimings, nil
+				default:
+					return nil, os.ErrNotExist
+				}
+			}
 
 			fileSystem.MockOpenFile = func(name string, _ int, _ os.FileMode) (fs.File, error) {
 				switch name {
@@ -89,6 +116,7 @@ var _ = Describe("local backend client", func() {
 					Language: v1.FrameworkLanguageJavaScript,
 				},
 				Tests: []v1.Test{{
+					Name: "a test",
 					Attempt: v1.TestAttempt{
 						Duration: &duration,
 						Status: v1.TestStatus{
@@ -103,17 +131,259 @@ var _ = Describe("local backend client", func() {
 		})
 
 		JustBeforeEach(func() {
-			uploadResults, err = client.UpdateTestResults(context.Background(), suiteID, testResults)
+			switch {
+			case partialRun:
+				uploadResults, err = client.UpdatePartialTestResults(context.Background(), suiteID, testResults)
+			case storeOnly:
+				uploadResults, err = client.StoreTestResults(context.Background(), suiteID, testResults)
+			default:
+				uploadResults, err = client.UpdateTestResults(context.Background(), suiteID, testResults)
+			}
 		})
 
 		It("updates the timings file", func() {
-			var result map[string]time.Duration
+			var result map[string]local.FileTiming
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(uploadResults).To(HaveLen(1))
 			Expect(yaml.Unmarshal([]byte(timings.Builder.String()), &result)).To(Succeed())
 			Expect(result).To(HaveKey(fmt.Sprintf("%d", GinkgoRandomSeed())))
-			Expect(result[fmt.Sprintf("%d", GinkgoRandomSeed())]).To(Equal(time.Second * time.Duration(GinkgoRandomSeed())))
+			Expect(result[fmt.Sprintf("%d", GinkgoRandomSeed())].Duration()).To(Equal(duration))
+		})
+
+		Context("with a timing history", func() {
+			BeforeEach(func() {
+				timings.Reader = strings.NewReader(fmt.Sprintf(`%d:
+  durations: [1s, 2s, 3s]
+spec/a_spec.rb:
+  durations: [4s]
+spec/b_spec.rb:
+  durations: [5s]
+  unseen-runs: 1
+`, GinkgoRandomSeed()))
+
+				client, err = local.NewClient(
+					&fileSystem, flakesPath, quarantinesPath, timingsPath, resultsPath, testTimingsPath, partitionsPath, timingHistory,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				duration = 6 * time.Second
+			})
+
+			It("averages the durations of the most recent runs", func() {
+				var result map[string]local.FileTiming
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(yaml.Unmarshal([]byte(timings.Builder.String()), &result)).To(Succeed())
+				Expect(result[fmt.Sprintf("%d", GinkgoRandomSeed())]).To(Equal(local.FileTiming{
+					Durations: []time.Duration{2 * time.Second, 3 * time.Second, 6 * time.Second},
+				}))
+				Expect(result[fmt.Sprintf("%d", GinkgoRandomSeed())].Duration()).To(Equal(11 * time.Second / 3))
+			})
+
+			It("drops the timings of test files that weren't part of the last runs", func() {
+				var result map[string]local.FileTiming
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(yaml.Unmarshal([]byte(timings.Builder.String()), &result)).To(Succeed())
+				Expect(result).To(HaveKeyWithValue("spec/a_spec.rb", local.FileTiming{
+					Durations:  []time.Duration{4 * time.Second},
+					UnseenRuns: 1,
+				}))
+				Expect(result).NotTo(HaveKey("spec/b_spec.rb"))
+			})
+
+			Context("when only a part of the test suite ran", func() {
+				BeforeEach(func() {
+					partialRun = true
+				})
+
+				It("keeps the timings of the other test files as they are", func() {
+					var result map[string]local.FileTiming
+
+					Expect(err).ToNot(HaveOccurred())
+					Expect(yaml.Unmarshal([]byte(timings.Builder.String()), &result)).To(Succeed())
+					Expect(result[fmt.Sprintf("%d", GinkgoRandomSeed())].Duration()).To(Equal(11 * time.Second / 3))
+					Expect(result).To(HaveKeyWithValue("spec/a_spec.rb", local.FileTiming{
+						Durations: []time.Duration{4 * time.Second},
+					}))
+					Expect(result).To(HaveKeyWithValue("spec/b_spec.rb", local.FileTiming{
+						Durations:  []time.Duration{5 * time.Second},
+						UnseenRuns: 1,
+					}))
+				})
+			})
+
+			Context("when only storing the test results", func() {
+				BeforeEach(func() {
+					storeOnly = true
+				})
+
+				It("doesn't record any timings", func() {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(timings.Builder.String()).To(BeEmpty())
+					Expect(testTimings.Builder.String()).To(BeEmpty())
+					Expect(results.Builder.String()).NotTo(BeEmpty())
+				})
+			})
+		})
+
+		Context("with go test results", func() {
+			BeforeEach(func() {
+				testPackage := "github.com/example/project/pkg"
+				test := func(name string, duration time.Duration) v1.Test {
+					return v1.Test{
+						Scope: &testPackage,
+						Name:  name,
+						Attempt: v1.TestAttempt{
+							Duration: &duration,
+							Meta:     map[string]any{"package": testPackage},
+							Status:   v1.NewSuccessfulTestStatus(),
+						},
+					}
+				}
+
+				testResults.Tests = []v1.Test{
+					test("TestA", 2*time.Second),
+					test("TestA/subtest", time.Second),
+					test("TestB", 3*time.Second),
+				}
+			})
+
+			It("records the timings of the packages", func() {
+				var result map[string]local.FileTiming
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(yaml.Unmarshal([]byte(timings.Builder.String()), &result)).To(Succeed())
+				Expect(result).To(HaveKey("github.com/example/project/pkg"))
+				Expect(result["github.com/example/project/pkg"].Duration()).To(Equal(5 * time.Second))
+			})
+		})
+
+		It("records the timings of the individual tests", func() {
+			var result []testing.TestTiming
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(yaml.Unmarshal([]byte(testTimings.Builder.String()), &result)).To(Succeed())
+			Expect(result).To(Equal([]testing.TestTiming{{
+				Name:     "a test",
+				Filepath: fmt.Sprintf("%d", GinkgoRandomSeed()),
+				Duration: duration,
+			}}))
+		})
+
+		It("stores the test results", func() {
+			Expect(err).ToNot(HaveOccurred())
+
+			fileSystem.MockOpen = func(name string) (fs.File, error) {
+				Expect(name).To(Equal(resultsPath))
+				return &mocks.File{Reader: strings.NewReader(results.Builder.String())}, nil
+			}
+
+			storedTestResults, err := client.GetTestResults(context.Background(), suiteID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storedTestResults.Framework).To(Equal(testResults.Framework))
+			Expect(storedTestResults.Tests).To(HaveLen(1))
+			Expect(storedTestResults.Tests[0].Location.File).To(Equal(fmt.Sprintf("%d", GinkgoRandomSeed())))
+		})
+	})
+
+	Describe("GetTestTimingManifest", func() {
+		It("migrates timings files that only hold the latest duration of each test file", func() {
+			timings.Reader = strings.NewReader("spec/a_spec.rb: 1.5s\nspec/b_spec.rb: 2s\n")
+
+			client, err = local.NewClient(
+				&fileSystem, flakesPath, quarantinesPath, timingsPath, resultsPath, testTimingsPath, partitionsPath, timingHistory,
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			manifest, err := client.GetTestTimingManifest(context.Background(), "suite-id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manifest).To(ConsistOf(
+				testing.TestFileTiming{Filepath: "spec/a_spec.rb", Duration: 1500 * time.Millisecond},
+				testing.TestFileTiming{Filepath: "spec/b_spec.rb", Duration: 2 * time.Second},
+			))
+		})
+	})
+
+	Describe("GetTestTimings", func() {
+		It("doesn't know any test timings before they were recorded", func() {
+			recordedTestTimings, err := client.GetTestTimings(context.Background(), "suite-id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recordedTestTimings).To(BeEmpty())
+		})
+
+		Context("with recorded test timings", func() {
+			BeforeEach(func() {
+				testTimings.Reader = strings.NewReader(`- id: ./spec/b_spec.rb[1:1]
+  name: b
+  file: spec/b_spec.rb
+  duration: 2s
+- id: ./spec/a_spec.rb[1:1]
+  name: a
+  lineage: [A, a]
+  file: spec/a_spec.rb
+  duration: 1.5s`)
+
+				fileSystem.MockOpen = func(name string) (fs.File, error) {
+					switch name {
+					case flakesPath:
+						return &flakes, nil
+					case quarantinesPath:
+						return &quarantines, nil
+					case timingsPath:
+						return &timings, nil
+					case testTimingsPath:
+						return &testTimings, nil
+					default:
+						return nil, os.ErrNotExist
+					}
+				}
+
+				client, err = local.NewClient(
+					&fileSystem, flakesPath, quarantinesPath, timingsPath, resultsPath, testTimingsPath, partitionsPath, timingHistory,
+				)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns the test timings sorted by file", func() {
+				recordedTestTimings, err := client.GetTestTimings(context.Background(), "suite-id")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(recordedTestTimings).To(Equal([]testing.TestTiming{
+					{
+						ID:       "./spec/a_spec.rb[1:1]",
+						Name:     "a",
+						Lineage:  []string{"A", "a"},
+						Filepath: "spec/a_spec.rb",
+						Duration: 1500 * time.Millisecond,
+					},
+					{ID: "./spec/b_spec.rb[1:1]", Name: "b", Filepath: "spec/b_spec.rb", Duration: 2 * time.Second},
+				}))
+			})
+		})
+	})
+
+	Describe("GetTestResults", func() {
+		It("errs when no test results were stored yet", func() {
+			_, err := client.GetTestResults(context.Background(), "suite-id")
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		})
+
+		It("returns the stored test results", func() {
+			storedTestResults, err := json.Marshal(v1.NewTestResults(v1.RubyRSpecFramework, []v1.Test{
+				{Name: "first", Attempt: v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()}},
+				{Name: "second", Attempt: v1.TestAttempt{Status: v1.NewFailedTestStatus(nil, nil, nil)}},
+			}, nil))
+			Expect(err).ToNot(HaveOccurred())
+
+			fileSystem.MockOpen = func(name string) (fs.File, error) {
+				Expect(name).To(Equal(resultsPath))
+				return &mocks.File{Reader: strings.NewReader(string(storedTestResults))}, nil
+			}
+
+			testResults, err := client.GetTestResults(context.Background(), "suite-id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(testResults.Summary.Tests).To(Equal(2))
 		})
 	})
 
@@ -150,7 +420,9 @@ var _ = Describe("local backend client", func() {
 					}
 				}
 
-				client, err = local.NewClient(&fileSystem, flakesPath, quarantinesPath, timingsPath)
+				client, err = local.NewClient(
+					&fileSystem, flakesPath, quarantinesPath, timingsPath, resultsPath, testTimingsPath, partitionsPath, timingHistory,
+				)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -191,7 +463,9 @@ var _ = Describe("local backend client", func() {
 					}
 				}
 
-				client, err = local.NewClient(&fileSystem, flakesPath, quarantinesPath, timingsPath)
+				client, err = local.NewClient(
+					&fileSystem, flakesPath, quarantinesPath, timingsPath, resultsPath, testTimingsPath, partitionsPath, timingHistory,
+				)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -0,0 +1,66 @@
+package local
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+)
+
+// PartitionAssignments records which partition every test file was assigned to, so that sticky partitioning can keep
+// test files on the same partition across runs
+type PartitionAssignments struct {
+	Total     int            `yaml:"total"`
+	TestFiles map[string]int `yaml:"test-files"`
+}
+
+// readPartitionAssignments reads the previous partition assignments. These are only written once a partitioning
+// updates them, so a missing file simply means that there are no previous assignments yet.
+func (c Client) readPartitionAssignments() (PartitionAssignments, error) {
+	assignments := PartitionAssignments{TestFiles: make(map[string]int)}
+	if c.partitionsPath == "" {
+		return assignments, nil
+	}
+
+	fd, err := c.fs.Open(c.partitionsPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return assignments, nil
+		}
+
+		return assignments, errors.Wrap(err, fmt.Sprintf("unable to open %q", c.partitionsPath))
+	}
+	defer fd.Close()
+
+	if err := yaml.NewDecoder(fd).Decode(&assignments); err != nil && !errors.Is(err, io.EOF) {
+		return assignments, errors.Wrap(err, fmt.Sprintf("unable to read %q", c.partitionsPath))
+	}
+
+	if assignments.TestFiles == nil {
+		assignments.TestFiles = make(map[string]int)
+	}
+
+	return assignments, nil
+}
+
+// UpdatePartitionAssignments stores the partition assignments for the next partitioning
+func (c Client) UpdatePartitionAssignments(assignments PartitionAssignments) error {
+	if c.partitionsPath == "" {
+		return errors.NewInternalError("no path is configured for the partition assignments")
+	}
+
+	file, err := c.fs.Create(c.partitionsPath)
+	if err != nil {
+		return errors.NewSystemError("unable to open %q: %s", c.partitionsPath, err)
+	}
+	defer file.Close()
+
+	if err := yaml.NewEncoder(file).Encode(assignments); err != nil {
+		return errors.NewSystemError("unable to write to %q: %s", c.partitionsPath, err)
+	}
+
+	return nil
+}
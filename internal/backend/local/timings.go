@@ -0,0 +1,97 @@
+package local
+
+import (
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+)
+
+const (
+	DefaultTimingWindow        = 5
+	DefaultTimingMaxUnseenRuns = 10
+)
+
+// TimingHistory configures how many runs the timings of a test file are smoothed over
+type TimingHistory struct {
+	// Window is the number of most recent durations of a test file that are averaged
+	Window int
+	// MaxUnseenRuns is the number of consecutive runs without a test file after which its timings are dropped. Zero
+	// keeps the timings of test files forever.
+	MaxUnseenRuns int
+}
+
+func (th TimingHistory) window() int {
+	if th.Window < 1 {
+		return DefaultTimingWindow
+	}
+
+	return th.Window
+}
+
+// FileTiming holds the most recent durations of a test file, oldest first
+type FileTiming struct {
+	Durations  []time.Duration `yaml:"durations,flow"`
+	UnseenRuns int             `yaml:"unseen-runs,omitempty"`
+}
+
+// Duration returns the average of the recorded durations
+func (ft FileTiming) Duration() time.Duration {
+	if len(ft.Durations) == 0 {
+		return 0
+	}
+
+	var total time.Duration
+	for _, duration := range ft.Durations {
+		total += duration
+	}
+
+	return total / time.Duration(len(ft.Durations))
+}
+
+// UnmarshalYAML reads both the current format and the flat `<file>: <duration>` format of earlier versions of
+// Captain, which is migrated to a history with a single duration.
+func (ft *FileTiming) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var duration time.Duration
+		if err := node.Decode(&duration); err != nil {
+			return errors.WithStack(err)
+		}
+
+		*ft = FileTiming{Durations: []time.Duration{duration}}
+		return nil
+	}
+
+	type plain FileTiming
+	return errors.WithStack(node.Decode((*plain)(ft)))
+}
+
+// recordTimings adds the durations of the test files that were part of a run to their history. When the run covered the
+// whole test suite, the timings of all other test files are aged, as they're likely gone.
+func (c Client) recordTimings(durations map[string]time.Duration, ageUnseen bool) {
+	window := c.timingHistory.window()
+
+	for file, fileTiming := range c.Timings {
+		if _, ok := durations[file]; ok || !ageUnseen {
+			continue
+		}
+
+		fileTiming.UnseenRuns++
+		if c.timingHistory.MaxUnseenRuns > 0 && fileTiming.UnseenRuns >= c.timingHistory.MaxUnseenRuns {
+			delete(c.Timings, file)
+			continue
+		}
+
+		c.Timings[file] = fileTiming
+	}
+
+	for file, duration := range durations {
+		history := append(c.Timings[file].Durations, duration)
+		if len(history) > window {
+			history = history[len(history)-window:]
+		}
+
+		c.Timings[file] = FileTiming{Durations: history}
+	}
+}
@@ -83,11 +83,42 @@ func (c Client) GetTestTimingManifest(
 	ctx context.Context,
 	testSuiteIdentifier string,
 ) ([]testing.TestFileTiming, error) {
+	manifest, err := c.getTimingManifest(ctx, testSuiteIdentifier, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest.FileTimings, nil
+}
+
+// GetTestTimings returns the timings of individual tests, as used when splitting test files across partitions
+func (c Client) GetTestTimings(
+	ctx context.Context,
+	testSuiteIdentifier string,
+) ([]testing.TestTiming, error) {
+	manifest, err := c.getTimingManifest(ctx, testSuiteIdentifier, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest.TestTimings, nil
+}
+
+type timingManifest struct {
+	FileTimings []testing.TestFileTiming `json:"file_timings"`
+	TestTimings []testing.TestTiming     `json:"test_timings"`
+}
+
+func (c Client) getTimingManifest(
+	ctx context.Context,
+	testSuiteIdentifier string,
+	includeTestTimings bool,
+) (timingManifest, error) {
 	endpoint := hostEndpointCompat(c, "/api/test_suites/timing_manifest")
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
-		return nil, errors.NewInternalError("unable to construct HTTP request: %s", err)
+		return timingManifest{}, errors.NewInternalError("unable to construct HTTP request: %s", err)
 	}
 
 	queryValues := req.URL.Query()
@@ -97,28 +128,28 @@ func (c Client) GetTestTimingManifest(
 	} else {
 		queryValues.Add("commit_sha", c.Provider.CommitSha)
 	}
+	if includeTestTimings {
+		queryValues.Add("include_test_timings", "true")
+	}
 	req.URL.RawQuery = queryValues.Encode()
 
 	resp, err := c.RoundTrip(req)
 	if err != nil {
-		return nil, err
+		return timingManifest{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return nil, errors.NewInternalError(
+		return timingManifest{}, errors.NewInternalError(
 			"API backend encountered an error. Endpoint was %q, Status Code %d",
 			endpoint,
 			resp.StatusCode,
 		)
 	}
 
-	respBody := struct {
-		FileTimings []testing.TestFileTiming `json:"file_timings"`
-	}{}
-
-	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
-		return nil, errors.NewInternalError(
+	var manifest timingManifest
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return timingManifest{}, errors.NewInternalError(
 			"unable to parse the response body. Endpoint was %q, Content-Type %q. Original Error: %s",
 			endpoint,
 			resp.Header.Get(headerContentType),
@@ -126,7 +157,7 @@ func (c Client) GetTestTimingManifest(
 		)
 	}
 
-	return respBody.FileTimings, nil
+	return manifest, nil
 }
 
 func (c Client) logError(err error) error {
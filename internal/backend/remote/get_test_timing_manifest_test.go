@@ -9,6 +9,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/rwx-research/captain-cli/internal/backend/remote"
+	"github.com/rwx-research/captain-cli/internal/testing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -86,3 +87,59 @@ var _ = Describe("GetTestTimingManifest", func() {
 		})
 	})
 })
+
+var _ = Describe("GetTestTimings", func() {
+	var (
+		apiClient        remote.Client
+		mockRoundTripper func(*http.Request) (*http.Response, error)
+	)
+
+	JustBeforeEach(func() {
+		apiClientConfig := remote.ClientConfig{Log: zap.NewNop().Sugar(), Host: "cloud.rwx.com"}
+		apiClient = remote.Client{ClientConfig: apiClientConfig, RoundTrip: mockRoundTripper}
+	})
+
+	Context("when the response is successful", func() {
+		BeforeEach(func() {
+			mockRoundTripper = func(req *http.Request) (*http.Response, error) {
+				var resp http.Response
+
+				Expect(req.Method).To(Equal(http.MethodGet))
+				Expect(req.URL.Path).To(HaveSuffix("/captain/api/test_suites/timing_manifest"))
+				Expect(req.URL.Query().Get("test_suite_identifier")).To(Equal("test-suite-id"))
+				Expect(req.URL.Query().Get("include_test_timings")).To(Equal("true"))
+
+				resp.Body = io.NopCloser(strings.NewReader(`
+					{
+						"file_timings": [
+							{ "file_path": "spec/a_spec.rb", "duration_in_nanoseconds": 300 }
+						],
+						"test_timings": [
+							{
+								"id": "./spec/a_spec.rb[1:1]",
+								"name": "A does something",
+								"lineage": ["A", "does something"],
+								"file_path": "spec/a_spec.rb",
+								"duration_in_nanoseconds": 200
+							}
+						]
+					}
+				`))
+				resp.StatusCode = 200
+				return &resp, nil
+			}
+		})
+
+		It("returns the timings of the individual tests", func() {
+			testTimings, err := apiClient.GetTestTimings(context.Background(), "test-suite-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testTimings).To(Equal([]testing.TestTiming{{
+				ID:       "./spec/a_spec.rb[1:1]",
+				Name:     "A does something",
+				Lineage:  []string{"A", "does something"},
+				Filepath: "spec/a_spec.rb",
+				Duration: 200,
+			}}))
+		})
+	})
+})
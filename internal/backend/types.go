@@ -12,6 +12,7 @@ type Client interface {
 	GetRunConfiguration(ctx context.Context, testSuiteIdentifier string) (RunConfiguration, error)
 	GetQuarantinedTests(ctx context.Context, testSuiteIdentifier string) ([]Test, error)
 	GetTestTimingManifest(context.Context, string) ([]testing.TestFileTiming, error)
+	GetTestTimings(context.Context, string) ([]testing.TestTiming, error)
 	UpdateTestResults(context.Context, string, v1.TestResults) ([]TestResultsUploadResult, error)
 }
 
@@ -0,0 +1,497 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/runpartition"
+	"github.com/rwx-research/captain-cli/internal/targetedretries"
+	"github.com/rwx-research/captain-cli/internal/templating"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// bisectCandidate is a unit that can be included in or excluded from a bisection step. Depending on the command
+// template, this is either a single test or a test file with all of its tests.
+type bisectCandidate struct {
+	// position is the order in which the candidate originally ran
+	position int
+	name     string
+	tests    []v1.Test
+	// file is set when the candidate consists of all tests of a file
+	file bool
+}
+
+func (c bisectCandidate) kind() string {
+	if c.file {
+		return "test file"
+	}
+
+	return "test"
+}
+
+// bisectCommand assembles the command that runs the given candidates, followed by the failing test. The returned
+// clean up function removes any temporary files the command depends on.
+type bisectCommand func(candidates []bisectCandidate) (command string, cleanUp func(), err error)
+
+// bisection holds the state of a single `captain bisect` invocation
+type bisection struct {
+	cfg     BisectConfig
+	victim  v1.Test
+	command bisectCommand
+	stdout  io.Writer
+	steps   int
+	// kind describes the candidates in the output, e.g. "test"
+	kind string
+	// warnedAboutOrder is set once Captain noticed that the tests did not run in the given order
+	warnedAboutOrder bool
+}
+
+// Bisect finds the smallest set of tests that need to run before a test in order for it to fail. It does so by
+// repeatedly running subsets of the tests that ran before the failing test in the original run.
+func (s Service) Bisect(ctx context.Context, cfg BisectConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	testResults, err := s.readTestResultsFile(cfg.ResultsPath)
+	if err != nil {
+		return err
+	}
+
+	victim, precedingTests, err := bisectVictim(*testResults, cfg.Test)
+	if err != nil {
+		return err
+	}
+
+	var command bisectCommand
+	var candidates []bisectCandidate
+	if cfg.RetryCommandTemplate != "" {
+		command, err = s.bisectRetryCommand(cfg, testResults.Framework, victim)
+		candidates = bisectCandidatesByTest(precedingTests)
+	} else {
+		command, err = s.bisectPartitionCommand(cfg, victim)
+		candidates = bisectCandidatesByFile(precedingTests, victim)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(candidates) == 0 {
+		return errors.NewInputError("No tests ran before %q, so its failure cannot depend on them", victim.Name)
+	}
+
+	var stdout io.Writer = os.Stdout
+	if cfg.Quiet {
+		stdout = io.Discard
+	}
+
+	// Commands are subject to interrupts, which would otherwise not reach them as they run in their own process group
+	interruptibleCtx, stopInterrupts := s.withInterrupts(ctx)
+	defer stopInterrupts()
+
+	b := &bisection{cfg: cfg, victim: victim, command: command, stdout: stdout, kind: candidates[0].kind()}
+
+	s.Log.Infoln(fmt.Sprintf(
+		"Bisecting the %v %v that ran before %q",
+		len(candidates),
+		pluralize(len(candidates), b.kind, b.kind+"s"),
+		victim.Name,
+	))
+
+	fails, err := s.bisectStep(interruptibleCtx, b, nil)
+	if err != nil {
+		return err
+	}
+	if fails {
+		return errors.NewInputError(
+			"%q also fails when it runs on its own, so its failure does not depend on the tests that ran before it",
+			victim.Name,
+		)
+	}
+
+	fails, err = s.bisectStep(interruptibleCtx, b, candidates)
+	if err != nil {
+		return err
+	}
+	if !fails {
+		return errors.NewInputError(
+			"%q passes when it runs after all of the tests that ran before it. Unable to reproduce the failure.",
+			victim.Name,
+		)
+	}
+
+	polluters, err := s.minimizePolluters(interruptibleCtx, b, candidates, nil)
+	if err != nil {
+		return err
+	}
+
+	// The reproduction is meant to be run by the user, so any temporary files it depends on are kept around
+	reproduction, _, err := command(polluters)
+	if err != nil {
+		return err
+	}
+
+	s.Log.Infoln(fmt.Sprintf(
+		"\nFound the minimal set of %v %v that %q depends on after %v %v:",
+		len(polluters),
+		pluralize(len(polluters), b.kind, b.kind+"s"),
+		victim.Name,
+		b.steps,
+		pluralize(b.steps, "step", "steps"),
+	))
+	for _, polluter := range polluters {
+		s.Log.Infoln(fmt.Sprintf("- %v", polluter.name))
+	}
+	s.Log.Infoln(fmt.Sprintf("\nReproduce the failure using:\n  %v", strings.Join(append(
+		[]string{reproduction},
+		cfg.Args...,
+	), " ")))
+
+	return nil
+}
+
+// minimizePolluters returns the smallest subset of `candidates` that, together with `required`, makes the failing
+// test fail. When neither half of the candidates is sufficient on its own, both halves are minimized separately while
+// keeping the other one in place.
+func (s Service) minimizePolluters(
+	ctx context.Context,
+	b *bisection,
+	candidates []bisectCandidate,
+	required []bisectCandidate,
+) ([]bisectCandidate, error) {
+	if len(candidates) <= 1 {
+		return candidates, nil
+	}
+
+	first, second := candidates[:len(candidates)/2], candidates[len(candidates)/2:]
+
+	fails, err := s.bisectStep(ctx, b, concatCandidates(required, first))
+	if err != nil {
+		return nil, err
+	}
+	if fails {
+		return s.minimizePolluters(ctx, b, first, required)
+	}
+
+	fails, err = s.bisectStep(ctx, b, concatCandidates(required, second))
+	if err != nil {
+		return nil, err
+	}
+	if fails {
+		return s.minimizePolluters(ctx, b, second, required)
+	}
+
+	minimizedFirst, err := s.minimizePolluters(ctx, b, first, concatCandidates(required, second))
+	if err != nil {
+		return nil, err
+	}
+
+	minimizedSecond, err := s.minimizePolluters(ctx, b, second, concatCandidates(required, minimizedFirst))
+	if err != nil {
+		return nil, err
+	}
+
+	return concatCandidates(minimizedFirst, minimizedSecond), nil
+}
+
+// bisectStep runs the given candidates followed by the failing test and reports whether the failing test failed
+func (s Service) bisectStep(ctx context.Context, b *bisection, candidates []bisectCandidate) (bool, error) {
+	if ctx.Err() != nil {
+		return false, errors.WithStack(context.Cause(ctx))
+	}
+
+	b.steps++
+
+	command, cleanUp, err := b.command(candidates)
+	if err != nil {
+		return false, err
+	}
+	defer cleanUp()
+
+	args, err := commandArgs(command, b.cfg.Args)
+	if err != nil {
+		return false, err
+	}
+
+	s.Log.Infoln(fmt.Sprintf(
+		"- Step %v: running %q after %v %v",
+		b.steps,
+		b.victim.Name,
+		len(candidates),
+		pluralize(len(candidates), b.kind, b.kind+"s"),
+	))
+
+	startedAt := time.Now()
+	_, cmdErr := s.runCommand(ctx, args, commandOptions{stdout: b.stdout, env: []string{}})
+	if isTerminationError(cmdErr) {
+		return false, errors.WithStack(cmdErr)
+	}
+
+	testResults, _, _, _, err := s.handleCommandOutcome(
+		RunConfig{TestResultsFileGlob: b.cfg.TestResultsFileGlob},
+		cmdErr,
+		0,
+		startedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if testResults != nil {
+		for i, test := range testResults.Tests {
+			if test.Matches(b.victim) {
+				s.checkBisectOrder(b, testResults.Tests[i+1:], candidates)
+
+				if test.Attempt.Status.ImpliesFailure() {
+					s.Log.Infoln(fmt.Sprintf("  %q failed", b.victim.Name))
+					return true, nil
+				}
+
+				s.Log.Infoln(fmt.Sprintf("  %q passed", b.victim.Name))
+				return false, nil
+			}
+		}
+	}
+
+	return false, errors.NewInputError(
+		"Captain could not find %q in the test results of %q. Please make sure that the command template runs the "+
+			"given tests and that the test results are written to %q.",
+		b.victim.Name,
+		command,
+		b.cfg.TestResultsFileGlob,
+	)
+}
+
+// checkBisectOrder warns when any of the candidates ran after the failing test. Bisecting relies on the tests running
+// in the given order, which frameworks that randomize the order (e.g. RSpec's random order or pytest-randomly) or that
+// schedule test files themselves (e.g. Jest) don't guarantee.
+func (s Service) checkBisectOrder(b *bisection, testsAfterVictim []v1.Test, candidates []bisectCandidate) {
+	if b.warnedAboutOrder {
+		return
+	}
+
+	for _, test := range testsAfterVictim {
+		for _, candidate := range candidates {
+			for _, candidateTest := range candidate.tests {
+				if !test.Matches(candidateTest) {
+					continue
+				}
+
+				b.warnedAboutOrder = true
+				s.Log.Warnf(
+					"%q ran after %q, so the tests did not run in the given order. The outcome of the bisection is "+
+						"unreliable unless the test framework runs the tests in a fixed order, e.g. without random "+
+						"ordering.",
+					test.Name,
+					b.victim.Name,
+				)
+				return
+			}
+		}
+	}
+}
+
+// bisectRetryCommand bisects individual tests using the retry command template
+func (s Service) bisectRetryCommand(
+	cfg BisectConfig,
+	framework v1.Framework,
+	victim v1.Test,
+) (bisectCommand, error) {
+	compiledTemplate, err := templating.CompileTemplate(cfg.RetryCommandTemplate)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	substitution, err := s.retrySubstitution(cfg.SubstitutionsByFramework, framework, compiledTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(candidates []bisectCandidate) (string, func(), error) {
+		tests := make([]v1.Test, 0, len(candidates)+1)
+		for _, candidate := range candidates {
+			tests = append(tests, candidate.tests...)
+		}
+		tests = append(tests, victim)
+
+		allSubstitutions, err := substitution.SubstitutionsFor(
+			compiledTemplate,
+			*v1.NewTestResults(framework, tests, nil),
+			func(v1.Test) bool { return true },
+		)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "Unable construct bisect substitutions")
+		}
+
+		cleanUp := func() {
+			if cleanableSubstitution, ok := substitution.(targetedretries.CleanableSubstitution); ok {
+				if err := cleanableSubstitution.CleanUp(allSubstitutions); err != nil {
+					s.Log.Warn(err)
+				}
+			}
+		}
+
+		if len(allSubstitutions) != 1 {
+			cleanUp()
+			return "", nil, errors.NewInputError(
+				"Bisecting requires a retry command that runs all tests in a single command, but %q results in %v commands",
+				cfg.RetryCommandTemplate,
+				len(allSubstitutions),
+			)
+		}
+
+		return compiledTemplate.Substitute(allSubstitutions[0]), cleanUp, nil
+	}, nil
+}
+
+// bisectPartitionCommand bisects whole test files using the partition command template
+func (s Service) bisectPartitionCommand(cfg BisectConfig, victim v1.Test) (bisectCommand, error) {
+	if victim.Location == nil || victim.Location.File == "" {
+		return nil, errors.NewInputError(
+			"Bisecting with the partition command requires the file of %q, but the test results do not include it",
+			victim.Name,
+		)
+	}
+
+	compiledTemplate, err := templating.CompileTemplate(cfg.PartitionCommandTemplate)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	delimiter := cfg.PartitionDelimiter
+	if delimiter == "" {
+		delimiter = " "
+	}
+
+	substitution := runpartition.DelimiterSubstitution{Delimiter: delimiter, FileSystem: s.FileSystem}
+	if err := substitution.ValidateTemplate(compiledTemplate); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return func(candidates []bisectCandidate) (string, func(), error) {
+		testFilePaths := make([]string, 0, len(candidates)+1)
+		for _, candidate := range candidates {
+			testFilePaths = append(testFilePaths, candidate.name)
+		}
+		testFilePaths = append(testFilePaths, victim.Location.File)
+
+		lookup, err := substitution.SubstitutionLookupFor(compiledTemplate, testFilePaths)
+		if err != nil {
+			return "", nil, errors.WithStack(err)
+		}
+
+		cleanUp := func() {
+			if err := substitution.CleanUp(lookup); err != nil {
+				s.Log.Warn(err)
+			}
+		}
+
+		return compiledTemplate.Substitute(lookup), cleanUp, nil
+	}, nil
+}
+
+// bisectVictim finds the failing test in the test results, together with all tests that started before it
+func bisectVictim(testResults v1.TestResults, identifier string) (v1.Test, []v1.Test, error) {
+	matches := make([]v1.Test, 0)
+	for _, test := range testResults.Tests {
+		if (test.ID != nil && *test.ID == identifier) || test.Name == identifier {
+			matches = append(matches, test)
+		}
+	}
+
+	if len(matches) == 0 {
+		return v1.Test{}, nil, errors.NewInputError("Unable to find a test with the ID or name %q", identifier)
+	}
+
+	if len(matches) > 1 {
+		return v1.Test{}, nil, errors.NewInputError(
+			"Found %v tests with the ID or name %q. Please use an identifier that is unique.",
+			len(matches),
+			identifier,
+		)
+	}
+
+	victim := matches[0]
+	if victim.Attempt.StartedAt == nil {
+		return v1.Test{}, nil, errors.NewInputError(
+			"The test results do not include when %q started, which is necessary to determine the tests that ran "+
+				"before it",
+			victim.Name,
+		)
+	}
+
+	precedingTests := make([]v1.Test, 0)
+	for _, test := range testResults.Tests {
+		startedAt := test.Attempt.StartedAt
+		if startedAt == nil || test.Attempt.Status.ImpliesSkipped() || test.Matches(victim) {
+			continue
+		}
+
+		if startedAt.Before(*victim.Attempt.StartedAt) {
+			precedingTests = append(precedingTests, test)
+		}
+	}
+
+	sort.SliceStable(precedingTests, func(i, j int) bool {
+		return precedingTests[i].Attempt.StartedAt.Before(*precedingTests[j].Attempt.StartedAt)
+	})
+
+	return victim, precedingTests, nil
+}
+
+func bisectCandidatesByTest(tests []v1.Test) []bisectCandidate {
+	candidates := make([]bisectCandidate, 0, len(tests))
+	for i, test := range tests {
+		candidates = append(candidates, bisectCandidate{position: i, name: test.Name, tests: []v1.Test{test}})
+	}
+
+	return candidates
+}
+
+// bisectCandidatesByFile groups the tests by their file, in the order in which the files first ran. The file of the
+// failing test always runs, so it isn't a candidate itself.
+func bisectCandidatesByFile(tests []v1.Test, victim v1.Test) []bisectCandidate {
+	candidates := make([]bisectCandidate, 0)
+	positions := make(map[string]int)
+
+	for _, test := range tests {
+		if test.Location == nil || test.Location.File == "" || test.Location.File == victim.Location.File {
+			continue
+		}
+
+		if i, ok := positions[test.Location.File]; ok {
+			candidates[i].tests = append(candidates[i].tests, test)
+			continue
+		}
+
+		positions[test.Location.File] = len(candidates)
+		candidates = append(candidates, bisectCandidate{
+			position: len(candidates),
+			name:     test.Location.File,
+			tests:    []v1.Test{test},
+			file:     true,
+		})
+	}
+
+	return candidates
+}
+
+// concatCandidates combines sets of candidates, preserving the order in which they originally ran
+func concatCandidates(sets ...[]bisectCandidate) []bisectCandidate {
+	combined := make([]bisectCandidate, 0)
+	for _, set := range sets {
+		combined = append(combined, set...)
+	}
+
+	sort.SliceStable(combined, func(i, j int) bool {
+		return combined[i].position < combined[j].position
+	})
+
+	return combined
+}
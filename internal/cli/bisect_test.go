@@ -0,0 +1,290 @@
+package cli_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/rwx-research/captain-cli/internal/cli"
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/exec"
+	"github.com/rwx-research/captain-cli/internal/fs"
+	"github.com/rwx-research/captain-cli/internal/mocks"
+	"github.com/rwx-research/captain-cli/internal/parsing"
+	"github.com/rwx-research/captain-cli/internal/targetedretries"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bisect", func() {
+	var (
+		err          error
+		service      cli.Service
+		recordedLogs *observer.ObservedLogs
+		bisectConfig cli.BisectConfig
+		testResults  v1.TestResults
+		commands     [][]string
+		polluters    []string
+	)
+
+	newTest := func(id string, file string, startedAt time.Time) v1.Test {
+		return v1.Test{
+			ID:       &id,
+			Name:     id,
+			Location: &v1.Location{File: file},
+			Attempt: v1.TestAttempt{
+				Status:    v1.NewSuccessfulTestStatus(),
+				StartedAt: &startedAt,
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		err = nil
+		commands = make([][]string, 0)
+		polluters = []string{"c"}
+
+		var core zapcore.Core
+		core, recordedLogs = observer.New(zapcore.InfoLevel)
+		log := zaptest.NewLogger(GinkgoT(), zaptest.WrapOptions(
+			zap.WrapCore(func(_ zapcore.Core) zapcore.Core { return core }),
+		)).Sugar()
+
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		victim := newTest("victim", "victim_spec.rb", start.Add(10*time.Second))
+		victim.Attempt.Status = v1.NewFailedTestStatus(nil, nil, nil)
+		testResults = v1.TestResults{
+			Framework: v1.RubyRSpecFramework,
+			Tests: []v1.Test{
+				newTest("e", "e_spec.rb", start.Add(5*time.Second)),
+				newTest("a", "a_spec.rb", start.Add(1*time.Second)),
+				newTest("b", "b_spec.rb", start.Add(2*time.Second)),
+				victim,
+				newTest("c", "c_spec.rb", start.Add(3*time.Second)),
+				newTest("d", "d_spec.rb", start.Add(4*time.Second)),
+				newTest("f", "f_spec.rb", start.Add(20*time.Second)),
+			},
+		}
+
+		mockFileSystem := new(mocks.FileSystem)
+		mockFileSystem.MockOpen = func(name string) (fs.File, error) {
+			file := new(mocks.File)
+			if name == "tmp/captain.json" {
+				encoded, err := json.Marshal(testResults)
+				Expect(err).NotTo(HaveOccurred())
+				file.Reader = strings.NewReader(string(encoded))
+			} else {
+				file.Reader = strings.NewReader("")
+			}
+			return file, nil
+		}
+		mockFileSystem.MockGlob = func(pattern string) ([]string, error) {
+			return []string{pattern}, nil
+		}
+
+		mockTaskRunner := new(mocks.TaskRunner)
+		mockTaskRunner.MockNewCommand = func(_ context.Context, cfg exec.CommandConfig) (exec.Command, error) {
+			commands = append(commands, append([]string{cfg.Name}, cfg.Args...))
+			return &mocks.Command{
+				MockStart: func() error { return nil },
+				MockWait:  func() error { return nil },
+			}, nil
+		}
+
+		// The victim fails whenever all of the polluters ran before it
+		mockParser := new(mocks.Parser)
+		mockParser.MockParse = func(_ io.Reader) (*v1.TestResults, error) {
+			lastCommand := commands[len(commands)-1]
+
+			victim := testResults.Tests[3]
+			victim.Attempt.Status = v1.NewFailedTestStatus(nil, nil, nil)
+			for _, polluter := range polluters {
+				if !slices.ContainsFunc(lastCommand, func(arg string) bool { return strings.HasPrefix(arg, polluter) }) {
+					victim.Attempt.Status = v1.NewSuccessfulTestStatus()
+				}
+			}
+
+			return &v1.TestResults{Framework: v1.RubyRSpecFramework, Tests: []v1.Test{victim}}, nil
+		}
+
+		service = cli.Service{
+			Log:        log,
+			FileSystem: mockFileSystem,
+			TaskRunner: mockTaskRunner,
+			ParseConfig: parsing.Config{
+				MutuallyExclusiveParsers: []parsing.Parser{mockParser},
+				Logger:                   log,
+			},
+		}
+
+		bisectConfig = cli.BisectConfig{
+			ResultsPath:          "tmp/captain.json",
+			RetryCommandTemplate: "rspec {{ tests }}",
+			SubstitutionsByFramework: map[v1.Framework]targetedretries.Substitution{
+				v1.RubyRSpecFramework: new(targetedretries.RubyRSpecSubstitution),
+			},
+			SuiteID:             "test",
+			Test:                "victim",
+			TestResultsFileGlob: "tmp/rspec.json",
+			Quiet:               true,
+		}
+	})
+
+	JustBeforeEach(func() {
+		err = service.Bisect(context.Background(), bisectConfig)
+	})
+
+	logMessages := func() []string {
+		messages := make([]string, 0)
+		for _, log := range recordedLogs.All() {
+			messages = append(messages, log.Message)
+		}
+		return messages
+	}
+
+	Context("when a single test pollutes the failing test", func() {
+		It("finds the polluter", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logMessages()).To(ContainElement(ContainSubstring(
+				`Found the minimal set of 1 test that "victim" depends on`,
+			)))
+			Expect(logMessages()).To(ContainElement("- c"))
+			Expect(logMessages()).To(ContainElement(ContainSubstring("Reproduce the failure using:\n  rspec 'c' 'victim'")))
+		})
+
+		It("only considers the tests that ran before the failing test, in the order in which they ran", func() {
+			Expect(commands[0]).To(Equal([]string{"rspec", "victim"}))
+			Expect(commands[1]).To(Equal([]string{"rspec", "a", "b", "c", "d", "e", "victim"}))
+		})
+	})
+
+	Context("when the failing test depends on tests from both halves", func() {
+		BeforeEach(func() {
+			polluters = []string{"b", "e"}
+		})
+
+		It("finds all of the polluters", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logMessages()).To(ContainElement(ContainSubstring(
+				`Found the minimal set of 2 tests that "victim" depends on`,
+			)))
+			Expect(logMessages()).To(ContainElement(ContainSubstring("rspec 'b' 'e' 'victim'")))
+		})
+	})
+
+	Context("when bisecting test files", func() {
+		BeforeEach(func() {
+			bisectConfig.RetryCommandTemplate = ""
+			bisectConfig.PartitionCommandTemplate = "rspec {{ testFiles }}"
+		})
+
+		It("finds the polluting file", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(commands[1]).To(Equal([]string{
+				"rspec", "a_spec.rb", "b_spec.rb", "c_spec.rb", "d_spec.rb", "e_spec.rb", "victim_spec.rb",
+			}))
+			Expect(logMessages()).To(ContainElement(ContainSubstring(
+				`Found the minimal set of 1 test file that "victim" depends on`,
+			)))
+			Expect(logMessages()).To(ContainElement("- c_spec.rb"))
+		})
+	})
+
+	Context("when the tests don't run in the given order", func() {
+		BeforeEach(func() {
+			mockParser := service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser)
+			parse := mockParser.MockParse
+			mockParser.MockParse = func(r io.Reader) (*v1.TestResults, error) {
+				results, err := parse(r)
+				if err != nil {
+					return nil, err
+				}
+
+				// The tests that were supposed to run before the failing test ran after it instead
+				lastCommand := commands[len(commands)-1]
+				for _, test := range testResults.Tests {
+					if test.Name != "victim" && slices.Contains(lastCommand, test.Name) {
+						results.Tests = append(results.Tests, test)
+					}
+				}
+
+				return results, nil
+			}
+		})
+
+		It("warns about the unreliable outcome once", func() {
+			Expect(err).NotTo(HaveOccurred())
+
+			warnings := make([]string, 0)
+			for _, log := range recordedLogs.FilterLevelExact(zapcore.WarnLevel).All() {
+				warnings = append(warnings, log.Message)
+			}
+			Expect(warnings).To(HaveLen(1))
+			Expect(warnings[0]).To(ContainSubstring(`"e" ran after "victim", so the tests did not run in the given order`))
+		})
+	})
+
+	Context("when the test also fails on its own", func() {
+		BeforeEach(func() {
+			polluters = []string{}
+			testResults.Tests[3].Attempt.Status = v1.NewFailedTestStatus(nil, nil, nil)
+			service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(_ io.Reader) (
+				*v1.TestResults,
+				error,
+			) {
+				return &v1.TestResults{Framework: v1.RubyRSpecFramework, Tests: []v1.Test{testResults.Tests[3]}}, nil
+			}
+		})
+
+		It("stops after the first step", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("also fails when it runs on its own"))
+			Expect(commands).To(HaveLen(1))
+		})
+	})
+
+	Context("when the failure cannot be reproduced", func() {
+		BeforeEach(func() {
+			polluters = []string{"f"}
+		})
+
+		It("errs", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unable to reproduce the failure"))
+		})
+	})
+
+	Context("when the test doesn't exist", func() {
+		BeforeEach(func() {
+			bisectConfig.Test = "missing"
+		})
+
+		It("errs", func() {
+			_, ok := errors.AsInputError(err)
+			Expect(ok).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(fmt.Sprintf("Unable to find a test with the ID or name %q", "missing")))
+		})
+	})
+
+	Context("when the configuration is incomplete", func() {
+		BeforeEach(func() {
+			bisectConfig.RetryCommandTemplate = ""
+		})
+
+		It("errs", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Missing command template"))
+		})
+	})
+})
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"io"
+	"path/filepath"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/fs"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+const (
+	logPathMetaKey           = "captain_log_path"
+	preRetryLogPathsMetaKey  = "captain_pre_retry_log_paths"
+	postRetryLogPathsMetaKey = "captain_post_retry_log_paths"
+)
+
+// commandLog is a log file in the intermediate artifacts that the output of a single command is teed into
+type commandLog struct {
+	path string
+	file fs.File
+}
+
+// createCommandLog creates the log file `name` in the logs directory of the current attempt's scope
+func (ias *IntermediateArtifactStorage) createCommandLog(name string) (*commandLog, error) {
+	logsPath := filepath.Join(ias.basePath, ias.attemptScope(), "logs")
+	if err := ias.fs.MkdirAll(logsPath, 0o750); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	path := filepath.Join(logsPath, name+".log")
+	file, err := ias.fs.Create(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &commandLog{path: path, file: file}, nil
+}
+
+// tee returns writers that write to the log file in addition to the given writers. Without a log, the writers are
+// returned as they are, so that a command still inherits a terminal. With a log, the command writes to a pipe instead,
+// which makes tools that check for a terminal turn off colors or interactive output.
+func (l *commandLog) tee(stdout, stderr io.Writer) (io.Writer, io.Writer) {
+	if l == nil {
+		return stdout, stderr
+	}
+
+	return io.MultiWriter(stdout, l.file), io.MultiWriter(stderr, l.file)
+}
+
+// openCommandLog creates a log file for the output of a command. Logs are only kept when there is an intermediate
+// artifacts path, as the intermediate artifacts are removed otherwise. Being unable to create the log is not fatal.
+func (s Service) openCommandLog(cfg RunConfig, ias *IntermediateArtifactStorage, name string) *commandLog {
+	if cfg.IntermediateArtifactsPath == "" || ias == nil {
+		return nil
+	}
+
+	log, err := ias.createCommandLog(name)
+	if err != nil {
+		s.Log.Warnf("Unable to create a log file for the output of the command: %s", err.Error())
+		return nil
+	}
+
+	return log
+}
+
+func (s Service) closeCommandLog(log *commandLog) {
+	if log == nil {
+		return
+	}
+
+	if err := log.file.Close(); err != nil {
+		s.Log.Warnf("Unable to close log file %q: %s", log.path, err.Error())
+	}
+}
+
+// recordCommandLog links test results to the log of the command that produced them. Besides the test results' meta,
+// the log is referenced by every test attempt and other error, as only those survive merging with other test results.
+func recordCommandLog(testResults *v1.TestResults, log *commandLog) {
+	if testResults == nil || log == nil {
+		return
+	}
+
+	if testResults.Meta == nil {
+		testResults.Meta = map[string]any{}
+	}
+	testResults.Meta[logPathMetaKey] = log.path
+
+	for i, test := range testResults.Tests {
+		testResults.Tests[i] = test.Tag(v1.LogPathTag, log.path)
+	}
+
+	for i, otherError := range testResults.OtherErrors {
+		if otherError.Meta == nil {
+			testResults.OtherErrors[i].Meta = map[string]any{}
+		}
+		testResults.OtherErrors[i].Meta[logPathMetaKey] = log.path
+	}
+}
+
+// recordCommandLogPaths references the logs of auxiliary commands, like pre-retry commands, in the test results
+func recordCommandLogPaths(testResults *v1.TestResults, metaKey string, logs []*commandLog) {
+	if testResults == nil {
+		return
+	}
+
+	paths := make([]string, 0, len(logs))
+	for _, log := range logs {
+		if log != nil {
+			paths = append(paths, log.path)
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+
+	if testResults.Meta == nil {
+		testResults.Meta = map[string]any{}
+	}
+	testResults.Meta[metaKey] = paths
+}
@@ -2,8 +2,12 @@ package cli
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -15,35 +19,78 @@ import (
 
 // RunConfig holds the configuration for running a test suite (used by `RunSuite`)
 type RunConfig struct {
-	Args                        []string
-	CloudOrganizationSlug       string
-	Command                     string
-	TestResultsFileGlob         string
-	FailOnUploadError           bool
-	FailOnMisconfiguredRetry    bool
-	FailRetriesFast             bool
-	FlakyRetries                int
-	IntermediateArtifactsPath   string
-	AdditionalArtifactPaths     []string
-	MaxTestsToRetry             string
-	PostRetryCommands           []string
-	PreRetryCommands            []string
-	PrintSummary                bool
-	Quiet                       bool
-	Reporters                   map[string]Reporter
-	Retries                     int
-	RetryCommandTemplate        string
-	SuiteID                     string
-	SubstitutionsByFramework    map[v1.Framework]targetedretries.Substitution
-	UpdateStoredResults         bool
-	UploadResults               bool
-	PartitionCommandTemplate    string
-	PartitionConfig             PartitionConfig
-	PartitionRoundRobin         bool
-	PartitionTrimPrefix         string
+	Args                       []string
+	AttemptTimeout             time.Duration
+	CloudOrganizationSlug      string
+	Command                    string
+	DeleteStaleTestResults     bool
+	DryRun                     bool
+	DryRunFormat               string
+	DryRunResultsPath          string
+	TestResultsFileGlob        string
+	FailOnUploadError          bool
+	FailOnMisconfiguredRetry   bool
+	FailRetriesFast            bool
+	FlakyRetries               int
+	IntermediateArtifactsPath  string
+	AdditionalArtifactPaths    []string
+	MaxTestsToRetry            string
+	MaxTestDropPercent         *float64
+	MinTests                   int
+	PostRetryCommands          []string
+	PreRetryCommands           []string
+	PrintSummary               bool
+	Quiet                      bool
+	Repeat                     int
+	Reporters                  map[string]Reporter
+	Retries                    int
+	RetryBatchSize             int
+	RetryCommandTemplate       string
+	RetryConcurrency           int
+	RetryNeverIfMessageMatches []string
+	RetryOnlyIfMessageMatches  []string
+	RunTimeout                 time.Duration
+	SuiteID                    string
+	SubstitutionsByFramework   map[v1.Framework]targetedretries.Substitution
+	TerminationGracePeriod     time.Duration
+	UpdateStoredResults        bool
+	UploadResults              bool
+	PartitionCommandTemplate   string
+	PartitionConfig            PartitionConfig
+	PartitionRoundRobin        bool
+	PartitionTrimPrefix        string
+	// Parallel splits the test suite into that many partitions locally, which all run at the same time
+	Parallel int
+	// QueueURL is the URL of a `captain queue serve` coordinator to pull batches of test files from
+	QueueURL                    string
 	WriteRetryFailedTestsAction bool
 	DidRetryFailedTestsInMint   bool
 	QuarantinedTestRetries      int
+
+	// dryRunPlan collects the retry commands that would run instead of running them, used by `--dry-run`
+	dryRunPlan *DryRunPlan
+	// stdout and stderr are where commands write their output to instead of the terminal, e.g. to prefix the output
+	// of suites that run concurrently
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// commandStdout returns where commands write their standard output to
+func (rc RunConfig) commandStdout() io.Writer {
+	if rc.stdout != nil {
+		return rc.stdout
+	}
+
+	return os.Stdout
+}
+
+// commandStderr returns where commands write their standard error to
+func (rc RunConfig) commandStderr() io.Writer {
+	if rc.stderr != nil {
+		return rc.stderr
+	}
+
+	return os.Stderr
 }
 
 var maxTestsToRetryRegexp = regexp.MustCompile(
@@ -61,6 +108,44 @@ func (rc RunConfig) Validate(log *zap.SugaredLogger) error {
 		)
 	}
 
+	if err := validateDryRunFormat(rc.DryRunFormat); err != nil {
+		return err
+	}
+
+	if rc.DryRunResultsPath != "" && !rc.DryRun {
+		return errors.NewConfigurationError(
+			"Test results for a dry run without --dry-run",
+			"You have specified test results to plan the retries of a dry run, but --dry-run is not set.",
+			"Please set --dry-run as well, or remove the --dry-run-results flag.",
+		)
+	}
+
+	if rc.Repeat < 0 {
+		return errors.NewConfigurationError(
+			"Unsupported --repeat value",
+			fmt.Sprintf("The number of repeats cannot be negative, it is currently set to %d.", rc.Repeat),
+			"Set --repeat to the total number of times the tests should run.",
+		)
+	}
+
+	if rc.Repeat > 1 && rc.RetryCommandTemplate == "" {
+		return errors.NewConfigurationError(
+			"Missing retry command",
+			"You have asked Captain to repeat your tests, but there is no retry command template configured.",
+			"Captain uses the retry command template to run the tests again. It can be set using the --retry-command "+
+				"flag or in the Captain configuration file.",
+		)
+	}
+
+	if rc.Repeat > 1 && (rc.Retries > 0 || rc.FlakyRetries > 0 || rc.QuarantinedTestRetries > 0) {
+		return errors.NewConfigurationError(
+			"Retries cannot be combined with --repeat",
+			"You have asked Captain to repeat your tests, but retries are configured as well.",
+			"Repeating the tests already runs every test multiple times. Please disable retries when using --repeat, "+
+				"e.g. by setting --retries, --flaky-retries and --quarantined-test-retries to 0.",
+		)
+	}
+
 	if rc.MaxTestsToRetry != "" && !maxTestsToRetryRegexp.MatchString(rc.MaxTestsToRetry) {
 		return errors.NewConfigurationError(
 			"Unsupported --max-tests-to-retry value",
@@ -77,6 +162,35 @@ func (rc RunConfig) Validate(log *zap.SugaredLogger) error {
 		log.Warn("The --max-tests-to-retry flag has no effect as no retries are otherwise configured.")
 	}
 
+	if err := rc.validateTestCountGuard(); err != nil {
+		return err
+	}
+
+	if rc.AttemptTimeout < 0 || rc.RunTimeout < 0 || rc.TerminationGracePeriod < 0 {
+		return errors.NewConfigurationError(
+			"Unsupported timeout value",
+			"Timeouts and the termination grace period cannot be negative.",
+			"The timeouts can be set using the --attempt-timeout and --run-timeout flags, the grace period using the "+
+				"--termination-grace-period flag. Set a timeout to 0 to disable it.",
+		)
+	}
+
+	if rc.AttemptTimeout > 0 && rc.RunTimeout > 0 && rc.AttemptTimeout > rc.RunTimeout {
+		log.Warnf(
+			"The attempt timeout (%s) is longer than the run timeout (%s) and will have no effect.",
+			rc.AttemptTimeout,
+			rc.RunTimeout,
+		)
+	}
+
+	if _, err := newRetryMessageRules(rc.RetryOnlyIfMessageMatches, rc.RetryNeverIfMessageMatches); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := rc.validateRetryCommands(); err != nil {
+		return err
+	}
+
 	if len(rc.AdditionalArtifactPaths) > 0 && rc.IntermediateArtifactsPath == "" {
 		return errors.NewConfigurationError(
 			"Missing intermediate artifacts path",
@@ -86,6 +200,14 @@ func (rc RunConfig) Validate(log *zap.SugaredLogger) error {
 		)
 	}
 
+	if rc.QueueURL != "" {
+		return rc.validateQueue()
+	}
+
+	if rc.Parallel < 0 || rc.Parallel > 1 {
+		return rc.validateParallel()
+	}
+
 	if rc.PartitionCommandTemplate != "" && rc.PartitionConfig.PartitionNodes.Total <= 1 {
 		log.Warnf("There is a partition command configured for this test suite, but partitioning is disabled.")
 	}
@@ -100,6 +222,140 @@ func (rc RunConfig) Validate(log *zap.SugaredLogger) error {
 	return nil
 }
 
+// validateQueue validates running batches of test files from a queue, which takes the place of partitioning
+func (rc RunConfig) validateQueue() error {
+	if rc.Parallel != 0 {
+		return errors.NewConfigurationError(
+			"Conflicting partitioning options",
+			"Captain is unable to run batches of test files from a queue in parallel.",
+			"Please remove either the --parallel or the --queue-url flag. To process a queue faster, run more "+
+				"nodes with the same --queue-url instead.",
+		)
+	}
+
+	if rc.PartitionCommandTemplate == "" {
+		return errors.NewConfigurationError(
+			"Missing partition command",
+			"You have specified a queue URL, but no partition command is configured to run the batches of test files.",
+			"The partition command can be set using the --partition-command flag or in the Captain configuration file.",
+		)
+	}
+
+	if rc.DryRun {
+		return errors.NewConfigurationError(
+			"Unsupported dry run",
+			"Captain cannot print the commands of a run with a queue URL, as that would take batches off the queue.",
+			"Please remove either the --dry-run or the --queue-url flag.",
+		)
+	}
+
+	return nil
+}
+
+// validateParallel validates running several partitions of the test suite at the same time on the local machine
+func (rc RunConfig) validateParallel() error {
+	if rc.Parallel < 0 {
+		return errors.NewConfigurationError(
+			"Unsupported --parallel value",
+			fmt.Sprintf("The number of parallel workers cannot be negative, it is currently set to %d.", rc.Parallel),
+			"Set --parallel to the number of partitions that should run at the same time.",
+		)
+	}
+
+	if rc.PartitionCommandTemplate == "" {
+		return errors.NewConfigurationError(
+			"Missing partition command",
+			"You have asked Captain to run the test suite in parallel, but no partition command is configured to run "+
+				"the partitions.",
+			"The partition command can be set using the --partition-command flag or in the Captain configuration file.",
+		)
+	}
+
+	if rc.PartitionConfig.PartitionNodes.Total > 1 {
+		return errors.NewConfigurationError(
+			"Conflicting partitioning options",
+			"Captain is unable to run a partition of the test suite in parallel.",
+			"Please remove either the --parallel flag or the --partition-index and --partition-total flags.",
+		)
+	}
+
+	if rc.DryRun {
+		return errors.NewConfigurationError(
+			"Unsupported dry run",
+			"Captain cannot print the commands of a parallel run yet.",
+			"Please remove either the --dry-run or the --parallel flag.",
+		)
+	}
+
+	if rc.TestResultsFileGlob != "" && !strings.Contains(rc.TestResultsFileGlob, WorkerIndexEnvVar) {
+		return errors.NewConfigurationError(
+			"Parallel workers would overwrite each other's test results",
+			fmt.Sprintf(
+				"You have asked Captain to run %d parallel workers, but the test results path %q is the same for "+
+					"every worker.",
+				rc.Parallel,
+				rc.TestResultsFileGlob,
+			),
+			fmt.Sprintf(
+				"Make your test framework write its results to a path that includes the $%s environment variable "+
+					"and reference the same variable in the test results path (e.g. 'tmp/rspec-$%s.json').",
+				WorkerIndexEnvVar,
+				WorkerIndexEnvVar,
+			),
+		)
+	}
+
+	return errors.WithStack(rc.parallelPartitionConfig().Validate())
+}
+
+// parallelPartitionConfig is the partition config of the local workers of a parallel run. The sticky assignments and
+// the weights describe the partitions of CI nodes, so neither of them applies to local workers.
+func (rc RunConfig) parallelPartitionConfig() PartitionConfig {
+	partitionConfig := rc.PartitionConfig
+	partitionConfig.PartitionNodes = config.PartitionNodes{Index: 0, Total: rc.Parallel}
+	partitionConfig.Sticky = false
+	partitionConfig.StickyThreshold = nil
+
+	return partitionConfig
+}
+
+func (rc RunConfig) validateTestCountGuard() error {
+	if rc.MinTests < 0 {
+		return errors.NewConfigurationError(
+			"Unsupported --min-tests value",
+			fmt.Sprintf("The minimum number of tests cannot be negative, it is currently set to %d.", rc.MinTests),
+			"Set --min-tests to the number of tests that a run needs to report at least, or to 0 to disable the check.",
+		)
+	}
+
+	if rc.MaxTestDropPercent != nil && (*rc.MaxTestDropPercent < 0 || *rc.MaxTestDropPercent > 100) {
+		return errors.NewConfigurationError(
+			"Unsupported --max-test-drop-percent value",
+			fmt.Sprintf(
+				"The maximum drop in the number of tests is a percentage, but it is currently set to %v.",
+				*rc.MaxTestDropPercent,
+			),
+			"Set --max-test-drop-percent to a value between 0 and 100.",
+		)
+	}
+
+	if rc.guardsTestCount() && rc.TestResultsFileGlob == "" {
+		return errors.NewConfigurationError(
+			"Missing test results path",
+			"You have asked Captain to check the number of tests, but there is no test results path configured.",
+			"Captain counts the tests in the test results. The test results path can be set using the "+
+				"--test-results flag or in the Captain configuration file.",
+		)
+	}
+
+	return nil
+}
+
+// guardsTestCount is set when the run should fail if fewer tests ran than expected
+func (rc RunConfig) guardsTestCount() bool {
+	return rc.MinTests > 0 || rc.MaxTestDropPercent != nil
+}
+
 func (rc RunConfig) MaxTestsToRetryCount() (*int, error) {
 	if rc.MaxTestsToRetry == "" {
 		return nil, nil
@@ -158,12 +414,46 @@ func (rc RunConfig) IsRunningPartition() bool {
 }
 
 type PartitionConfig struct {
-	SuiteID        string
-	TestFilePaths  []string
-	Delimiter      string
+	SuiteID       string
+	TestFilePaths []string
+	Delimiter     string
+	DryRun        bool
+	DryRunFormat  string
+	// EstimateByFileSize scales the estimated timings of test files without historical timings by their file size
+	EstimateByFileSize bool
+	// Explain describes every partition together with how balanced it is, instead of only the partition at the index
+	Explain        bool
 	PartitionNodes config.PartitionNodes
-	RoundRobin     bool
-	TrimPrefix     string
+	// Packages partitions Go packages instead of test files, in which case the test file paths are `go list` patterns
+	// like `./...` and the partitions consist of import paths
+	Packages bool
+	// RoundRobin is a shorthand for the round-robin strategy
+	RoundRobin bool
+	// SplitTests splits test files that take longer than a partition should take into their individual tests
+	SplitTests bool
+	// Sticky keeps test files on the partition they were assigned to previously, until the partitions get too imbalanced
+	Sticky bool
+	// StickyThreshold is the imbalance percentage above which sticky partitions are rebalanced, 10 by default
+	StickyThreshold *float64
+	// Strategy is the partitioning strategy, greedy by default
+	Strategy PartitionStrategy
+	// TimingsFrom are globs of test results files, e.g. from earlier CI runs, that the timings are read from instead of
+	// the backend
+	TimingsFrom []string
+	TrimPrefix  string
+}
+
+// strategy returns the partitioning strategy that is configured, taking the round robin shorthand into account
+func (pc PartitionConfig) strategy() PartitionStrategy {
+	if pc.Strategy != "" {
+		return pc.Strategy
+	}
+
+	if pc.RoundRobin {
+		return PartitionStrategyRoundRobin
+	}
+
+	return PartitionStrategyGreedy
 }
 
 func (pc PartitionConfig) Validate() error {
@@ -175,6 +465,40 @@ func (pc PartitionConfig) Validate() error {
 		)
 	}
 
+	if pc.Strategy != "" {
+		if err := pc.Strategy.validate(); err != nil {
+			return err
+		}
+
+		if pc.RoundRobin && pc.Strategy != PartitionStrategyRoundRobin {
+			return errors.NewConfigurationError(
+				"Conflicting partitioning strategies",
+				fmt.Sprintf("Round robin partitioning was enabled together with the %q strategy.", string(pc.Strategy)),
+				"Please either remove the round robin option or the strategy.",
+			)
+		}
+	}
+
+	if pc.Sticky && !pc.strategy().usesTimings() {
+		return errors.NewConfigurationError(
+			"Unsupported sticky partitioning",
+			fmt.Sprintf("The %q strategy doesn't balance partitions by their timings.", string(pc.strategy())),
+			fmt.Sprintf(
+				"Please use sticky partitioning with either the %q or the %q strategy.",
+				string(PartitionStrategyGreedy),
+				string(PartitionStrategyKarmarkarKarp),
+			),
+		)
+	}
+
+	if pc.StickyThreshold != nil && *pc.StickyThreshold < 0 {
+		return errors.NewConfigurationError(
+			"Unsupported sticky threshold",
+			fmt.Sprintf("The sticky threshold needs to be a positive percentage, but it is %v.", *pc.StickyThreshold),
+			"Please set the sticky threshold to 0 or more.",
+		)
+	}
+
 	if pc.PartitionNodes.Total <= 0 {
 		return errors.NewConfigurationError(
 			"Missing total partition count",
@@ -207,6 +531,12 @@ func (pc PartitionConfig) Validate() error {
 		)
 	}
 
+	if pc.PartitionNodes.IsWeighted() {
+		if err := pc.validateWeights(); err != nil {
+			return err
+		}
+	}
+
 	if len(pc.TestFilePaths) == 0 {
 		return errors.NewConfigurationError(
 			"Missing test file paths",
@@ -219,9 +549,45 @@ func (pc PartitionConfig) Validate() error {
 		)
 	}
 
+	return validateDryRunFormat(pc.DryRunFormat)
+}
+
+// validateWeights validates that every partition has a weight, and that the strategy takes weights into account
+func (pc PartitionConfig) validateWeights() error {
+	if len(pc.PartitionNodes.Weights) != pc.PartitionNodes.Total {
+		return errors.NewConfigurationError(
+			"Mismatching partition weights",
+			fmt.Sprintf(
+				"You specified %d partition weights, but there are %d partitions.",
+				len(pc.PartitionNodes.Weights), pc.PartitionNodes.Total,
+			),
+			"Please specify exactly one weight per partition.",
+		)
+	}
+
+	if pc.strategy() == PartitionStrategyKarmarkarKarp {
+		return errors.NewConfigurationError(
+			"Unsupported partition weights",
+			fmt.Sprintf("The %q strategy doesn't support partition weights.", string(PartitionStrategyKarmarkarKarp)),
+			fmt.Sprintf("Please either remove the partition weights or use the %q strategy.", string(PartitionStrategyGreedy)),
+		)
+	}
+
 	return nil
 }
 
+func validateDryRunFormat(format string) error {
+	if format == "" || format == dryRunFormatText || format == dryRunFormatJSON {
+		return nil
+	}
+
+	return errors.NewConfigurationError(
+		"Unsupported --dry-run-format value",
+		fmt.Sprintf("Captain is unable to print a dry run as %q.", format),
+		fmt.Sprintf("Please set --dry-run-format to either %q or %q.", dryRunFormatText, dryRunFormatJSON),
+	)
+}
+
 type QuarantineConfig struct {
 	Args                []string
 	TestResultsFileGlob string
@@ -237,3 +603,188 @@ type MergeConfig struct {
 	PrintSummary bool
 	Reporters    map[string]Reporter
 }
+
+// PartitionVerifyConfig holds the configuration for verifying that every test file of a partitioned test suite ran
+// exactly once (used by `VerifyPartitions`)
+type PartitionVerifyConfig struct {
+	// PartitionConfig needs the same options as when partitioning the test suite, in order to recalculate which
+	// partition each test file was assigned to. The partition index is ignored.
+	PartitionConfig PartitionConfig
+	ResultsGlobs    []string
+}
+
+// partitionConfig returns the config for recalculating all partitions, without storing sticky partitions
+func (vc PartitionVerifyConfig) partitionConfig() PartitionConfig {
+	cfg := vc.PartitionConfig
+	cfg.PartitionNodes.Index = 0
+	cfg.DryRun = true
+	cfg.Explain = false
+
+	return cfg
+}
+
+func (vc PartitionVerifyConfig) Validate() error {
+	if len(vc.ResultsGlobs) == 0 {
+		return errors.NewConfigurationError(
+			"Missing test results",
+			"Captain needs the test results of all partitions in order to verify them.",
+			"Please specify the paths to the test results files of the partitions using the --results flag.",
+		)
+	}
+
+	return vc.partitionConfig().Validate()
+}
+
+// BisectConfig holds the configuration for finding the tests that a failing test depends on (used by `Bisect`)
+type BisectConfig struct {
+	Args                     []string
+	PartitionCommandTemplate string
+	PartitionDelimiter       string
+	Quiet                    bool
+	ResultsPath              string
+	RetryCommandTemplate     string
+	SubstitutionsByFramework map[v1.Framework]targetedretries.Substitution
+	SuiteID                  string
+	Test                     string
+	TestResultsFileGlob      string
+}
+
+func (bc BisectConfig) Validate() error {
+	if bc.ResultsPath == "" {
+		return errors.NewConfigurationError(
+			"Missing test results",
+			"Captain needs the test results of the run in which the test failed in order to bisect it.",
+			"Please specify the path to the RWX v1 test results using the --results flag.",
+		)
+	}
+
+	if bc.Test == "" {
+		return errors.NewConfigurationError(
+			"Missing test",
+			"Captain needs to know which test failed in order to bisect it.",
+			"Please specify the ID or name of the failing test using the --test flag.",
+		)
+	}
+
+	if bc.RetryCommandTemplate == "" && bc.PartitionCommandTemplate == "" {
+		return errors.NewConfigurationError(
+			"Missing command template",
+			"Captain needs a command template in order to run a subset of the tests.",
+			"Please configure either a retry command template using the --retry-command flag or a partition command "+
+				"template using the --partition-command flag. Alternatively, you can set them in the Captain "+
+				"configuration file.",
+		)
+	}
+
+	if bc.TestResultsFileGlob == "" {
+		return errors.NewConfigurationError(
+			"Missing test results path",
+			"Captain needs to read the test results of every command it runs while bisecting.",
+			"Please specify the path to the test results using the --test-results flag or in the Captain configuration "+
+				"file.",
+		)
+	}
+
+	return nil
+}
+
+// validateRetryCommands checks the options that control how a retry is split into retry commands and how these run
+func (rc RunConfig) validateRetryCommands() error {
+	if rc.RetryBatchSize < 0 {
+		return errors.NewConfigurationError(
+			"Unsupported --retry-batch-size value",
+			fmt.Sprintf("The retry batch size cannot be negative, it is currently set to %d.", rc.RetryBatchSize),
+			"Set the retry batch size to the maximum number of tests a single retry command should retry, or to 0 "+
+				"to retry all tests at once.",
+		)
+	}
+
+	if rc.RetryConcurrency < 0 {
+		return errors.NewConfigurationError(
+			"Unsupported --retry-concurrency value",
+			fmt.Sprintf("The retry concurrency cannot be negative, it is currently set to %d.", rc.RetryConcurrency),
+			"Set the retry concurrency to the number of retry commands that may run at the same time.",
+		)
+	}
+
+	if rc.RetryConcurrency > 1 && rc.TestResultsFileGlob != "" &&
+		!strings.Contains(rc.TestResultsFileGlob, RetryCommandIDEnvVar) {
+		return errors.NewConfigurationError(
+			"Retry commands would overwrite each other's test results",
+			fmt.Sprintf(
+				"You have configured a retry concurrency of %d, but the test results path %q is the same for every "+
+					"retry command.",
+				rc.RetryConcurrency,
+				rc.TestResultsFileGlob,
+			),
+			fmt.Sprintf(
+				"Make your test framework write its results to a path that includes the $%s environment variable "+
+					"and reference the same variable in the test results path (e.g. 'tmp/rspec-$%s.json').",
+				RetryCommandIDEnvVar,
+				RetryCommandIDEnvVar,
+			),
+		)
+	}
+
+	return nil
+}
+
+// RerunConfig holds the configuration for rerunning the failed tests of the stored test results (used by `Rerun`)
+type RerunConfig struct {
+	AttemptTimeout           time.Duration
+	FailOnMisconfiguredRetry bool
+	PostRetryCommands        []string
+	PreRetryCommands         []string
+	Quiet                    bool
+	RetryBatchSize           int
+	RetryCommandTemplate     string
+	RetryConcurrency         int
+	SubstitutionsByFramework map[v1.Framework]targetedretries.Substitution
+	SuiteID                  string
+	TerminationGracePeriod   time.Duration
+	TestResultsFileGlob      string
+}
+
+func (rc RerunConfig) Validate() error {
+	if rc.RetryCommandTemplate == "" {
+		return errors.NewConfigurationError(
+			"Missing retry command",
+			"Captain reruns the failed tests using the retry command template, but none is configured.",
+			"Please set 'retries.command' for this suite in the Captain configuration file or use the --retry-command "+
+				"flag.",
+		)
+	}
+
+	if rc.TestResultsFileGlob == "" {
+		return errors.NewConfigurationError(
+			"Missing test results path",
+			"Captain needs to read the test results of the rerun in order to update the stored test results.",
+			"Please specify the path to the test results using the --test-results flag or in the Captain configuration "+
+				"file.",
+		)
+	}
+
+	return RunConfig{
+		RetryBatchSize:      rc.RetryBatchSize,
+		RetryConcurrency:    rc.RetryConcurrency,
+		TestResultsFileGlob: rc.TestResultsFileGlob,
+	}.validateRetryCommands()
+}
+
+// runConfig returns the run configuration that the retry commands of a rerun are based on
+func (rc RerunConfig) runConfig() RunConfig {
+	return RunConfig{
+		AttemptTimeout:           rc.AttemptTimeout,
+		FailOnMisconfiguredRetry: rc.FailOnMisconfiguredRetry,
+		PostRetryCommands:        rc.PostRetryCommands,
+		PreRetryCommands:         rc.PreRetryCommands,
+		Quiet:                    rc.Quiet,
+		RetryBatchSize:           rc.RetryBatchSize,
+		RetryCommandTemplate:     rc.RetryCommandTemplate,
+		RetryConcurrency:         rc.RetryConcurrency,
+		SubstitutionsByFramework: rc.SubstitutionsByFramework,
+		SuiteID:                  rc.SuiteID,
+		TerminationGracePeriod:   rc.TerminationGracePeriod,
+		TestResultsFileGlob:      rc.TestResultsFileGlob,
+	}
+}
@@ -1,5 +1,7 @@
 package cli
 
+import "time"
+
 // configFile holds all options that can be set over the config file
 type ConfigFile struct {
 	Cloud struct {
@@ -21,13 +23,18 @@ type SuiteConfigOutput struct {
 }
 
 type SuiteConfigResults struct {
-	Framework string
-	Language  string
-	Path      string
+	DeleteStale        bool `yaml:"delete-stale"`
+	Framework          string
+	Language           string
+	MaxTestDropPercent *float64 `yaml:"max-test-drop-percent"`
+	MinTests           int      `yaml:"min-tests"`
+	Path               string
 }
 
 type SuiteConfigRetries struct {
 	Attempts                  int
+	AttemptTimeout            time.Duration `yaml:"attempt-timeout"`
+	BatchSize                 int           `yaml:"batch-size"`
 	Command                   string
 	FailFast                  bool     `yaml:"fail-fast"`
 	FailOnMisconfiguration    bool     `yaml:"fail-on-misconfiguration"`
@@ -39,23 +46,42 @@ type SuiteConfigRetries struct {
 	IntermediateArtifactsPath string   `yaml:"intermediate-artifacts-path"`
 	AdditionalArtifactPaths   []string `yaml:"additional-artifact-paths"`
 	QuarantinedAttempts       int      `yaml:"quarantined-attempts"`
+	Concurrency               int
+	OnlyIfMessageMatches      []string `yaml:"only-if-message-matches"`
+	NeverIfMessageMatches     []string `yaml:"never-if-message-matches"`
 }
 
 type SuiteConfigPartition struct {
-	Command    string
-	Globs      []string
-	Delimiter  string
-	RoundRobin bool   `yaml:"round-robin"`
-	TrimPrefix string `yaml:"trim-prefix"`
+	Command            string
+	Globs              []string
+	Delimiter          string
+	RoundRobin         bool   `yaml:"round-robin"`
+	TrimPrefix         string `yaml:"trim-prefix"`
+	SplitTests         bool   `yaml:"split-tests"`
+	Strategy           string
+	EstimateByFileSize bool `yaml:"estimate-by-file-size"`
+	Packages           bool
+	TimingsFrom        []string `yaml:"timings-from"`
+	Sticky             bool
+	StickyThreshold    *float64 `yaml:"sticky-threshold"`
+	Parallel           int
+}
+
+type SuiteConfigTimings struct {
+	Window        int
+	MaxUnseenRuns *int `yaml:"max-unseen-runs"`
 }
 
 // SuiteConfig holds options that can be customized per suite
 type SuiteConfig struct {
-	Command               string
-	FailOnUploadError     bool `yaml:"fail-on-upload-error"`
-	FailOnDuplicateTestID bool `yaml:"fail-on-duplicate-test-id"`
-	Output                SuiteConfigOutput
-	Results               SuiteConfigResults
-	Retries               SuiteConfigRetries
-	Partition             SuiteConfigPartition
+	Command                string
+	FailOnUploadError      bool `yaml:"fail-on-upload-error"`
+	FailOnDuplicateTestID  bool `yaml:"fail-on-duplicate-test-id"`
+	Output                 SuiteConfigOutput
+	Results                SuiteConfigResults
+	Retries                SuiteConfigRetries
+	Partition              SuiteConfigPartition
+	Timings                SuiteConfigTimings
+	RunTimeout             time.Duration `yaml:"run-timeout"`
+	TerminationGracePeriod time.Duration `yaml:"termination-grace-period"`
 }
@@ -104,6 +104,85 @@ var _ = Describe("RunConfig", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("errs when a retry message pattern is not a valid regular expression", func() {
+			err := cli.RunConfig{RetryOnlyIfMessageMatches: []string{"timeout("}}.Validate(logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid retries.only-if-message-matches pattern"))
+
+			err = cli.RunConfig{RetryNeverIfMessageMatches: []string{"[assert"}}.Validate(logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid retries.never-if-message-matches pattern"))
+		})
+
+		It("errs when the retry batch size is negative", func() {
+			err := cli.RunConfig{RetryBatchSize: -1}.Validate(logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unsupported --retry-batch-size value"))
+		})
+
+		It("errs when the minimum number of tests is negative", func() {
+			err := cli.RunConfig{MinTests: -1, TestResultsFileGlob: "rspec.json"}.Validate(logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unsupported --min-tests value"))
+		})
+
+		It("errs when the maximum drop in the number of tests is not a percentage", func() {
+			maxTestDropPercent := 101.0
+			err := cli.RunConfig{MaxTestDropPercent: &maxTestDropPercent, TestResultsFileGlob: "rspec.json"}.Validate(logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unsupported --max-test-drop-percent value"))
+		})
+
+		It("errs when the number of tests should be checked without test results", func() {
+			err := cli.RunConfig{MinTests: 10}.Validate(logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Missing test results path"))
+		})
+
+		It("errs when the dry run format is unsupported", func() {
+			err := cli.RunConfig{DryRun: true, DryRunFormat: "yaml"}.Validate(logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unsupported --dry-run-format value"))
+		})
+
+		It("errs when test results for a dry run are given without --dry-run", func() {
+			err := cli.RunConfig{DryRunResultsPath: "rspec.json"}.Validate(logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Test results for a dry run without --dry-run"))
+		})
+
+		It("errs when the retry concurrency is negative", func() {
+			err := cli.RunConfig{RetryConcurrency: -1}.Validate(logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unsupported --retry-concurrency value"))
+		})
+
+		It("errs when concurrent retry commands share the same test results path", func() {
+			err := cli.RunConfig{RetryConcurrency: 2, TestResultsFileGlob: "tmp/rspec.json"}.Validate(logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Retry commands would overwrite each other's test results"))
+		})
+
+		It("is valid when concurrent retry commands write their test results to separate paths", func() {
+			err := cli.RunConfig{
+				RetryConcurrency:    2,
+				TestResultsFileGlob: "tmp/rspec-${CAPTAIN_RETRY_COMMAND_ID}.json",
+			}.Validate(logger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("errs when the tests should be repeated without a retry command", func() {
+			err := cli.RunConfig{Repeat: 3}.Validate(logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Missing retry command"))
+		})
+
+		It("errs when the tests should be repeated and retried", func() {
+			err := cli.RunConfig{Repeat: 3, Retries: 1, RetryCommandTemplate: "retry {{ tests }}"}.Validate(logger)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Retries cannot be combined with --repeat"))
+		})
+
 		It("errs when additional artifact paths are provided without intermediate artifacts path", func() {
 			err := cli.RunConfig{
 				AdditionalArtifactPaths:   []string{"coverage/**/*"},
@@ -0,0 +1,237 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-shellwords"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/templating"
+	"github.com/rwx-research/captain-cli/internal/testing"
+)
+
+const (
+	dryRunFormatText = "text"
+	dryRunFormatJSON = "json"
+)
+
+// DryRunPlan describes the commands that Captain would run, as printed by `--dry-run`
+type DryRunPlan struct {
+	Command   []string         `json:"command,omitempty"`
+	Partition *DryRunPartition `json:"partition,omitempty"`
+	// Retries are the commands of the first retry (or repeat). Later retries depend on the outcome of the earlier ones.
+	Retries []DryRunRetryCommand `json:"retries,omitempty"`
+
+	// retryLabel is how the retries are referred to in the output, e.g. "retry"
+	retryLabel string
+	// plannedRetries is set when test results were provided to plan the retries from
+	plannedRetries bool
+}
+
+// DryRunPartition describes the test files of the partition that would run
+type DryRunPartition struct {
+	Index           int           `json:"index"`
+	Total           int           `json:"total"`
+	TestFilePaths   []string      `json:"testFilePaths"`
+	Tests           []string      `json:"tests,omitempty"`
+	ExpectedRuntime time.Duration `json:"expectedRuntimeInNanoseconds"`
+}
+
+// DryRunRetryCommand describes a single retry command, together with the values of the retry template's keywords
+type DryRunRetryCommand struct {
+	Command       []string          `json:"command"`
+	Substitutions map[string]string `json:"substitutions"`
+}
+
+func newDryRunPartition(partition testing.TestPartition, total int) *DryRunPartition {
+	return &DryRunPartition{
+		Index:           partition.Index,
+		Total:           total,
+		TestFilePaths:   partition.TestFilePaths,
+		Tests:           partitionTestNames(partition),
+		ExpectedRuntime: partition.Runtime,
+	}
+}
+
+// partitionTestNames identifies the individual tests of the partition, preferably by their ID
+func partitionTestNames(partition testing.TestPartition) []string {
+	var tests []string
+	for _, testTimingMatch := range partition.Tests {
+		test := testTimingMatch.TestTiming.ID
+		if test == "" {
+			test = fmt.Sprintf("%v (%v)", testTimingMatch.TestTiming.Name, testTimingMatch.ClientFilepath)
+		}
+		tests = append(tests, test)
+	}
+
+	return tests
+}
+
+func (p *DryRunPlan) addRetryCommands(
+	label string,
+	compiledRetryTemplate templating.CompiledTemplate,
+	allSubstitutions []map[string]string,
+) error {
+	p.retryLabel = label
+	for _, substitutions := range allSubstitutions {
+		command := compiledRetryTemplate.Substitute(substitutions)
+		args, err := shellwords.Parse(command)
+		if err != nil {
+			return errors.Wrapf(err, "Unable to parse %q into shell arguments", command)
+		}
+
+		p.Retries = append(p.Retries, DryRunRetryCommand{Command: args, Substitutions: substitutions})
+	}
+
+	return nil
+}
+
+// dryRun prints the commands that `RunSuite` would run without running them. Retries are only planned when test
+// results of a previous run are provided. Temporary files that the commands reference are kept, so that the commands
+// can be run by hand.
+func (s Service) dryRun(ctx context.Context, cfg RunConfig) error {
+	plan := new(DryRunPlan)
+
+	// A dry run mustn't store sticky partition assignments
+	cfg.PartitionConfig.DryRun = true
+
+	runCommand, err := s.makeRunCommand(ctx, cfg)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to assemble run command")
+	}
+
+	if runCommand.shortCircuit {
+		s.Log.Warn(runCommand.shortCircuitInfo)
+	} else {
+		plan.Command = runCommand.commandArgs
+	}
+
+	if runCommand.partition != nil {
+		plan.Partition = newDryRunPartition(*runCommand.partition, cfg.PartitionConfig.PartitionNodes.Total)
+	}
+
+	if cfg.DryRunResultsPath != "" && cfg.RetryCommandTemplate != "" {
+		testResults, err := s.readTestResultsFile(cfg.DryRunResultsPath)
+		if err != nil {
+			return err
+		}
+
+		apiConfiguration, err := s.API.GetRunConfiguration(ctx, cfg.SuiteID)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		cfg.dryRunPlan = plan
+		plan.plannedRetries = true
+		if cfg.Repeat > 1 {
+			_, _, _, err = s.attemptRepeats(ctx, testResults, testResults, cfg, 0)
+		} else {
+			_, _, _, err = s.attemptRetries(ctx, testResults, testResults, cfg, apiConfiguration, 0)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.printDryRunPlan(*plan, cfg.DryRunFormat)
+}
+
+func (s Service) printDryRunPlan(plan DryRunPlan, format string) error {
+	if format == dryRunFormatJSON {
+		encoded, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		s.Log.Infoln(string(encoded))
+		return nil
+	}
+
+	var output strings.Builder
+
+	if plan.Command != nil {
+		output.WriteString(fmt.Sprintf("Captain would run:\n  %v\n", formatCommandArgs(plan.Command)))
+	}
+
+	if plan.Partition != nil {
+		output.WriteString(fmt.Sprintf(
+			"\nPartition %v/%v with %v test %v (expected runtime: %v):\n",
+			plan.Partition.Index,
+			plan.Partition.Total,
+			len(plan.Partition.TestFilePaths),
+			pluralize(len(plan.Partition.TestFilePaths), "file", "files"),
+			plan.Partition.ExpectedRuntime,
+		))
+		for _, testFilePath := range plan.Partition.TestFilePaths {
+			output.WriteString(fmt.Sprintf("  %v\n", testFilePath))
+		}
+
+		if len(plan.Partition.Tests) > 0 {
+			output.WriteString(fmt.Sprintf(
+				"\nand %v individual %v of split test files:\n",
+				len(plan.Partition.Tests),
+				pluralize(len(plan.Partition.Tests), "test", "tests"),
+			))
+			for _, test := range plan.Partition.Tests {
+				output.WriteString(fmt.Sprintf("  %v\n", test))
+			}
+		}
+	}
+
+	if plan.plannedRetries && len(plan.Retries) == 0 {
+		output.WriteString("\nCaptain would not retry any tests\n")
+	}
+
+	if len(plan.Retries) > 0 {
+		output.WriteString(fmt.Sprintf(
+			"\nThe first %v would run %v %v:\n",
+			plan.retryLabel,
+			len(plan.Retries),
+			pluralize(len(plan.Retries), "command", "commands"),
+		))
+		for _, retry := range plan.Retries {
+			output.WriteString(fmt.Sprintf("  %v\n", formatCommandArgs(retry.Command)))
+
+			keywords := make([]string, 0, len(retry.Substitutions))
+			for keyword := range retry.Substitutions {
+				keywords = append(keywords, keyword)
+			}
+			sort.Strings(keywords)
+
+			for _, keyword := range keywords {
+				output.WriteString(fmt.Sprintf("    - %v: %v\n", keyword, retry.Substitutions[keyword]))
+			}
+		}
+	}
+
+	s.Log.Infoln(strings.TrimSuffix(output.String(), "\n"))
+	return nil
+}
+
+// formatCommandArgs joins the arguments of a command, quoting those that the shell would otherwise split up
+func formatCommandArgs(args []string) string {
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;#~") {
+			formatted[i] = arg
+			continue
+		}
+
+		formatted[i] = fmt.Sprintf("'%v'", strings.ReplaceAll(arg, "'", `'\''`))
+	}
+
+	return strings.Join(formatted, " ")
+}
+
+// dryRunPartition prints the partition that `Partition` would print, together with its expected runtime
+func (s Service) dryRunPartition(partition testing.TestPartition, cfg PartitionConfig) error {
+	return s.printDryRunPlan(
+		DryRunPlan{Partition: newDryRunPartition(partition, cfg.PartitionNodes.Total)},
+		cfg.DryRunFormat,
+	)
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"os"
 
 	"github.com/rwx-research/captain-cli/internal/exec"
 	"github.com/rwx-research/captain-cli/internal/fs"
@@ -18,4 +19,5 @@ type Reporter func(fs.File, v1.TestResults, reporting.Configuration) error
 type TaskRunner interface {
 	NewCommand(ctx context.Context, cfg exec.CommandConfig) (exec.Command, error)
 	GetExitStatusFromError(error) (int, error)
+	NotifyInterrupts(signals chan<- os.Signal) (stop func(), err error)
 }
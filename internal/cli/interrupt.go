@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// interruptHandlerKey is the context key of a process-wide interruptHandler
+type interruptHandlerKey struct{}
+
+// interruptHandler relays the first SIGINT or SIGTERM that Captain receives to all of its subscribers. There should
+// only be one per process, so that concurrent test suites agree on whether a signal is the first or the second one.
+type interruptHandler struct {
+	mu          sync.Mutex
+	stop        func()
+	interrupted error
+	subscribers map[int]context.CancelCauseFunc
+	nextID      int
+}
+
+// listenForInterrupts installs an interruptHandler and attaches it to the returned context. Any calls to
+// `withInterrupts` with this context share the handler instead of installing their own.
+func (s Service) listenForInterrupts(ctx context.Context) (context.Context, func()) {
+	signals := make(chan os.Signal, 1)
+
+	stop, err := s.TaskRunner.NotifyInterrupts(signals)
+	if err != nil {
+		s.Log.Debugf("Unable to listen for interrupts: %s", err.Error())
+		return ctx, func() {}
+	}
+
+	handler := &interruptHandler{
+		stop:        stop,
+		subscribers: make(map[int]context.CancelCauseFunc),
+	}
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-signals:
+			handler.interrupt(sig)
+		case <-done:
+		}
+	}()
+
+	return context.WithValue(ctx, interruptHandlerKey{}, handler), func() {
+		stop()
+		close(done)
+	}
+}
+
+// interrupt restores the default signal handling, so a second signal terminates Captain right away, and cancels all
+// current and future subscribers.
+func (h *interruptHandler) interrupt(sig os.Signal) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stop()
+	h.interrupted = errors.NewInterruptError(sig, interruptExitCode(sig), "Captain was interrupted (%s)", sig)
+	for _, cancel := range h.subscribers {
+		cancel(h.interrupted)
+	}
+}
+
+// subscribe returns a context that is canceled with an InterruptError once the handler receives a signal
+func (h *interruptHandler) subscribe(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.interrupted != nil {
+		cancel(h.interrupted)
+		return ctx, func() {}
+	}
+
+	id := h.nextID
+	h.nextID++
+	h.subscribers[id] = cancel
+
+	return ctx, func() {
+		h.mu.Lock()
+		delete(h.subscribers, id)
+		h.mu.Unlock()
+
+		cancel(nil)
+	}
+}
+
+// withInterrupts returns a context that is canceled with an InterruptError once Captain receives SIGINT or SIGTERM.
+// The default signal handling is restored after the first signal, so a second one terminates Captain right away.
+func (s Service) withInterrupts(ctx context.Context) (context.Context, context.CancelFunc) {
+	if handler, ok := ctx.Value(interruptHandlerKey{}).(*interruptHandler); ok {
+		return handler.subscribe(ctx)
+	}
+
+	ctx, stopListening := s.listenForInterrupts(ctx)
+	handler, ok := ctx.Value(interruptHandlerKey{}).(*interruptHandler)
+	if !ok {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel
+	}
+
+	ctx, unsubscribe := handler.subscribe(ctx)
+	return ctx, func() {
+		unsubscribe()
+		stopListening()
+	}
+}
+
+// interruptExitCode follows the shell convention of exiting with 128 plus the signal number.
+func interruptExitCode(sig os.Signal) int {
+	if signal, ok := sig.(syscall.Signal); ok {
+		return 128 + int(signal)
+	}
+
+	return 1
+}
+
+// interruptOtherError records an interruption of the original test command. Just like with timeouts, Captain cannot
+// know which tests were supposed to run.
+func interruptOtherError(interruptErr errors.InterruptError) v1.OtherError {
+	return v1.OtherError{
+		Message: fmt.Sprintf(
+			"%s. Captain stopped the test suite, any tests that did not report a result are missing.",
+			interruptErr.Error(),
+		),
+		Meta: map[string]any{"signal": interruptErr.Signal.String()},
+	}
+}
+
+// canceledTest returns a copy of a test that was supposed to be retried, but didn't report a result before Captain was
+// interrupted.
+func canceledTest(test v1.Test, interruptErr errors.InterruptError) v1.Test {
+	return v1.Test{
+		ID:       test.ID,
+		Name:     test.Name,
+		Scope:    test.Scope,
+		Lineage:  test.Lineage,
+		Location: test.Location,
+		Attempt: v1.TestAttempt{
+			Status: v1.NewCanceledTestStatus(),
+			Meta:   map[string]any{"signal": interruptErr.Signal.String()},
+		},
+	}
+}
+
+// terminationOtherError returns the error to record when Captain stopped the original test command.
+func terminationOtherError(err error) (v1.OtherError, bool) {
+	if timeoutErr, ok := errors.AsTimeoutError(err); ok {
+		return timeoutOtherError(timeoutErr), true
+	}
+
+	if interruptErr, ok := errors.AsInterruptError(err); ok {
+		return interruptOtherError(interruptErr), true
+	}
+
+	return v1.OtherError{}, false
+}
+
+// unfinishedTest returns the result to record for a retried test that didn't report a result because Captain stopped
+// the retry command.
+func unfinishedTest(test v1.Test, err error) (v1.Test, bool) {
+	if timeoutErr, ok := errors.AsTimeoutError(err); ok {
+		return timedOutTest(test, timeoutErr), true
+	}
+
+	if interruptErr, ok := errors.AsInterruptError(err); ok {
+		return canceledTest(test, interruptErr), true
+	}
+
+	return v1.Test{}, false
+}
+
+// isTerminationError checks whether Captain stopped a command, either due to a timeout or an interrupt.
+func isTerminationError(err error) bool {
+	if _, ok := errors.AsTimeoutError(err); ok {
+		return true
+	}
+
+	_, ok := errors.AsInterruptError(err)
+	return ok
+}
@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// WorkerIndexEnvVar is the environment variable that identifies a parallel worker of `captain run --parallel`. The test
+// results path needs to reference it, so that the workers don't overwrite each other's test results.
+const WorkerIndexEnvVar = "CAPTAIN_WORKER_INDEX"
+
+// expandWorkerIndex resolves any references to the worker index in the test results path
+func expandWorkerIndex(testResultsFileGlob string, workerIndex string) string {
+	return strings.NewReplacer(
+		fmt.Sprintf("${%s}", WorkerIndexEnvVar), workerIndex,
+		fmt.Sprintf("$%s", WorkerIndexEnvVar), workerIndex,
+	).Replace(testResultsFileGlob)
+}
+
+// parallelWorker is a single partition of the test suite that runs alongside the other partitions
+type parallelWorker struct {
+	index      int
+	runCommand RunCommand
+	ias        *IntermediateArtifactStorage
+	log        *commandLog
+	startedAt  time.Time
+	cmdErr     error
+}
+
+// runParallelWorkers splits the test suite into `cfg.Parallel` partitions and runs the partition command for all of
+// them at the same time. The test results of all workers are merged, just like the test results of queued batches.
+func (s Service) runParallelWorkers(
+	ctx context.Context,
+	cfg RunConfig,
+	stdout io.Writer,
+) (*v1.TestResults, []string, error, error) {
+	partitionCfg := cfg.parallelPartitionConfig()
+
+	partitionResult, err := s.calculatePartition(ctx, partitionCfg)
+	if err != nil {
+		return nil, nil, nil, errors.WithStack(err)
+	}
+
+	ias, err := s.NewIntermediateArtifactStorage(cfg.IntermediateArtifactsPath)
+	if err != nil {
+		return nil, nil, nil, errors.WithStack(err)
+	}
+
+	workers := make([]*parallelWorker, 0, len(partitionResult.partitions))
+	defer func() {
+		for _, worker := range workers {
+			worker.runCommand.cleanUp()
+		}
+	}()
+
+	for _, partition := range partitionResult.partitions {
+		if partition.IsEmpty() {
+			s.Log.Warnf(
+				"Worker %d contained no test files. %d/%d workers were utilized. "+
+					"We recommend you set --parallel no more than %d",
+				partition.Index,
+				partitionResult.utilizedPartitionCount,
+				cfg.Parallel,
+				partitionResult.utilizedPartitionCount,
+			)
+			continue
+		}
+
+		runCommand, err := s.makePartitionCommand(cfg, partition)
+		if err != nil {
+			return nil, nil, nil, errors.Wrapf(err, "Failed to assemble the command of worker %d", partition.Index)
+		}
+
+		workerIAS := *ias
+		workerIAS.SetCommandID(partition.Index + 1)
+
+		workers = append(workers, &parallelWorker{
+			index:      partition.Index,
+			runCommand: runCommand,
+			ias:        &workerIAS,
+		})
+	}
+
+	s.Log.Infof("Running %d workers in parallel", len(workers))
+
+	var eg errgroup.Group
+	for _, worker := range workers {
+		eg.Go(func() error {
+			s.runParallelWorker(ctx, cfg, worker, stdout)
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	var runErr error
+	var terminationErr error
+	var emptyTestResultsFiles []string
+	workerTestResults := make([]v1.TestResults, 0, len(workers))
+
+	for _, worker := range workers {
+		// Workers inherit Captain's environment, including any retry command ID
+		workerCfg := cfg
+		workerCfg.TestResultsFileGlob = expandRetryCommandID(
+			expandWorkerIndex(cfg.TestResultsFileGlob, strconv.Itoa(worker.index)),
+			os.Getenv(RetryCommandIDEnvVar),
+		)
+
+		testResults, testResultsFiles, workerEmptyTestResultsFiles, workerRunErr, err := s.handleCommandOutcome(
+			workerCfg,
+			worker.cmdErr,
+			0,
+			worker.startedAt,
+		)
+		if err != nil {
+			return nil, nil, runErr, err
+		}
+		emptyTestResultsFiles = append(emptyTestResultsFiles, workerEmptyTestResultsFiles...)
+		if runErr == nil {
+			runErr = workerRunErr
+		}
+
+		if otherError, ok := terminationOtherError(worker.cmdErr); ok && cfg.TestResultsFileGlob != "" {
+			if testResults == nil {
+				testResults = v1.NewTestResults(v1.NewOtherFramework(nil, nil), []v1.Test{}, []v1.OtherError{})
+			}
+
+			testResults.OtherErrors = append(testResults.OtherErrors, otherError)
+			testResults.Summary = v1.NewSummary(testResults.Tests, testResults.OtherErrors)
+		}
+
+		recordCommandLog(testResults, worker.log)
+
+		if testResults != nil {
+			if shouldPreserveAttachments() {
+				scope := filepath.Join(originalAttemptID, fmt.Sprintf("worker-%d", worker.index))
+				if err := s.preserveAttachments(testResults, scope); err != nil {
+					return nil, nil, runErr, errors.WithStack(err)
+				}
+			}
+
+			// Retries write their test results and artifacts to the same paths
+			if err := worker.ias.moveTestResults(testResultsFiles); err != nil {
+				return nil, nil, runErr, errors.WithStack(err)
+			}
+			if err := worker.ias.MoveAdditionalArtifacts(cfg.AdditionalArtifactPaths); err != nil {
+				return nil, nil, runErr, errors.WithStack(err)
+			}
+
+			workerTestResults = append(workerTestResults, *testResults)
+		}
+
+		if terminationErr == nil && isTerminationError(worker.cmdErr) {
+			terminationErr = worker.cmdErr
+		}
+	}
+
+	// Timeouts and interrupts stop the whole run, not only a single worker
+	if terminationErr != nil {
+		runErr = terminationErr
+	}
+
+	if len(workerTestResults) == 0 {
+		return nil, emptyTestResultsFiles, runErr, nil
+	}
+
+	testResults := v1.Merge(workerTestResults)
+	return &testResults, emptyTestResultsFiles, runErr, nil
+}
+
+// runParallelWorker runs the partition command of a single worker. Workers share the terminal, so every line of their
+// output is prefixed with the worker it originates from.
+func (s Service) runParallelWorker(ctx context.Context, cfg RunConfig, worker *parallelWorker, stdout io.Writer) {
+	prefix := fmt.Sprintf("[worker %d] ", worker.index)
+	prefixedStdout := newPrefixWriter(stdout, prefix)
+	defer prefixedStdout.Flush()
+	prefixedStderr := newPrefixWriter(cfg.commandStderr(), prefix)
+	defer prefixedStderr.Flush()
+
+	worker.log = s.openCommandLog(cfg, worker.ias, "command")
+	defer s.closeCommandLog(worker.log)
+	commandStdout, commandStderr := worker.log.tee(prefixedStdout, prefixedStderr)
+
+	worker.startedAt = time.Now()
+	_, worker.cmdErr = s.runCommand(ctx, worker.runCommand.commandArgs, commandOptions{
+		stdout:      commandStdout,
+		stderr:      commandStderr,
+		env:         []string{fmt.Sprintf("%s=%d", WorkerIndexEnvVar, worker.index)},
+		timeout:     cfg.AttemptTimeout,
+		gracePeriod: cfg.TerminationGracePeriod,
+	})
+}
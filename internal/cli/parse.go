@@ -53,32 +53,47 @@ func (s Service) Parse(_ context.Context, filepaths []string) error {
 	return nil
 }
 
+// parse parses the test results files and merges them
 func (s Service) parse(filepaths []string, group int) (*v1.TestResults, error) {
+	testResults, _, err := s.parseTestResultsFiles(filepaths, group)
+	return testResults, err
+}
+
+// parseTestResultsFiles parses the test results files and merges them. It also returns the paths of the files without
+// any tests or other errors, which usually means that the test suite didn't run as intended.
+func (s Service) parseTestResultsFiles(filepaths []string, group int) (*v1.TestResults, []string, error) {
 	var framework *v1.Framework
 	allResults := make([]v1.TestResults, 0)
+	emptyTestResultsFiles := make([]string, 0)
 
 	for _, testResultsFilePath := range filepaths {
 		s.Log.Debugf("Attempting to parse %q", testResultsFilePath)
 
 		fd, err := s.FileSystem.Open(testResultsFilePath)
 		if err != nil {
-			return nil, errors.NewSystemError("unable to open file: %s", err)
+			return nil, nil, errors.NewSystemError("unable to open file: %s", err)
 		}
 		defer fd.Close()
 
 		results, err := parsing.Parse(fd, group, s.ParseConfig)
 		if err != nil {
 			if _, ok := errors.AsDuplicateTestIDError(err); ok {
-				return nil, errors.WithStack(err)
+				return nil, nil, errors.WithStack(err)
 			}
 
-			return nil, errors.NewInputError("Unable to parse %q with the available parsers", testResultsFilePath)
+			return nil, nil, errors.NewInputError("Unable to parse %q with the available parsers", testResultsFilePath)
+		}
+
+		// The run only fails once the test results are reported, see `checkTestCount`
+		if len(results.Tests) == 0 && len(results.OtherErrors) == 0 {
+			s.Log.Warnf("The test results file %q does not contain any tests", testResultsFilePath)
+			emptyTestResultsFiles = append(emptyTestResultsFiles, testResultsFilePath)
 		}
 
 		if framework == nil {
 			framework = &results.Framework
 		} else if !framework.Equal(results.Framework) {
-			return nil, errors.NewInputError(
+			return nil, nil, errors.NewInputError(
 				"Multiple frameworks detected. The captain CLI only works with one framework at a time",
 			)
 		}
@@ -87,9 +102,9 @@ func (s Service) parse(filepaths []string, group int) (*v1.TestResults, error) {
 	}
 
 	if len(allResults) == 0 {
-		return nil, nil
+		return nil, emptyTestResultsFiles, nil
 	}
 
 	mergedResults := v1.Merge(allResults)
-	return &mergedResults, nil
+	return &mergedResults, emptyTestResultsFiles, nil
 }
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/rwx-research/captain-cli/internal/backend/local"
+	"github.com/rwx-research/captain-cli/internal/config"
 	"github.com/rwx-research/captain-cli/internal/errors"
 	"github.com/rwx-research/captain-cli/internal/testing"
 )
@@ -21,129 +23,340 @@ func (s Service) Partition(ctx context.Context, cfg PartitionConfig) error {
 	if err != nil {
 		return err
 	}
+	if cfg.Explain {
+		return s.explainPartitions(partitionResult, cfg)
+	}
+	if cfg.DryRun {
+		return s.dryRunPartition(partitionResult.partition, cfg)
+	}
 	s.Log.Infoln(strings.Join(partitionResult.partition.TestFilePaths, cfg.Delimiter))
 	return nil
 }
 
 func (s Service) calculatePartition(ctx context.Context, cfg PartitionConfig) (PartitionResult, error) {
-	fileTimingMatches := make([]testing.FileTimingMatch, 0)
-	unmatchedFilepaths := make([]string, 0)
-	testFilePaths, err := s.FileSystem.GlobMany(cfg.TestFilePaths)
+	testFilePaths, err := s.expandTestFilePaths(ctx, cfg)
+	if err != nil {
+		return PartitionResult{}, err
+	}
+
+	var stickyStorage local.Client
+	if cfg.Sticky {
+		stickyStorage, err = s.stickyPartitionStorage()
+		if err != nil {
+			return PartitionResult{}, err
+		}
+	}
+
+	timings, err := s.timingSource(cfg)
 	if err != nil {
-		return PartitionResult{}, errors.NewSystemError("unable to expand filepath glob: %s", err)
+		return PartitionResult{}, err
+	}
+
+	strategy := cfg.strategy()
+	fileTimingMatches := make([]testing.FileTimingMatch, 0)
+	unmatchedFilepaths := testFilePaths
+	roundRobinFilepaths := unmatchedFilepaths
+
+	// Strategies that don't balance by timings still fetch them when explaining, in order to estimate the runtimes
+	if strategy.usesTimings() || cfg.Explain {
+		fileTimingMatches, unmatchedFilepaths, err = s.matchFileTimings(ctx, cfg, timings, testFilePaths)
+		if err != nil {
+			return PartitionResult{}, err
+		}
+		roundRobinFilepaths = unmatchedFilepaths
+
+		if strategy.usesTimings() && len(fileTimingMatches) == 0 {
+			s.Log.Warnln("No test file timings were matched. Using naive round-robin strategy.")
+		}
+
+		// Test files without timings are placed just like the others, based on the timings of similar test files
+		if strategy.usesTimings() && len(fileTimingMatches) > 0 && len(unmatchedFilepaths) > 0 {
+			estimatedTimingMatches := s.estimateFileTimings(cfg, fileTimingMatches, unmatchedFilepaths)
+			fileTimingMatches = append(fileTimingMatches, estimatedTimingMatches...)
+			sortFileTimingMatches(fileTimingMatches)
+			roundRobinFilepaths = nil
+		}
 	}
 
-	if cfg.RoundRobin {
-		unmatchedFilepaths = append(unmatchedFilepaths, testFilePaths...)
-	} else {
-		fileTimings, err := s.API.GetTestTimingManifest(ctx, cfg.SuiteID)
+	testTimingMatches := make([]testing.TestTimingMatch, 0)
+	if cfg.SplitTests && strategy.usesTimings() && len(fileTimingMatches) > 0 {
+		fileTimingMatches, testTimingMatches, err = s.splitSlowTestFiles(ctx, cfg, timings, fileTimingMatches)
 		if err != nil {
-			return PartitionResult{}, errors.WithStack(err)
-		}
-
-		// Compare expanded client file paths w/ expanded server file paths
-		// taking care to always use the client path and sort by duration desc
-		for _, clientTestFile := range testFilePaths {
-			match := false
-			var fileTimingMatch testing.FileTimingMatch
-			clientExpandedFilepath := clientTestFile
-			if cfg.TrimPrefix != "" {
-				trimmedClientExpandedFilepath := strings.TrimPrefix(clientTestFile, cfg.TrimPrefix)
-				s.Log.Debugf(
-					"Trimming prefix '%s' from '%s' resulting in '%s' for comparison",
-					cfg.TrimPrefix,
-					clientTestFile,
-					trimmedClientExpandedFilepath,
-				)
-				clientExpandedFilepath = trimmedClientExpandedFilepath
+			return PartitionResult{}, err
+		}
+	}
+
+	var totalRuntime time.Duration
+	for _, fileTimingMatch := range fileTimingMatches {
+		totalRuntime += fileTimingMatch.Duration()
+	}
+	for _, testTimingMatch := range testTimingMatches {
+		totalRuntime += testTimingMatch.Duration()
+	}
+	partitionRuntime := targetRuntime(totalRuntime, cfg.PartitionNodes, cfg.PartitionNodes.Index)
+
+	s.Log.Debugf("Total Runtime: %s", totalRuntime)
+	s.Log.Debugf("Target Partition Runtime: %s", partitionRuntime)
+
+	partitions := newTestPartitions(cfg.PartitionNodes.Total)
+
+	switch strategy {
+	case PartitionStrategyGreedy, PartitionStrategyKarmarkarKarp:
+		items := partitionItems(fileTimingMatches, testTimingMatches)
+
+		kept := false
+		if cfg.Sticky {
+			var stickyPartitions []testing.TestPartition
+			stickyPartitions, kept = s.assignToPreviousPartitions(
+				cfg, stickyStorage.PartitionAssignments, items, roundRobinFilepaths,
+			)
+			if kept {
+				partitions = stickyPartitions
 			}
-			clientExpandedFilepath, err = filepath.Abs(clientExpandedFilepath)
-			if err != nil {
-				s.Log.Warnf("failed to expand path of test file: %s", clientTestFile)
-				unmatchedFilepaths = append(unmatchedFilepaths, clientTestFile)
-				continue
+		}
+
+		if !kept {
+			if strategy == PartitionStrategyKarmarkarKarp {
+				partitions = s.assignWithKarmarkarKarp(partitions, items)
+			} else {
+				partitions = s.assignWithLeastRuntime(partitions, cfg.PartitionNodes, items)
 			}
 
-			for _, serverTiming := range fileTimings {
-				serverExpandedFilepath, err := filepath.Abs(serverTiming.Filepath)
-				if err != nil {
-					s.Log.Warnf("failed to expand filepath of timing file: %s", serverTiming.Filepath)
-					break
-				}
-				if clientExpandedFilepath == serverExpandedFilepath {
-					match = true
-					fileTimingMatch = testing.FileTimingMatch{
-						FileTiming:     serverTiming,
-						ClientFilepath: clientTestFile,
-					}
-					break
-				}
+			roundRobin := newRoundRobin(cfg.PartitionNodes)
+			for _, testFilepath := range roundRobinFilepaths {
+				partition := partitions[roundRobin.next()]
+				partitions[partition.Index] = partition.AddFilePath(testFilepath)
+				s.Log.Debugf("%s: Assigned '%s' using round robin strategy", partition, testFilepath)
 			}
-			if match {
-				fileTimingMatches = append(fileTimingMatches, fileTimingMatch)
+		}
+	case PartitionStrategyRoundRobin, PartitionStrategyHashedStable:
+		fileTimingMatchesByPath := make(map[string]testing.FileTimingMatch, len(fileTimingMatches))
+		for _, fileTimingMatch := range fileTimingMatches {
+			fileTimingMatchesByPath[fileTimingMatch.ClientFilepath] = fileTimingMatch
+		}
+
+		roundRobin := newRoundRobin(cfg.PartitionNodes)
+		for _, testFilepath := range testFilePaths {
+			var index int
+			if strategy == PartitionStrategyHashedStable {
+				index = hashedPartitionIndex(strings.TrimPrefix(testFilepath, cfg.TrimPrefix), cfg.PartitionNodes)
 			} else {
-				unmatchedFilepaths = append(unmatchedFilepaths, clientTestFile)
+				index = roundRobin.next()
 			}
+
+			partition := partitions[index]
+			if fileTimingMatch, ok := fileTimingMatchesByPath[testFilepath]; ok {
+				partitions[index] = partition.Add(fileTimingMatch)
+			} else {
+				partitions[index] = partition.AddFilePath(testFilepath)
+			}
+			s.Log.Debugf("%s: Assigned '%s' using %s strategy", partition, testFilepath, strategyLabel(strategy))
+		}
+	}
+
+	partitionResult := PartitionResult{
+		partition:              partitions[cfg.PartitionNodes.Index],
+		partitions:             partitions,
+		unmatchedFilepaths:     unmatchedFilepaths,
+		utilizedPartitionCount: utilizedPartitionCount(partitions),
+	}
+
+	if cfg.Sticky {
+		movedTestFileCount, err := s.recordStickyPartitions(cfg, stickyStorage, partitions)
+		if err != nil {
+			return PartitionResult{}, err
 		}
-		sort.SliceStable(fileTimingMatches, func(i, j int) bool {
-			if fileTimingMatches[i].Duration() == fileTimingMatches[j].Duration() {
-				return fileTimingMatches[i].ClientFilepath > fileTimingMatches[j].ClientFilepath
+		partitionResult.movedTestFileCount = &movedTestFileCount
+	}
+
+	return partitionResult, nil
+}
+
+// matchFileTimings compares the expanded client file paths with the expanded server file paths, taking care to always
+// use the client path. The matched timings are sorted by duration, slowest first.
+func (s Service) matchFileTimings(
+	ctx context.Context,
+	cfg PartitionConfig,
+	timings timingSource,
+	testFilePaths []string,
+) ([]testing.FileTimingMatch, []string, error) {
+	fileTimingMatches := make([]testing.FileTimingMatch, 0)
+	unmatchedFilepaths := make([]string, 0)
+
+	fileTimings, err := timings.GetTestTimingManifest(ctx, cfg.SuiteID)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	for _, clientTestFile := range testFilePaths {
+		match := false
+		var fileTimingMatch testing.FileTimingMatch
+		clientExpandedFilepath := clientTestFile
+		if cfg.TrimPrefix != "" {
+			trimmedClientExpandedFilepath := strings.TrimPrefix(clientTestFile, cfg.TrimPrefix)
+			s.Log.Debugf(
+				"Trimming prefix '%s' from '%s' resulting in '%s' for comparison",
+				cfg.TrimPrefix,
+				clientTestFile,
+				trimmedClientExpandedFilepath,
+			)
+			clientExpandedFilepath = trimmedClientExpandedFilepath
+		}
+		clientExpandedFilepath, err = filepath.Abs(clientExpandedFilepath)
+		if err != nil {
+			s.Log.Warnf("failed to expand path of test file: %s", clientTestFile)
+			unmatchedFilepaths = append(unmatchedFilepaths, clientTestFile)
+			continue
+		}
+
+		for _, serverTiming := range fileTimings {
+			serverExpandedFilepath, err := filepath.Abs(serverTiming.Filepath)
+			if err != nil {
+				s.Log.Warnf("failed to expand filepath of timing file: %s", serverTiming.Filepath)
+				break
+			}
+			if clientExpandedFilepath == serverExpandedFilepath {
+				match = true
+				fileTimingMatch = testing.FileTimingMatch{
+					FileTiming:     serverTiming,
+					ClientFilepath: clientTestFile,
+				}
+				break
 			}
+		}
+		if match {
+			fileTimingMatches = append(fileTimingMatches, fileTimingMatch)
+		} else {
+			unmatchedFilepaths = append(unmatchedFilepaths, clientTestFile)
+		}
+	}
+	sortFileTimingMatches(fileTimingMatches)
 
-			return fileTimingMatches[i].Duration() > fileTimingMatches[j].Duration()
-		})
+	return fileTimingMatches, unmatchedFilepaths, nil
+}
 
-		if len(fileTimingMatches) == 0 {
-			s.Log.Warnln("No test file timings were matched. Using naive round-robin strategy.")
+// sortFileTimingMatches sorts the timings by duration, slowest first
+func sortFileTimingMatches(fileTimingMatches []testing.FileTimingMatch) {
+	sort.SliceStable(fileTimingMatches, func(i, j int) bool {
+		if fileTimingMatches[i].Duration() == fileTimingMatches[j].Duration() {
+			return fileTimingMatches[i].ClientFilepath > fileTimingMatches[j].ClientFilepath
+		}
+
+		return fileTimingMatches[i].Duration() > fileTimingMatches[j].Duration()
+	})
+}
+
+// assignWithLeastRuntime assigns the items, slowest first, to the partition with the least runtime so far relative to
+// the weight of its node
+func (s Service) assignWithLeastRuntime(
+	partitions []testing.TestPartition,
+	nodes config.PartitionNodes,
+	items []partitionItem,
+) []testing.TestPartition {
+	for _, item := range items {
+		partition := item.addTo(partitionWithLeastRuntime(partitions, nodes))
+		partitions[partition.Index] = partition
+		s.Log.Debugf("%s: Assigned %s using least runtime strategy", partition, item)
+	}
+
+	return partitions
+}
+
+// assignWithKarmarkarKarp assigns the items using the largest differencing method
+func (s Service) assignWithKarmarkarKarp(
+	partitions []testing.TestPartition,
+	items []partitionItem,
+) []testing.TestPartition {
+	for index, itemIndexes := range karmarkarKarp(items, len(partitions)) {
+		for _, itemIndex := range itemIndexes {
+			partitions[index] = items[itemIndex].addTo(partitions[index])
+			s.Log.Debugf("%s: Assigned %s using karmarkar-karp strategy", partitions[index], items[itemIndex])
 		}
 	}
 
-	partitions := make([]testing.TestPartition, 0)
+	return partitions
+}
+
+func strategyLabel(strategy PartitionStrategy) string {
+	return strings.ReplaceAll(string(strategy), "-", " ")
+}
+
+// splitSlowTestFiles splits the test files that take longer than a partition should take into their individual tests,
+// so that they can be spread across partitions. Test files without any recorded test timings are kept as they are.
+// Both the remaining test files and the tests are returned sorted by their duration.
+func (s Service) splitSlowTestFiles(
+	ctx context.Context,
+	cfg PartitionConfig,
+	timings timingSource,
+	fileTimingMatches []testing.FileTimingMatch,
+) ([]testing.FileTimingMatch, []testing.TestTimingMatch, error) {
 	var totalRuntime time.Duration
 	for _, fileTimingMatch := range fileTimingMatches {
 		totalRuntime += fileTimingMatch.Duration()
 	}
-	partitionRuntime := totalRuntime / time.Duration(cfg.PartitionNodes.Total)
+	partitionRuntime := smallestTargetRuntime(totalRuntime, cfg.PartitionNodes)
 
-	s.Log.Debugf("Total Runtime: %s", totalRuntime)
-	s.Log.Debugf("Target Partition Runtime: %s", partitionRuntime)
+	testTimings, err := timings.GetTestTimings(ctx, cfg.SuiteID)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	testTimingsByFile := make(map[string][]testing.TestTiming)
+	for _, testTiming := range testTimings {
+		expandedFilepath, err := filepath.Abs(testTiming.Filepath)
+		if err != nil {
+			s.Log.Warnf("failed to expand filepath of test timing: %s", testTiming.Filepath)
+			continue
+		}
 
-	for i := 0; i < cfg.PartitionNodes.Total; i++ {
-		partitions = append(partitions, testing.TestPartition{
-			Index:         i,
-			TestFilePaths: make([]string, 0),
-			Runtime:       time.Duration(0),
-		})
+		testTimingsByFile[expandedFilepath] = append(testTimingsByFile[expandedFilepath], testTiming)
 	}
 
+	remainingFileTimingMatches := make([]testing.FileTimingMatch, 0, len(fileTimingMatches))
+	testTimingMatches := make([]testing.TestTimingMatch, 0)
 	for _, fileTimingMatch := range fileTimingMatches {
-		partition := partitionWithLeastRuntime(partitions).Add(fileTimingMatch)
-		partitions[partition.Index] = partition
-		s.Log.Debugf("%s: Assigned %s using least runtime strategy", partition, fileTimingMatch)
-	}
+		expandedFilepath, err := filepath.Abs(fileTimingMatch.FileTiming.Filepath)
+		fileTestTimings := testTimingsByFile[expandedFilepath]
+
+		if err != nil || fileTimingMatch.Duration() <= partitionRuntime || len(fileTestTimings) == 0 {
+			remainingFileTimingMatches = append(remainingFileTimingMatches, fileTimingMatch)
+			continue
+		}
 
-	for i, testFilepath := range unmatchedFilepaths {
-		partition := partitions[i%len(partitions)]
-		partitions[partition.Index] = partition.AddFilePath(testFilepath)
-		s.Log.Debugf("%s: Assigned '%s' using round robin strategy", partition, testFilepath)
+		s.Log.Debugf("Splitting %s into %d tests", fileTimingMatch, len(fileTestTimings))
+		for _, testTiming := range fileTestTimings {
+			testTimingMatches = append(testTimingMatches, testing.TestTimingMatch{
+				TestTiming:     testTiming,
+				ClientFilepath: fileTimingMatch.ClientFilepath,
+			})
+		}
 	}
 
-	return PartitionResult{
-		partition:              partitions[cfg.PartitionNodes.Index],
-		utilizedPartitionCount: utilizedPartitionCount(partitions),
-	}, nil
+	sort.SliceStable(testTimingMatches, func(i, j int) bool {
+		if testTimingMatches[i].Duration() == testTimingMatches[j].Duration() {
+			return testTimingMatches[i].TestTiming.Key() < testTimingMatches[j].TestTiming.Key()
+		}
+
+		return testTimingMatches[i].Duration() > testTimingMatches[j].Duration()
+	})
+
+	return remainingFileTimingMatches, testTimingMatches, nil
 }
 
-func partitionWithLeastRuntime(partitions []testing.TestPartition) testing.TestPartition {
+func partitionWithLeastRuntime(partitions []testing.TestPartition, nodes config.PartitionNodes) testing.TestPartition {
 	selected := partitions[0]
+	weightedRuntime := func(partition testing.TestPartition) float64 {
+		return float64(partition.Runtime) / nodes.Weight(partition.Index)
+	}
 
 	for _, candidate := range partitions {
-		if candidate.Runtime < selected.Runtime {
+		if weightedRuntime(candidate) < weightedRuntime(selected) {
 			selected = candidate
 			continue
 		}
 
-		if candidate.Runtime == selected.Runtime && len(candidate.TestFilePaths) < len(selected.TestFilePaths) {
+		if weightedRuntime(candidate) == weightedRuntime(selected) &&
+			len(candidate.TestFilePaths)+len(candidate.Tests) < len(selected.TestFilePaths)+len(selected.Tests) {
 			selected = candidate
 		}
 	}
@@ -154,7 +367,7 @@ func partitionWithLeastRuntime(partitions []testing.TestPartition) testing.TestP
 func utilizedPartitionCount(partitions []testing.TestPartition) int {
 	count := 0
 	for _, partition := range partitions {
-		if len(partition.TestFilePaths) != 0 {
+		if !partition.IsEmpty() {
 			count++
 		}
 	}
@@ -162,6 +375,12 @@ func utilizedPartitionCount(partitions []testing.TestPartition) int {
 }
 
 type PartitionResult struct {
-	partition              testing.TestPartition
+	partition testing.TestPartition
+	// partitions are all partitions, including the one at the configured index
+	partitions []testing.TestPartition
+	// movedTestFileCount is the number of test files on a different partition than before, if partitions are sticky
+	movedTestFileCount *int
+	// unmatchedFilepaths are the test files without historical timings
+	unmatchedFilepaths     []string
 	utilizedPartitionCount int
 }
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"slices"
+	"time"
+
+	"github.com/rwx-research/captain-cli/internal/testing"
+)
+
+// estimateFileTimings estimates the timings of test files without historical timings as the median timing of the test
+// files with timings. When configured, the estimate is scaled by how the size of a test file compares to the median
+// size of the test files with timings.
+func (s Service) estimateFileTimings(
+	cfg PartitionConfig,
+	fileTimingMatches []testing.FileTimingMatch,
+	unmatchedFilepaths []string,
+) []testing.FileTimingMatch {
+	durations := make([]int64, len(fileTimingMatches))
+	for i, fileTimingMatch := range fileTimingMatches {
+		durations[i] = int64(fileTimingMatch.Duration())
+	}
+	medianDuration := time.Duration(median(durations))
+
+	var medianSize int64
+	if cfg.EstimateByFileSize {
+		sizes := make([]int64, 0, len(fileTimingMatches))
+		for _, fileTimingMatch := range fileTimingMatches {
+			if size, ok := s.fileSize(fileTimingMatch.ClientFilepath); ok {
+				sizes = append(sizes, size)
+			}
+		}
+		medianSize = median(sizes)
+	}
+
+	estimatedTimingMatches := make([]testing.FileTimingMatch, len(unmatchedFilepaths))
+	for i, unmatchedFilepath := range unmatchedFilepaths {
+		duration := medianDuration
+		basis := "the median timing of the test files with timings"
+
+		if medianSize > 0 {
+			if size, ok := s.fileSize(unmatchedFilepath); ok {
+				duration = time.Duration(float64(medianDuration) * float64(size) / float64(medianSize))
+				basis = "the median timing of the test files with timings, scaled by file size"
+			}
+		}
+
+		estimatedTimingMatches[i] = testing.FileTimingMatch{
+			FileTiming:     testing.TestFileTiming{Filepath: unmatchedFilepath, Duration: duration},
+			ClientFilepath: unmatchedFilepath,
+			Estimated:      true,
+		}
+		s.Log.Debugf("Estimated a timing of %s for '%s' based on %s", duration, unmatchedFilepath, basis)
+	}
+
+	return estimatedTimingMatches
+}
+
+func (s Service) fileSize(path string) (int64, bool) {
+	info, err := s.FileSystem.Stat(path)
+	if err != nil {
+		s.Log.Debugf("Unable to determine the size of '%s': %s", path, err.Error())
+		return 0, false
+	}
+
+	return info.Size(), true
+}
+
+// median returns the median of the values, or zero if there are none
+func median(values []int64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
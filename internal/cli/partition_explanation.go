@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/testing"
+)
+
+// PartitionExplanation describes how a strategy partitions a test suite, as printed by `captain partition --explain`
+type PartitionExplanation struct {
+	Strategy PartitionStrategy `json:"strategy"`
+	Total    int               `json:"total"`
+	// ExpectedRuntime is the expected runtime of the slowest partition
+	ExpectedRuntime time.Duration `json:"expectedRuntimeInNanoseconds"`
+	// ImbalancePercentage is how much longer the most overloaded partition takes than its share of the total runtime
+	ImbalancePercentage float64 `json:"imbalancePercentage"`
+	// MovedTestFiles is the number of test files on a different partition than before, only set for sticky partitions
+	MovedTestFiles *int                 `json:"movedTestFiles,omitempty"`
+	Partitions     []ExplainedPartition `json:"partitions"`
+}
+
+// ExplainedPartition describes a single partition of a PartitionExplanation
+type ExplainedPartition struct {
+	Index         int      `json:"index"`
+	TestFilePaths []string `json:"testFilePaths"`
+	Tests         []string `json:"tests,omitempty"`
+	// UntimedTestFilePaths are the test files without historical timings, whose runtime is at most estimated
+	UntimedTestFilePaths []string      `json:"untimedTestFilePaths"`
+	ExpectedRuntime      time.Duration `json:"expectedRuntimeInNanoseconds"`
+	// ImbalancePercentage is how much longer (or, if negative, shorter) the partition takes than its share of the total
+	// runtime, which is proportional to the weight of its node
+	ImbalancePercentage float64 `json:"imbalancePercentage"`
+}
+
+func newPartitionExplanation(partitionResult PartitionResult, cfg PartitionConfig) PartitionExplanation {
+	explanation := PartitionExplanation{
+		Strategy:       cfg.strategy(),
+		Total:          len(partitionResult.partitions),
+		MovedTestFiles: partitionResult.movedTestFileCount,
+		Partitions:     make([]ExplainedPartition, 0, len(partitionResult.partitions)),
+	}
+
+	for _, partition := range partitionResult.partitions {
+		explanation.ExpectedRuntime = max(explanation.ExpectedRuntime, partition.Runtime)
+	}
+
+	var imbalances []float64
+	imbalances, explanation.ImbalancePercentage = partitionImbalances(partitionResult.partitions, cfg.PartitionNodes)
+
+	for i, partition := range partitionResult.partitions {
+		explanation.Partitions = append(explanation.Partitions, ExplainedPartition{
+			Index:                partition.Index,
+			TestFilePaths:        partition.TestFilePaths,
+			Tests:                partitionTestNames(partition),
+			UntimedTestFilePaths: untimedTestFilePaths(partition, partitionResult.unmatchedFilepaths),
+			ExpectedRuntime:      partition.Runtime,
+			ImbalancePercentage:  imbalances[i],
+		})
+	}
+
+	return explanation
+}
+
+func untimedTestFilePaths(partition testing.TestPartition, unmatchedFilepaths []string) []string {
+	untimed := make([]string, 0)
+	for _, testFilePath := range partition.TestFilePaths {
+		if slices.Contains(unmatchedFilepaths, testFilePath) {
+			untimed = append(untimed, testFilePath)
+		}
+	}
+
+	return untimed
+}
+
+// imbalancePercentage returns by how many percent the runtime deviates from the average runtime, rounded to two
+// decimal places
+func imbalancePercentage(runtime time.Duration, averageRuntime float64) float64 {
+	if averageRuntime == 0 {
+		return 0
+	}
+
+	return math.Round((float64(runtime)-averageRuntime)/averageRuntime*100*100) / 100
+}
+
+// explainPartitions prints every partition together with its expected runtime and how balanced it is, so that
+// strategies can be compared with each other
+func (s Service) explainPartitions(partitionResult PartitionResult, cfg PartitionConfig) error {
+	encoded, err := json.MarshalIndent(newPartitionExplanation(partitionResult, cfg), "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	s.Log.Infoln(string(encoded))
+	return nil
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/exec"
+)
+
+// goListTestPackagesFormat prints the import path of every package with tests, and nothing for all other packages
+const goListTestPackagesFormat = "{{if or .TestGoFiles .XTestGoFiles}}{{.ImportPath}}{{end}}"
+
+// expandTestFilePaths expands the test file paths of a partition config. These are either globs of test files or, when
+// partitioning Go packages, `go list` patterns like `./...`.
+func (s Service) expandTestFilePaths(ctx context.Context, cfg PartitionConfig) ([]string, error) {
+	if cfg.Packages {
+		return s.listGoTestPackages(ctx, cfg.TestFilePaths)
+	}
+
+	testFilePaths, err := s.FileSystem.GlobMany(cfg.TestFilePaths)
+	if err != nil {
+		return nil, errors.NewSystemError("unable to expand filepath glob: %s", err)
+	}
+
+	return testFilePaths, nil
+}
+
+// listGoTestPackages returns the import paths of all packages with tests that match the given `go list` patterns
+func (s Service) listGoTestPackages(ctx context.Context, patterns []string) ([]string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd, err := s.TaskRunner.NewCommand(ctx, exec.CommandConfig{
+		Name:   "go",
+		Args:   append([]string{"list", "-f", goListTestPackagesFormat}, patterns...),
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, errors.NewSystemError("unable to execute 'go list': %s", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, errors.NewSystemError(
+			"unable to list the Go packages matching %q: %s\n%s",
+			strings.Join(patterns, " "),
+			err,
+			strings.TrimSpace(stderr.String()),
+		)
+	}
+
+	importPaths := make([]string, 0)
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if importPath := strings.TrimSpace(line); importPath != "" {
+			importPaths = append(importPaths, importPath)
+		}
+	}
+
+	s.Log.Debugf("Listed %d Go packages with tests matching %q", len(importPaths), strings.Join(patterns, " "))
+
+	return importPaths, nil
+}
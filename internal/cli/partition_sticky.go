@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rwx-research/captain-cli/internal/backend/local"
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/testing"
+)
+
+const defaultStickyThreshold = 10.0
+
+// stickyThreshold returns the imbalance percentage above which sticky partitions are rebalanced
+func (pc PartitionConfig) stickyThreshold() float64 {
+	if pc.StickyThreshold == nil {
+		return defaultStickyThreshold
+	}
+
+	return *pc.StickyThreshold
+}
+
+// stickyKey is the path that a test file's partition assignment is stored under. Like the timings, it doesn't include
+// the trimmed prefix, which often differs between machines.
+func stickyKey(cfg PartitionConfig, testFilePath string) string {
+	return strings.TrimPrefix(testFilePath, cfg.TrimPrefix)
+}
+
+// stickyPartitionStorage returns the storage of the previous partition assignments, which only exists in OSS mode
+func (s Service) stickyPartitionStorage() (local.Client, error) {
+	localStorage, ok := s.API.(local.Client)
+	if !ok {
+		return local.Client{}, errors.NewConfigurationError(
+			"Sticky partitioning only works in OSS mode",
+			"You are trying to keep test files on their previous partitions, however it appears that you are using "+
+				"Captain Cloud, which doesn't store partition assignments.",
+			"Please disable sticky partitioning.",
+		)
+	}
+
+	return localStorage, nil
+}
+
+// assignToPreviousPartitions keeps the test files on the partitions they were assigned to previously. New test files
+// are assigned to the partition with the least runtime. The partitions are only returned if they are balanced within
+// the sticky threshold.
+func (s Service) assignToPreviousPartitions(
+	cfg PartitionConfig,
+	previous local.PartitionAssignments,
+	items []partitionItem,
+	untimedFilepaths []string,
+) ([]testing.TestPartition, bool) {
+	total := cfg.PartitionNodes.Total
+	if previous.Total != total {
+		if len(previous.TestFiles) > 0 {
+			s.Log.Debugf("The previous partitioning used %d instead of %d partitions, rebalancing", previous.Total, total)
+		}
+		return nil, false
+	}
+
+	partitions := newTestPartitions(total)
+	newItems := make([]partitionItem, 0)
+	for _, item := range items {
+		if item.file != nil {
+			if index, ok := previous.TestFiles[stickyKey(cfg, item.file.ClientFilepath)]; ok && index < total {
+				partitions[index] = item.addTo(partitions[index])
+				s.Log.Debugf("%s: Kept %s on its previous partition", partitions[index], item)
+				continue
+			}
+		}
+
+		newItems = append(newItems, item)
+	}
+	partitions = s.assignWithLeastRuntime(partitions, cfg.PartitionNodes, newItems)
+
+	roundRobin := newRoundRobin(cfg.PartitionNodes)
+	for _, testFilepath := range untimedFilepaths {
+		if index, ok := previous.TestFiles[stickyKey(cfg, testFilepath)]; ok && index < total {
+			partitions[index] = partitions[index].AddFilePath(testFilepath)
+			s.Log.Debugf("%s: Kept '%s' on its previous partition", partitions[index], testFilepath)
+			continue
+		}
+
+		index := roundRobin.next()
+		partitions[index] = partitions[index].AddFilePath(testFilepath)
+		s.Log.Debugf("%s: Assigned '%s' using round robin strategy", partitions[index], testFilepath)
+	}
+
+	_, imbalance := partitionImbalances(partitions, cfg.PartitionNodes)
+	if imbalance > cfg.stickyThreshold() {
+		s.Log.Debugf(
+			"Keeping the previous partitions would be %.2f%% imbalanced, more than the threshold of %.2f%%, rebalancing",
+			imbalance,
+			cfg.stickyThreshold(),
+		)
+		return nil, false
+	}
+
+	return partitions, true
+}
+
+// recordStickyPartitions counts the test files that moved to a different partition and stores the new assignments
+// for the next partitioning. Dry runs & explanations don't store anything.
+func (s Service) recordStickyPartitions(
+	cfg PartitionConfig,
+	storage local.Client,
+	partitions []testing.TestPartition,
+) (int, error) {
+	assignments := local.PartitionAssignments{
+		Total:     cfg.PartitionNodes.Total,
+		TestFiles: make(map[string]int),
+	}
+
+	moved := 0
+	for _, partition := range partitions {
+		for _, testFilePath := range partition.TestFilePaths {
+			key := stickyKey(cfg, testFilePath)
+			if index, ok := storage.PartitionAssignments.TestFiles[key]; ok && index != partition.Index {
+				moved++
+			}
+
+			assignments.TestFiles[key] = partition.Index
+		}
+	}
+	s.Log.Debugf("Moved %d test files to a different partition than before", moved)
+
+	if cfg.DryRun || cfg.Explain {
+		return moved, nil
+	}
+
+	return moved, errors.WithStack(storage.UpdatePartitionAssignments(assignments))
+}
+
+func newTestPartitions(total int) []testing.TestPartition {
+	partitions := make([]testing.TestPartition, 0, total)
+	for i := 0; i < total; i++ {
+		partitions = append(partitions, testing.TestPartition{
+			Index:         i,
+			TestFilePaths: make([]string, 0),
+			Runtime:       time.Duration(0),
+		})
+	}
+
+	return partitions
+}
@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"container/heap"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rwx-research/captain-cli/internal/config"
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/testing"
+)
+
+// PartitionStrategy is the algorithm that assigns test files to partitions
+type PartitionStrategy string
+
+const (
+	// PartitionStrategyGreedy assigns the slowest remaining test file to the partition with the least runtime
+	PartitionStrategyGreedy PartitionStrategy = "greedy"
+	// PartitionStrategyKarmarkarKarp uses the largest differencing method, which repeatedly combines the partial
+	// partitionings with the largest difference between their slowest and fastest partition
+	PartitionStrategyKarmarkarKarp PartitionStrategy = "karmarkar-karp"
+	// PartitionStrategyRoundRobin assigns test files to partitions in turn, without considering their timings
+	PartitionStrategyRoundRobin PartitionStrategy = "round-robin"
+	// PartitionStrategyHashedStable assigns test files to partitions by the hash of their path, so that a test file
+	// stays on the same partition when other test files are added or removed
+	PartitionStrategyHashedStable PartitionStrategy = "hashed-stable"
+)
+
+// PartitionStrategies are all the supported partitioning strategies
+var PartitionStrategies = []PartitionStrategy{
+	PartitionStrategyGreedy,
+	PartitionStrategyKarmarkarKarp,
+	PartitionStrategyRoundRobin,
+	PartitionStrategyHashedStable,
+}
+
+// PartitionStrategyNames returns the names of all supported partitioning strategies, e.g. for help texts
+func PartitionStrategyNames() string {
+	names := make([]string, len(PartitionStrategies))
+	for i, strategy := range PartitionStrategies {
+		names[i] = fmt.Sprintf("'%v'", strategy)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func (ps PartitionStrategy) validate() error {
+	for _, strategy := range PartitionStrategies {
+		if ps == strategy {
+			return nil
+		}
+	}
+
+	return errors.NewConfigurationError(
+		"Unsupported partitioning strategy",
+		fmt.Sprintf("Captain does not know the partitioning strategy %q.", string(ps)),
+		fmt.Sprintf("Please set the strategy to one of %v.", PartitionStrategyNames()),
+	)
+}
+
+// usesTimings returns whether the strategy balances the partitions based on historical test timings
+func (ps PartitionStrategy) usesTimings() bool {
+	return ps == PartitionStrategyGreedy || ps == PartitionStrategyKarmarkarKarp
+}
+
+// partitionItem is either a test file or an individual test of a split test file
+type partitionItem struct {
+	file *testing.FileTimingMatch
+	test *testing.TestTimingMatch
+}
+
+func (i partitionItem) duration() time.Duration {
+	if i.test != nil {
+		return i.test.Duration()
+	}
+
+	return i.file.Duration()
+}
+
+func (i partitionItem) String() string {
+	if i.test != nil {
+		return i.test.String()
+	}
+
+	return i.file.String()
+}
+
+func (i partitionItem) addTo(partition testing.TestPartition) testing.TestPartition {
+	if i.test != nil {
+		return partition.AddTest(*i.test)
+	}
+
+	return partition.Add(*i.file)
+}
+
+// partitionItems merges the test files and individual tests, which are both sorted by their duration, into a single
+// list that is sorted by duration. Test files go first when they take as long as a test.
+func partitionItems(
+	fileTimingMatches []testing.FileTimingMatch,
+	testTimingMatches []testing.TestTimingMatch,
+) []partitionItem {
+	items := make([]partitionItem, 0, len(fileTimingMatches)+len(testTimingMatches))
+
+	for len(fileTimingMatches) > 0 || len(testTimingMatches) > 0 {
+		if len(testTimingMatches) == 0 ||
+			(len(fileTimingMatches) > 0 && fileTimingMatches[0].Duration() >= testTimingMatches[0].Duration()) {
+			items = append(items, partitionItem{file: &fileTimingMatches[0]})
+			fileTimingMatches = fileTimingMatches[1:]
+			continue
+		}
+
+		items = append(items, partitionItem{test: &testTimingMatches[0]})
+		testTimingMatches = testTimingMatches[1:]
+	}
+
+	return items
+}
+
+// differencingSet is one of the partitions within a partial partitioning of the largest differencing method
+type differencingSet struct {
+	runtime time.Duration
+	items   []int
+}
+
+// differencingTuple is a partial partitioning of the largest differencing method. Its sets are sorted by their
+// runtime, slowest first.
+type differencingTuple struct {
+	sets     []differencingSet
+	sequence int
+}
+
+func (t differencingTuple) difference() time.Duration {
+	return t.sets[0].runtime - t.sets[len(t.sets)-1].runtime
+}
+
+// differencingHeap is a max-heap of partial partitionings by their difference. Ties are broken by the order in which
+// the partitionings were created so that the result is deterministic.
+type differencingHeap []differencingTuple
+
+func (h differencingHeap) Len() int { return len(h) }
+
+func (h differencingHeap) Less(i, j int) bool {
+	if h[i].difference() == h[j].difference() {
+		return h[i].sequence < h[j].sequence
+	}
+
+	return h[i].difference() > h[j].difference()
+}
+
+func (h differencingHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *differencingHeap) Push(x any) { *h = append(*h, x.(differencingTuple)) }
+
+func (h *differencingHeap) Pop() any {
+	old := *h
+	n := len(old)
+	tuple := old[n-1]
+	*h = old[:n-1]
+	return tuple
+}
+
+// karmarkarKarp partitions the items using the largest differencing method for any number of partitions. It returns
+// the indexes of the items of every partition, slowest partition first.
+func karmarkarKarp(items []partitionItem, total int) [][]int {
+	tuples := make(differencingHeap, 0, len(items))
+	for i, item := range items {
+		sets := make([]differencingSet, total)
+		sets[0] = differencingSet{runtime: item.duration(), items: []int{i}}
+		tuples = append(tuples, differencingTuple{sets: sets, sequence: i})
+	}
+	heap.Init(&tuples)
+
+	sequence := len(items)
+	for tuples.Len() > 1 {
+		first := heap.Pop(&tuples).(differencingTuple)
+		second := heap.Pop(&tuples).(differencingTuple)
+
+		// combine the slowest sets of one partitioning with the fastest sets of the other. The popped partitionings
+		// aren't used anymore, so their sets can be extended in place.
+		sets := first.sets
+		for i := range sets {
+			other := second.sets[total-1-i]
+			sets[i].runtime += other.runtime
+			sets[i].items = append(sets[i].items, other.items...)
+		}
+		sort.SliceStable(sets, func(i, j int) bool { return sets[i].runtime > sets[j].runtime })
+
+		heap.Push(&tuples, differencingTuple{sets: sets, sequence: sequence})
+		sequence++
+	}
+
+	partitions := make([][]int, total)
+	if tuples.Len() == 0 {
+		return partitions
+	}
+
+	for i, set := range tuples[0].sets {
+		partitions[i] = set.items
+		sort.Ints(partitions[i])
+	}
+
+	return partitions
+}
+
+// hashedPartitionIndex returns the partition of a test file based on the hash of its path. With weights, every
+// partition covers a range of hashes that is proportional to its weight.
+func hashedPartitionIndex(testFilePath string, nodes config.PartitionNodes) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(testFilePath))
+
+	if !nodes.IsWeighted() {
+		return int(hash.Sum32() % uint32(nodes.Total)) //nolint:gosec // the number of partitions is always positive
+	}
+
+	position := float64(hash.Sum32()) / (math.MaxUint32 + 1) * nodes.TotalWeight()
+	for i := 0; i < nodes.Total; i++ {
+		position -= nodes.Weight(i)
+		if position < 0 {
+			return i
+		}
+	}
+
+	return nodes.Total - 1
+}
@@ -2,19 +2,27 @@ package cli_test
 
 import (
 	"context"
+	"io"
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest"
 	"go.uber.org/zap/zaptest/observer"
 
+	"github.com/rwx-research/captain-cli/internal/backend/local"
 	"github.com/rwx-research/captain-cli/internal/cli"
 	"github.com/rwx-research/captain-cli/internal/config"
 	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/exec"
+	"github.com/rwx-research/captain-cli/internal/fs"
 	"github.com/rwx-research/captain-cli/internal/mocks"
 	"github.com/rwx-research/captain-cli/internal/parsing"
 	"github.com/rwx-research/captain-cli/internal/testing"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -93,6 +101,20 @@ var _ = Describe("Partition", func() {
 			err = service.Partition(ctx, cfgWithArgs(0, 1, []string{}, " ", false, ""))
 			Expect(err.Error()).To(ContainSubstring("Missing test file paths"))
 		})
+
+		It("requires a known strategy", func() {
+			cfg := cfgWithGlob(0, 2, "*.test")
+			cfg.Strategy = "fastest"
+			err = service.Partition(ctx, cfg)
+			Expect(err.Error()).To(ContainSubstring("Unsupported partitioning strategy"))
+		})
+
+		It("doesn't allow round robin together with another strategy", func() {
+			cfg := cfgWithGlobAndRoundRobin(0, 2, "*.test")
+			cfg.Strategy = cli.PartitionStrategyKarmarkarKarp
+			err = service.Partition(ctx, cfg)
+			Expect(err.Error()).To(ContainSubstring("Conflicting partitioning strategies"))
+		})
 	})
 
 	Context("when the client provides multiple globs", func() {
@@ -184,6 +206,40 @@ var _ = Describe("Partition", func() {
 			}
 			Expect(logMessages).To(ContainElement("b.test c.test"))
 		})
+
+		It("logs the partition with its expected runtime when doing a dry run", func() {
+			cfg := cfgWithGlob(1, 2, "*.test")
+			cfg.DryRun = true
+			Expect(service.Partition(ctx, cfg)).To(Succeed())
+
+			logMessages := make([]string, 0)
+			for _, log := range recordedLogs.FilterLevelExact(zap.InfoLevel).All() {
+				logMessages = append(logMessages, log.Message)
+			}
+			Expect(logMessages).To(ContainElement(
+				"\nPartition 1/2 with 2 test files (expected runtime: 5ns):\n  b.test\n  c.test",
+			))
+		})
+
+		It("logs the partition as JSON when doing a dry run with the JSON format", func() {
+			cfg := cfgWithGlob(0, 2, "*.test")
+			cfg.DryRun = true
+			cfg.DryRunFormat = "json"
+			Expect(service.Partition(ctx, cfg)).To(Succeed())
+
+			logMessages := make([]string, 0)
+			for _, log := range recordedLogs.FilterLevelExact(zap.InfoLevel).All() {
+				logMessages = append(logMessages, log.Message)
+			}
+			Expect(logMessages).To(ContainElement(MatchJSON(`{
+				"partition": {
+					"index": 0,
+					"total": 2,
+					"testFilePaths": ["a.test", "d.test"],
+					"expectedRuntimeInNanoseconds": 5
+				}
+			}`)))
+		})
 	})
 
 	Context("when round robin is specified", func() {
@@ -402,7 +458,7 @@ var _ = Describe("Partition", func() {
 			Expect(fetchedTimingManifest).To(BeTrue())
 		})
 
-		It("uses first fit, falls back to most remaining, then round robins unknowns", func() {
+		It("uses first fit with the median timing as an estimate for unknowns", func() {
 			_ = service.Partition(ctx, cfgWithGlob(1, 2, "*.test"))
 
 			assignments := make([]string, 0)
@@ -410,12 +466,13 @@ var _ = Describe("Partition", func() {
 				assignments = append(assignments, log.Message)
 			}
 			Expect(assignments).To(ContainElements([]string{
-				"Total Runtime: 13ns",
-				"Target Partition Runtime: 6ns",
+				"Estimated a timing of 4ns for 'd.test' based on the median timing of the test files with timings",
+				"Total Runtime: 17ns",
+				"Target Partition Runtime: 8ns",
 				"[PART 0 (0.00s)]: Assigned 'a.test' (6ns) using least runtime strategy",
+				"[PART 1 (0.00s)]: Assigned 'd.test' (4ns, estimated) using least runtime strategy",
 				"[PART 1 (0.00s)]: Assigned 'b.test' (4ns) using least runtime strategy",
-				"[PART 1 (0.00s)]: Assigned 'c.test' (3ns) using least runtime strategy",
-				"[PART 0 (0.00s)]: Assigned 'd.test' using round robin strategy",
+				"[PART 0 (0.00s)]: Assigned 'c.test' (3ns) using least runtime strategy",
 			}))
 		})
 
@@ -425,7 +482,7 @@ var _ = Describe("Partition", func() {
 			for _, log := range recordedLogs.FilterLevelExact(zap.InfoLevel).All() {
 				logMessages = append(logMessages, log.Message)
 			}
-			Expect(logMessages).To(ContainElement("a.test d.test"))
+			Expect(logMessages).To(ContainElement("a.test c.test"))
 		})
 
 		It("logs the partitioned files for index 1", func() {
@@ -434,7 +491,33 @@ var _ = Describe("Partition", func() {
 			for _, log := range recordedLogs.FilterLevelExact(zap.InfoLevel).All() {
 				logMessages = append(logMessages, log.Message)
 			}
-			Expect(logMessages).To(ContainElement("b.test c.test"))
+			Expect(logMessages).To(ContainElement("d.test b.test"))
+		})
+
+		Context("when estimating by file size", func() {
+			BeforeEach(func() {
+				fileSizes := map[string]int64{"a.test": 300, "b.test": 200, "c.test": 100, "d.test": 400}
+				service.FileSystem.(*mocks.FileSystem).MockStat = func(name string) (os.FileInfo, error) {
+					return &mocks.FileInfo{FileName: name, FileSize: fileSizes[name]}, nil
+				}
+			})
+
+			It("scales the median timing by the size of the test file", func() {
+				cfg := cfgWithGlob(1, 2, "*.test")
+				cfg.EstimateByFileSize = true
+				Expect(service.Partition(ctx, cfg)).To(Succeed())
+
+				assignments := make([]string, 0)
+				for _, log := range recordedLogs.FilterLevelExact(zap.DebugLevel).All() {
+					assignments = append(assignments, log.Message)
+				}
+				Expect(assignments).To(ContainElements([]string{
+					"Estimated a timing of 8ns for 'd.test' based on the median timing of the test files with timings, " +
+						"scaled by file size",
+					"[PART 0 (0.00s)]: Assigned 'd.test' (8ns, estimated) using least runtime strategy",
+					"[PART 1 (0.00s)]: Assigned 'a.test' (6ns) using least runtime strategy",
+				}))
+			})
 		})
 	})
 
@@ -737,4 +820,524 @@ var _ = Describe("Partition", func() {
 			Expect(logMessages).To(ContainElement("test/b.test test/c.test"))
 		})
 	})
+
+	Context("when splitting slow test files into their tests", func() {
+		var fetchedTestTimings bool
+
+		BeforeEach(func() {
+			fetchedTestTimings = false
+
+			service.FileSystem.(*mocks.FileSystem).MockGlob = func(_ string) ([]string, error) {
+				return []string{"a.test", "b.test", "c.test"}, nil
+			}
+			service.API.(*mocks.API).MockGetTestTimingManifest = func(
+				_ context.Context,
+				_ string,
+			) ([]testing.TestFileTiming, error) {
+				return []testing.TestFileTiming{
+					{Filepath: "a.test", Duration: 6},
+					{Filepath: "b.test", Duration: 2},
+					{Filepath: "c.test", Duration: 2},
+				}, nil
+			}
+			service.API.(*mocks.API).MockGetTestTimings = func(
+				_ context.Context,
+				_ string,
+			) ([]testing.TestTiming, error) {
+				fetchedTestTimings = true
+				return []testing.TestTiming{
+					{ID: "a.test[1]", Name: "first", Filepath: "a.test", Duration: 3},
+					{ID: "a.test[2]", Name: "second", Filepath: "a.test", Duration: 3},
+					{ID: "b.test[1]", Name: "third", Filepath: "b.test", Duration: 2},
+				}, nil
+			}
+		})
+
+		It("spreads the tests of the slow test files across partitions", func() {
+			cfg := cfgWithGlob(0, 2, "*.test")
+			cfg.SplitTests = true
+			cfg.DryRun = true
+			cfg.DryRunFormat = "json"
+			Expect(service.Partition(ctx, cfg)).To(Succeed())
+			Expect(fetchedTestTimings).To(BeTrue())
+
+			logMessages := make([]string, 0)
+			for _, log := range recordedLogs.FilterLevelExact(zap.InfoLevel).All() {
+				logMessages = append(logMessages, log.Message)
+			}
+			Expect(logMessages).To(ContainElement(MatchJSON(`{
+				"partition": {
+					"index": 0,
+					"total": 2,
+					"testFilePaths": ["c.test"],
+					"tests": ["a.test[1]"],
+					"expectedRuntimeInNanoseconds": 5
+				}
+			}`)))
+		})
+
+		It("keeps whole test files when none of them are slow", func() {
+			cfg := cfgWithGlob(0, 1, "*.test")
+			cfg.SplitTests = true
+			Expect(service.Partition(ctx, cfg)).To(Succeed())
+
+			logMessages := make([]string, 0)
+			for _, log := range recordedLogs.FilterLevelExact(zap.InfoLevel).All() {
+				logMessages = append(logMessages, log.Message)
+			}
+			Expect(logMessages).To(ContainElement("a.test c.test b.test"))
+		})
+	})
+
+	Context("with a partitioning strategy", func() {
+		var testFilePaths []string
+
+		partitionWith := func(strategy cli.PartitionStrategy, index int) string {
+			cfg := cfgWithGlob(index, 2, "*.test")
+			cfg.Strategy = strategy
+			Expect(service.Partition(ctx, cfg)).To(Succeed())
+
+			logs := recordedLogs.FilterLevelExact(zap.InfoLevel).All()
+			return logs[len(logs)-1].Message
+		}
+
+		BeforeEach(func() {
+			testFilePaths = []string{"a.test", "b.test", "c.test", "d.test", "e.test", "f.test"}
+
+			service.FileSystem.(*mocks.FileSystem).MockGlob = func(_ string) ([]string, error) {
+				return testFilePaths, nil
+			}
+			service.API.(*mocks.API).MockGetTestTimingManifest = func(
+				_ context.Context,
+				_ string,
+			) ([]testing.TestFileTiming, error) {
+				fetchedTimingManifest = true
+				return []testing.TestFileTiming{
+					{Filepath: "a.test", Duration: 8},
+					{Filepath: "b.test", Duration: 7},
+					{Filepath: "c.test", Duration: 6},
+					{Filepath: "d.test", Duration: 5},
+					{Filepath: "e.test", Duration: 4},
+				}, nil
+			}
+		})
+
+		It("balances the partitions with the largest differencing method", func() {
+			Expect(partitionWith(cli.PartitionStrategyKarmarkarKarp, 0)).To(Equal("b.test f.test d.test"))
+			Expect(partitionWith(cli.PartitionStrategyKarmarkarKarp, 1)).To(Equal("a.test c.test e.test"))
+		})
+
+		It("keeps test files on the same partition with the hashed stable strategy", func() {
+			before := strings.Fields(partitionWith(cli.PartitionStrategyHashedStable, 0))
+			testFilePaths = append(testFilePaths, "g.test", "h.test")
+			after := strings.Fields(partitionWith(cli.PartitionStrategyHashedStable, 0))
+
+			Expect(after).To(ContainElements(before))
+			Expect(fetchedTimingManifest).To(BeFalse())
+		})
+
+		It("explains every partition", func() {
+			cfg := cfgWithGlob(0, 2, "*.test")
+			cfg.Explain = true
+			Expect(service.Partition(ctx, cfg)).To(Succeed())
+
+			logMessages := make([]string, 0)
+			for _, log := range recordedLogs.FilterLevelExact(zap.InfoLevel).All() {
+				logMessages = append(logMessages, log.Message)
+			}
+			Expect(logMessages).To(ContainElement(MatchJSON(`{
+				"strategy": "greedy",
+				"total": 2,
+				"expectedRuntimeInNanoseconds": 18,
+				"imbalancePercentage": 0,
+				"partitions": [
+					{
+						"index": 0,
+						"testFilePaths": ["a.test", "c.test", "e.test"],
+						"untimedTestFilePaths": [],
+						"expectedRuntimeInNanoseconds": 18,
+						"imbalancePercentage": 0
+					},
+					{
+						"index": 1,
+						"testFilePaths": ["b.test", "f.test", "d.test"],
+						"untimedTestFilePaths": ["f.test"],
+						"expectedRuntimeInNanoseconds": 18,
+						"imbalancePercentage": 0
+					}
+				]
+			}`)))
+		})
+
+		It("estimates the runtimes of strategies that don't use timings when explaining", func() {
+			cfg := cfgWithGlob(0, 2, "*.test")
+			cfg.Explain = true
+			cfg.Strategy = cli.PartitionStrategyRoundRobin
+			Expect(service.Partition(ctx, cfg)).To(Succeed())
+
+			logMessages := make([]string, 0)
+			for _, log := range recordedLogs.FilterLevelExact(zap.InfoLevel).All() {
+				logMessages = append(logMessages, log.Message)
+			}
+			Expect(logMessages).To(ContainElement(ContainSubstring(`"strategy": "round-robin"`)))
+			Expect(logMessages).To(ContainElement(ContainSubstring(`"expectedRuntimeInNanoseconds": 18`)))
+		})
+
+		Context("with partition weights", func() {
+			weightedPartition := func(strategy cli.PartitionStrategy, index int) string {
+				cfg := cfgWithGlob(index, 2, "*.test")
+				cfg.Strategy = strategy
+				cfg.PartitionNodes.Weights = []float64{1, 2}
+				Expect(service.Partition(ctx, cfg)).To(Succeed())
+
+				logs := recordedLogs.FilterLevelExact(zap.InfoLevel).All()
+				return logs[len(logs)-1].Message
+			}
+
+			It("gives every partition a share of the runtime that is proportional to its weight", func() {
+				Expect(weightedPartition(cli.PartitionStrategyGreedy, 0)).To(Equal("a.test d.test"))
+				Expect(weightedPartition(cli.PartitionStrategyGreedy, 1)).To(Equal("b.test f.test c.test e.test"))
+			})
+
+			It("hands out test files in proportion to the weights with the round-robin strategy", func() {
+				Expect(weightedPartition(cli.PartitionStrategyRoundRobin, 0)).To(Equal("b.test e.test"))
+				Expect(weightedPartition(cli.PartitionStrategyRoundRobin, 1)).To(Equal("a.test c.test d.test f.test"))
+			})
+
+			It("explains the imbalance relative to the weights", func() {
+				cfg := cfgWithGlob(0, 2, "*.test")
+				cfg.PartitionNodes.Weights = []float64{1, 2}
+				cfg.Explain = true
+				Expect(service.Partition(ctx, cfg)).To(Succeed())
+
+				logs := recordedLogs.FilterLevelExact(zap.InfoLevel).All()
+				Expect(logs[len(logs)-1].Message).To(ContainSubstring(`"imbalancePercentage": 8.33`))
+				Expect(logs[len(logs)-1].Message).To(ContainSubstring(`"imbalancePercentage": -4.17`))
+			})
+
+			It("requires one weight per partition", func() {
+				cfg := cfgWithGlob(0, 2, "*.test")
+				cfg.PartitionNodes.Weights = []float64{1, 2, 3}
+
+				err := service.Partition(ctx, cfg)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Mismatching partition weights"))
+			})
+
+			It("doesn't support weights with the karmarkar-karp strategy", func() {
+				cfg := cfgWithGlob(0, 2, "*.test")
+				cfg.PartitionNodes.Weights = []float64{1, 2}
+				cfg.Strategy = cli.PartitionStrategyKarmarkarKarp
+
+				err := service.Partition(ctx, cfg)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Unsupported partition weights"))
+			})
+		})
+	})
+
+	Context("with sticky partitions", func() {
+		var (
+			partitionsFile *mocks.File
+			previous       string
+			testFilePaths  []string
+		)
+
+		partitionWith := func(cfg cli.PartitionConfig) []string {
+			api, err := local.NewClient(
+				service.FileSystem, "flakes.yaml", "quarantines.yaml", "timings.yaml", "", "", "partitions.yaml",
+				local.TimingHistory{},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			service.API = api
+
+			Expect(service.Partition(ctx, cfg)).To(Succeed())
+
+			logMessages := make([]string, 0)
+			for _, log := range recordedLogs.FilterLevelExact(zap.InfoLevel).All() {
+				logMessages = append(logMessages, log.Message)
+			}
+			return logMessages
+		}
+
+		stickyCfg := func(threshold float64) cli.PartitionConfig {
+			cfg := cfgWithGlob(0, 2, "*.test")
+			cfg.Sticky = true
+			cfg.StickyThreshold = &threshold
+			return cfg
+		}
+
+		BeforeEach(func() {
+			testFilePaths = []string{"a.test", "b.test", "c.test", "d.test"}
+			previous = "total: 2\ntest-files:\n  a.test: 0\n  c.test: 0\n  b.test: 1\n  d.test: 1\n"
+			partitionsFile = &mocks.File{Builder: new(strings.Builder)}
+
+			mockFileSystem := service.FileSystem.(*mocks.FileSystem)
+			mockFileSystem.MockGlob = func(_ string) ([]string, error) {
+				return testFilePaths, nil
+			}
+			mockFileSystem.MockOpen = func(name string) (fs.File, error) {
+				switch name {
+				case "timings.yaml":
+					return &mocks.File{Reader: strings.NewReader("a.test: 4s\nb.test: 3s\nc.test: 2s\nd.test: 1s\n")}, nil
+				case "partitions.yaml":
+					return &mocks.File{Reader: strings.NewReader(previous)}, nil
+				default:
+					return &mocks.File{Reader: strings.NewReader("")}, nil
+				}
+			}
+			mockFileSystem.MockCreate = func(name string) (fs.File, error) {
+				Expect(name).To(Equal("partitions.yaml"))
+				return partitionsFile, nil
+			}
+		})
+
+		It("keeps test files on their previous partitions within the threshold", func() {
+			Expect(partitionWith(stickyCfg(25))).To(ContainElement("a.test c.test"))
+			Expect(partitionsFile.String()).To(MatchYAML(previous))
+		})
+
+		It("rebalances the partitions once they are too imbalanced", func() {
+			Expect(partitionWith(stickyCfg(10))).To(ContainElement("a.test d.test"))
+			Expect(partitionsFile.String()).To(MatchYAML(
+				"total: 2\ntest-files:\n  a.test: 0\n  d.test: 0\n  b.test: 1\n  c.test: 1\n",
+			))
+		})
+
+		It("assigns new test files and forgets deleted ones", func() {
+			testFilePaths = []string{"a.test", "b.test", "c.test", "e.test"}
+
+			Expect(partitionWith(stickyCfg(25))).To(ContainElement("a.test c.test"))
+			Expect(partitionsFile.String()).To(MatchYAML(
+				"total: 2\ntest-files:\n  a.test: 0\n  c.test: 0\n  b.test: 1\n  e.test: 1\n",
+			))
+		})
+
+		It("rebalances when the number of partitions changed", func() {
+			cfg := stickyCfg(25)
+			cfg.PartitionNodes.Total = 3
+
+			Expect(partitionWith(cfg)).To(ContainElement("a.test"))
+			Expect(partitionsFile.String()).To(ContainSubstring("total: 3"))
+		})
+
+		It("explains how many test files moved without storing the assignments", func() {
+			cfg := stickyCfg(10)
+			cfg.Explain = true
+
+			Expect(partitionWith(cfg)).To(ContainElement(ContainSubstring(`"movedTestFiles": 2`)))
+			Expect(partitionsFile.String()).To(BeEmpty())
+		})
+
+		It("only works in OSS mode", func() {
+			err := service.Partition(ctx, stickyCfg(10))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Sticky partitioning only works in OSS mode"))
+		})
+
+		It("requires a strategy that balances partitions by their timings", func() {
+			cfg := stickyCfg(10)
+			cfg.Strategy = cli.PartitionStrategyRoundRobin
+
+			err := service.Partition(ctx, cfg)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unsupported sticky partitioning"))
+		})
+	})
+
+	Context("with Go packages", func() {
+		var goListArgs []string
+		var goListErr error
+
+		BeforeEach(func() {
+			goListArgs = nil
+			goListErr = nil
+
+			service.TaskRunner.(*mocks.TaskRunner).MockNewCommand = func(
+				_ context.Context,
+				cfg exec.CommandConfig,
+			) (exec.Command, error) {
+				Expect(cfg.Name).To(Equal("go"))
+				goListArgs = cfg.Args
+
+				command := new(mocks.Command)
+				command.MockStart = func() error {
+					_, err := cfg.Stdout.Write([]byte("example.com/pkg/a\nexample.com/pkg/b\nexample.com/pkg/c\n" +
+						"example.com/pkg/d\n"))
+					return err
+				}
+				command.MockWait = func() error {
+					if goListErr != nil {
+						_, _ = cfg.Stderr.Write([]byte("pattern ./nope/...: directory not found"))
+					}
+					return goListErr
+				}
+				return command, nil
+			}
+
+			service.API.(*mocks.API).MockGetTestTimingManifest = func(
+				_ context.Context,
+				_ string,
+			) ([]testing.TestFileTiming, error) {
+				return []testing.TestFileTiming{
+					{Filepath: "example.com/pkg/a", Duration: 8},
+					{Filepath: "example.com/pkg/b", Duration: 2},
+					{Filepath: "example.com/pkg/c", Duration: 5},
+				}, nil
+			}
+		})
+
+		packagesCfg := func(index int) cli.PartitionConfig {
+			cfg := cfgWithGlob(index, 2, "./...")
+			cfg.Packages = true
+			return cfg
+		}
+
+		It("lists the packages with tests that match the patterns", func() {
+			Expect(service.Partition(ctx, packagesCfg(0))).To(Succeed())
+			Expect(goListArgs).To(Equal([]string{
+				"list", "-f", "{{if or .TestGoFiles .XTestGoFiles}}{{.ImportPath}}{{end}}", "./...",
+			}))
+		})
+
+		It("partitions the import paths by their timings", func() {
+			Expect(service.Partition(ctx, packagesCfg(0))).To(Succeed())
+			Expect(service.Partition(ctx, packagesCfg(1))).To(Succeed())
+
+			logs := recordedLogs.FilterLevelExact(zap.InfoLevel).All()
+			Expect(logs).To(HaveLen(2))
+			Expect(logs[0].Message).To(Equal("example.com/pkg/a example.com/pkg/b"))
+			Expect(logs[1].Message).To(Equal("example.com/pkg/d example.com/pkg/c"))
+		})
+
+		It("errs when the packages can't be listed", func() {
+			goListErr = errors.NewExecutionError(1, "exit status 1")
+
+			err := service.Partition(ctx, packagesCfg(0))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unable to list the Go packages matching \"./...\""))
+			Expect(err.Error()).To(ContainSubstring("directory not found"))
+		})
+	})
+
+	Context("with timings from test results files", func() {
+		BeforeEach(func() {
+			service.ParseConfig = parsing.Config{
+				MutuallyExclusiveParsers: []parsing.Parser{new(mocks.Parser)},
+				Logger:                   service.Log,
+			}
+
+			service.FileSystem.(*mocks.FileSystem).MockGlob = func(pattern string) ([]string, error) {
+				if pattern == "artifacts/*.json" {
+					return []string{"artifacts/1.json", "artifacts/2.json"}, nil
+				}
+				return []string{"a.test", "b.test", "c.test", "d.test"}, nil
+			}
+			service.FileSystem.(*mocks.FileSystem).MockOpen = func(name string) (fs.File, error) {
+				return &mocks.File{Reader: strings.NewReader(name)}, nil
+			}
+
+			test := func(file string, duration time.Duration) v1.Test {
+				return v1.Test{
+					Name:     file + " test",
+					Location: &v1.Location{File: file},
+					Attempt:  v1.TestAttempt{Duration: &duration, Status: v1.NewSuccessfulTestStatus()},
+				}
+			}
+
+			service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(
+				reader io.Reader,
+			) (*v1.TestResults, error) {
+				name, err := io.ReadAll(reader)
+				Expect(err).ToNot(HaveOccurred())
+
+				results := v1.TestResults{Framework: v1.RubyRSpecFramework}
+				if string(name) == "artifacts/1.json" {
+					results.Tests = []v1.Test{test("a.test", 4), test("a.test", 2), test("b.test", 3)}
+				} else {
+					results.Tests = []v1.Test{test("a.test", 10), test("c.test", 1)}
+				}
+				return &results, nil
+			}
+		})
+
+		timingsFromCfg := func(index int) cli.PartitionConfig {
+			cfg := cfgWithGlob(index, 2, "*.test")
+			cfg.TimingsFrom = []string{"artifacts/*.json"}
+			return cfg
+		}
+
+		It("partitions by the average timings of the test results files instead of fetching the timings", func() {
+			Expect(service.Partition(ctx, timingsFromCfg(0))).To(Succeed())
+			Expect(service.Partition(ctx, timingsFromCfg(1))).To(Succeed())
+
+			logs := recordedLogs.FilterLevelExact(zap.InfoLevel).All()
+			Expect(logs).To(HaveLen(2))
+			Expect(logs[0].Message).To(Equal("a.test"))
+			Expect(logs[1].Message).To(Equal("d.test b.test c.test"))
+
+			debugLogs := recordedLogs.FilterLevelExact(zap.DebugLevel).All()
+			debugMessages := make([]string, 0, len(debugLogs))
+			for _, log := range debugLogs {
+				debugMessages = append(debugMessages, log.Message)
+			}
+			Expect(debugMessages).To(ContainElement("Read the timings of 3 test files from 2 test results files"))
+			Expect(debugMessages).To(ContainElement(ContainSubstring("'a.test' (8ns)")))
+		})
+
+		It("falls back to the packages of go test results without locations, skipping subtests", func() {
+			goTest := func(name string, importPath string, duration time.Duration) v1.Test {
+				return v1.Test{
+					Name: name,
+					Attempt: v1.TestAttempt{
+						Duration: &duration,
+						Status:   v1.NewSuccessfulTestStatus(),
+						Meta:     map[string]any{"package": importPath},
+					},
+				}
+			}
+
+			service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(
+				reader io.Reader,
+			) (*v1.TestResults, error) {
+				name, err := io.ReadAll(reader)
+				Expect(err).ToNot(HaveOccurred())
+
+				results := v1.TestResults{Framework: v1.GoTestFramework}
+				if string(name) == "artifacts/1.json" {
+					results.Tests = []v1.Test{
+						goTest("TestA", "a.test", 6),
+						goTest("TestA/subtest", "a.test", 5),
+						goTest("TestB", "b.test", 3),
+					}
+				} else {
+					results.Tests = []v1.Test{goTest("TestA", "a.test", 10)}
+				}
+				return &results, nil
+			}
+
+			Expect(service.Partition(ctx, timingsFromCfg(0))).To(Succeed())
+
+			debugLogs := recordedLogs.FilterLevelExact(zap.DebugLevel).All()
+			debugMessages := make([]string, 0, len(debugLogs))
+			for _, log := range debugLogs {
+				debugMessages = append(debugMessages, log.Message)
+			}
+			Expect(debugMessages).To(ContainElement("Read the timings of 2 test files from 2 test results files"))
+			Expect(debugMessages).To(ContainElement(ContainSubstring("'a.test' (8ns)")))
+			Expect(debugMessages).To(ContainElement(ContainSubstring("'b.test' (3ns)")))
+		})
+
+		It("errs when no test results files match", func() {
+			cfg := timingsFromCfg(0)
+			cfg.TimingsFrom = []string{"missing/*.json"}
+			service.FileSystem.(*mocks.FileSystem).MockGlob = func(_ string) ([]string, error) {
+				return []string{}, nil
+			}
+
+			err := service.Partition(ctx, cfg)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Missing test results files"))
+		})
+	})
 })
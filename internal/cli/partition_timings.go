@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/testing"
+)
+
+// timingSource provides the historical timings that partitions are balanced by. Usually, this is the backend.
+type timingSource interface {
+	GetTestTimingManifest(ctx context.Context, suiteID string) ([]testing.TestFileTiming, error)
+	GetTestTimings(ctx context.Context, suiteID string) ([]testing.TestTiming, error)
+}
+
+// resultsTimings are the timings of test files and tests that were read from test results files
+type resultsTimings struct {
+	fileTimings []testing.TestFileTiming
+	testTimings []testing.TestTiming
+}
+
+func (rt resultsTimings) GetTestTimingManifest(_ context.Context, _ string) ([]testing.TestFileTiming, error) {
+	return rt.fileTimings, nil
+}
+
+func (rt resultsTimings) GetTestTimings(_ context.Context, _ string) ([]testing.TestTiming, error) {
+	return rt.testTimings, nil
+}
+
+// timingSource returns where the timings of a partition config come from, which is either the backend or the test
+// results files that the config points to
+func (s Service) timingSource(cfg PartitionConfig) (timingSource, error) {
+	if len(cfg.TimingsFrom) == 0 {
+		return s.API, nil
+	}
+
+	return s.timingsFromResults(cfg.TimingsFrom)
+}
+
+// timingsFromResults reads the timings of test files and tests from test results files, e.g. the artifacts of earlier
+// CI runs. Since these are usually from several runs, the timing of a test file or test is the average over all results
+// files that contain it.
+func (s Service) timingsFromResults(globs []string) (resultsTimings, error) {
+	resultsFilePaths, err := s.FileSystem.GlobMany(globs)
+	if err != nil {
+		return resultsTimings{}, errors.NewSystemError("unable to expand filepath glob: %s", err)
+	}
+
+	if len(resultsFilePaths) == 0 {
+		return resultsTimings{}, errors.NewConfigurationError(
+			"Missing test results files",
+			fmt.Sprintf("Captain was unable to find any test results files matching %q.", strings.Join(globs, " ")),
+			"Please make sure that the test results files of earlier runs are available before partitioning.",
+		)
+	}
+
+	fileDurations := make(map[string][]time.Duration)
+	testTimings := make(map[string][]testing.TestTiming)
+
+	for _, resultsFilePath := range resultsFilePaths {
+		results, err := s.parse([]string{resultsFilePath}, 1)
+		if err != nil {
+			return resultsTimings{}, errors.WithStack(err)
+		}
+
+		resultsFileDurations := make(map[string]time.Duration)
+		resultsTestTimings := make(map[string]testing.TestTiming)
+
+		for _, test := range results.Tests {
+			timingPath, ok := testing.TimingPath(test)
+			if !ok || test.Attempt.Duration == nil {
+				continue
+			}
+			resultsFileDurations[timingPath] += *test.Attempt.Duration
+
+			testTiming, ok := testing.NewTestTiming(test)
+			if !ok {
+				continue
+			}
+			if previousTestTiming, ok := resultsTestTimings[testTiming.Key()]; ok {
+				testTiming.Duration += previousTestTiming.Duration
+			}
+			resultsTestTimings[testTiming.Key()] = testTiming
+		}
+
+		for file, duration := range resultsFileDurations {
+			fileDurations[file] = append(fileDurations[file], duration)
+		}
+		for key, testTiming := range resultsTestTimings {
+			testTimings[key] = append(testTimings[key], testTiming)
+		}
+	}
+
+	s.Log.Debugf(
+		"Read the timings of %d test files from %d test results files", len(fileDurations), len(resultsFilePaths),
+	)
+
+	timings := resultsTimings{
+		fileTimings: make([]testing.TestFileTiming, 0, len(fileDurations)),
+		testTimings: make([]testing.TestTiming, 0, len(testTimings)),
+	}
+
+	for file, durations := range fileDurations {
+		timings.fileTimings = append(timings.fileTimings, testing.TestFileTiming{
+			Filepath: file,
+			Duration: averageDuration(durations),
+		})
+	}
+	sort.Slice(timings.fileTimings, func(i, j int) bool {
+		return timings.fileTimings[i].Filepath < timings.fileTimings[j].Filepath
+	})
+
+	for _, testTimingsOfTest := range testTimings {
+		testTiming := testTimingsOfTest[0]
+		durations := make([]time.Duration, len(testTimingsOfTest))
+		for i, runTestTiming := range testTimingsOfTest {
+			durations[i] = runTestTiming.Duration
+		}
+		testTiming.Duration = averageDuration(durations)
+
+		timings.testTimings = append(timings.testTimings, testTiming)
+	}
+	sort.Slice(timings.testTimings, func(i, j int) bool {
+		return timings.testTimings[i].Key() < timings.testTimings[j].Key()
+	})
+
+	return timings, nil
+}
+
+func averageDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	var total time.Duration
+	for _, duration := range durations {
+		total += duration
+	}
+
+	return total / time.Duration(len(durations))
+}
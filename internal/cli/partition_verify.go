@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// VerifyPartitions checks that the test results of all partitions together contain every test file exactly once. The
+// partitions are recalculated, so that test files that didn't run can be attributed to the partition they were
+// assigned to, which helps to notice partitions that were dropped silently.
+func (s Service) VerifyPartitions(ctx context.Context, cfg PartitionVerifyConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	partitionCfg := cfg.partitionConfig()
+	partitionResult, err := s.calculatePartition(ctx, partitionCfg)
+	if err != nil {
+		return err
+	}
+
+	runCounts, err := s.testFileRunCounts(cfg.ResultsGlobs)
+	if err != nil {
+		return err
+	}
+
+	testFileCount := 0
+	missingTestFileCount := 0
+	duplicatedTestFileCount := 0
+
+	for _, partition := range partitionResult.partitions {
+		missing := make([]string, 0)
+
+		for _, testFilePath := range partition.TestFilePaths {
+			testFileCount++
+
+			runCount := runCounts[s.verificationKey(strings.TrimPrefix(testFilePath, partitionCfg.TrimPrefix))]
+			switch {
+			case runCount == 0:
+				missing = append(missing, testFilePath)
+			case runCount > 1:
+				duplicatedTestFileCount++
+				s.Log.Warnf("Partition %d: '%s' ran %d times", partition.Index, testFilePath, runCount)
+			}
+		}
+
+		missingTestFileCount += len(missing)
+		if len(missing) > 0 && len(missing) == len(partition.TestFilePaths) {
+			s.Log.Warnf("Partition %d: none of its %d test files ran", partition.Index, len(missing))
+			continue
+		}
+
+		for _, testFilePath := range missing {
+			s.Log.Warnf("Partition %d: '%s' didn't run", partition.Index, testFilePath)
+		}
+	}
+
+	if missingTestFileCount > 0 || duplicatedTestFileCount > 0 {
+		return errors.NewConfigurationError(
+			"Incomplete partitions",
+			fmt.Sprintf(
+				"Out of %d test files, %d didn't run and %d ran more than once.",
+				testFileCount,
+				missingTestFileCount,
+				duplicatedTestFileCount,
+			),
+			"Please make sure that every partition ran and that the test results of all partitions are included. "+
+				"The partitions need to be verified with the same options, e.g. the same total, as they were run with.",
+		)
+	}
+
+	s.Log.Infof(
+		"All %d test files of the %d partitions ran exactly once",
+		testFileCount,
+		partitionCfg.PartitionNodes.Total,
+	)
+
+	return nil
+}
+
+// testFileRunCounts returns how many times each test file ran according to the test results files, keyed by their
+// verification key. A test file ran as many times as its most frequent test appears in the test results, so that
+// retries within a single run don't count as separate runs.
+func (s Service) testFileRunCounts(resultsGlobs []string) (map[string]int, error) {
+	resultsFilePaths, err := s.FileSystem.GlobMany(resultsGlobs)
+	if err != nil {
+		return nil, errors.NewSystemError("unable to expand filepath glob: %s", err)
+	}
+
+	testRunCounts := make(map[string]int)
+	testFiles := make(map[string]string)
+
+	for _, resultsFilePath := range resultsFilePaths {
+		results, err := s.parse([]string{resultsFilePath}, 1)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		for _, test := range results.Tests {
+			testFile, ok := verifiedTestFile(test)
+			if !ok {
+				continue
+			}
+
+			testKey := verifiedTestKey(testFile, test)
+			testRunCounts[testKey]++
+			testFiles[testKey] = testFile
+		}
+	}
+
+	s.Log.Debugf("Read the test results of %d tests from %d files", len(testRunCounts), len(resultsFilePaths))
+
+	runCounts := make(map[string]int)
+	for testKey, runCount := range testRunCounts {
+		key := s.verificationKey(testFiles[testKey])
+		runCounts[key] = max(runCounts[key], runCount)
+	}
+
+	return runCounts, nil
+}
+
+// verificationKey expands a test file path, so that the paths in test results can be compared with the partitioned
+// test file paths
+func (s Service) verificationKey(testFilePath string) string {
+	expandedFilepath, err := filepath.Abs(testFilePath)
+	if err != nil {
+		s.Log.Warnf("failed to expand path of test file: %s", testFilePath)
+		return testFilePath
+	}
+
+	return expandedFilepath
+}
+
+// verifiedTestFile returns the test file of a test or, for `go test` results without a location, its package
+func verifiedTestFile(test v1.Test) (string, bool) {
+	if test.Location != nil {
+		return test.Location.File, true
+	}
+
+	importPath, ok := test.Attempt.Meta["package"].(string)
+	return importPath, ok
+}
+
+// verifiedTestKey identifies a test across test results files
+func verifiedTestKey(testFile string, test v1.Test) string {
+	id := ""
+	if test.ID != nil {
+		id = *test.ID
+	}
+
+	return strings.Join(append([]string{testFile, id, test.Name}, test.Lineage...), "\x00")
+}
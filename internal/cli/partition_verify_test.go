@@ -0,0 +1,137 @@
+package cli_test
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/rwx-research/captain-cli/internal/cli"
+	"github.com/rwx-research/captain-cli/internal/fs"
+	"github.com/rwx-research/captain-cli/internal/mocks"
+	"github.com/rwx-research/captain-cli/internal/parsing"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VerifyPartitions", func() {
+	var (
+		service        cli.Service
+		recordedLogs   *observer.ObservedLogs
+		verifyConfig   cli.PartitionVerifyConfig
+		resultsFiles   []string
+		testsByResults map[string][]string
+	)
+
+	newTest := func(file string) v1.Test {
+		return v1.Test{
+			Name:     file + " test",
+			Location: &v1.Location{File: file},
+			Attempt:  v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()},
+		}
+	}
+
+	BeforeEach(func() {
+		var core zapcore.Core
+		core, recordedLogs = observer.New(zapcore.InfoLevel)
+		log := zaptest.NewLogger(GinkgoT(), zaptest.WrapOptions(
+			zap.WrapCore(func(_ zapcore.Core) zapcore.Core { return core }),
+		)).Sugar()
+
+		service = cli.Service{
+			API:        new(mocks.API),
+			Log:        log,
+			FileSystem: new(mocks.FileSystem),
+			ParseConfig: parsing.Config{
+				MutuallyExclusiveParsers: []parsing.Parser{new(mocks.Parser)},
+				Logger:                   log,
+			},
+		}
+
+		resultsFiles = []string{"results/0.json", "results/1.json"}
+		testsByResults = map[string][]string{
+			"results/0.json": {"a.test", "c.test"},
+			"results/1.json": {"b.test", "d.test"},
+		}
+
+		service.FileSystem.(*mocks.FileSystem).MockGlob = func(pattern string) ([]string, error) {
+			if pattern == "results/*.json" {
+				return resultsFiles, nil
+			}
+			return []string{"a.test", "b.test", "c.test", "d.test"}, nil
+		}
+		service.FileSystem.(*mocks.FileSystem).MockOpen = func(name string) (fs.File, error) {
+			return &mocks.File{Reader: strings.NewReader(name)}, nil
+		}
+		service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(
+			reader io.Reader,
+		) (*v1.TestResults, error) {
+			name, err := io.ReadAll(reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			results := v1.TestResults{Framework: v1.RubyRSpecFramework}
+			for _, file := range testsByResults[string(name)] {
+				results.Tests = append(results.Tests, newTest(file))
+			}
+			return &results, nil
+		}
+
+		verifyConfig = cli.PartitionVerifyConfig{
+			PartitionConfig: cfgWithGlobAndRoundRobin(0, 2, "*.test"),
+			ResultsGlobs:    []string{"results/*.json"},
+		}
+	})
+
+	logMessages := func() []string {
+		messages := make([]string, 0)
+		for _, log := range recordedLogs.All() {
+			messages = append(messages, log.Message)
+		}
+		return messages
+	}
+
+	It("succeeds when every test file ran exactly once", func() {
+		Expect(service.VerifyPartitions(context.Background(), verifyConfig)).To(Succeed())
+		Expect(logMessages()).To(ContainElement("All 4 test files of the 2 partitions ran exactly once"))
+	})
+
+	It("fails when a partition didn't run", func() {
+		resultsFiles = []string{"results/0.json"}
+
+		err := service.VerifyPartitions(context.Background(), verifyConfig)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Incomplete partitions"))
+		Expect(logMessages()).To(ContainElement("Partition 1: none of its 2 test files ran"))
+	})
+
+	It("fails when a test file didn't run", func() {
+		testsByResults["results/0.json"] = []string{"a.test"}
+
+		err := service.VerifyPartitions(context.Background(), verifyConfig)
+		Expect(err).To(HaveOccurred())
+		Expect(logMessages()).To(ContainElement("Partition 0: 'c.test' didn't run"))
+	})
+
+	It("fails when a test file ran more than once", func() {
+		testsByResults["results/1.json"] = []string{"b.test", "d.test", "a.test"}
+
+		err := service.VerifyPartitions(context.Background(), verifyConfig)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Incomplete partitions"))
+		Expect(logMessages()).To(ContainElement("Partition 0: 'a.test' ran 2 times"))
+	})
+
+	It("requires the test results of the partitions", func() {
+		verifyConfig.ResultsGlobs = nil
+
+		err := service.VerifyPartitions(context.Background(), verifyConfig)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Missing test results"))
+	})
+})
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"time"
+
+	"github.com/rwx-research/captain-cli/internal/config"
+	"github.com/rwx-research/captain-cli/internal/testing"
+)
+
+// targetRuntime returns the share of the total runtime that the partition at the index should take, which is
+// proportional to the weight of its node
+func targetRuntime(totalRuntime time.Duration, nodes config.PartitionNodes, index int) time.Duration {
+	if !nodes.IsWeighted() {
+		return totalRuntime / time.Duration(nodes.Total)
+	}
+
+	return time.Duration(float64(totalRuntime) * nodes.Weight(index) / nodes.TotalWeight())
+}
+
+// smallestTargetRuntime returns the target runtime of the partition with the least capacity
+func smallestTargetRuntime(totalRuntime time.Duration, nodes config.PartitionNodes) time.Duration {
+	smallest := targetRuntime(totalRuntime, nodes, 0)
+	for i := 1; i < nodes.Total; i++ {
+		smallest = min(smallest, targetRuntime(totalRuntime, nodes, i))
+	}
+
+	return smallest
+}
+
+// partitionImbalances returns by how many percent the runtime of every partition deviates from its share of the total
+// runtime, together with the largest of these deviations
+func partitionImbalances(partitions []testing.TestPartition, nodes config.PartitionNodes) ([]float64, float64) {
+	var totalRuntime time.Duration
+	for _, partition := range partitions {
+		totalRuntime += partition.Runtime
+	}
+
+	imbalances := make([]float64, len(partitions))
+	largestImbalance := 0.0
+	for i, partition := range partitions {
+		share := float64(totalRuntime) * nodes.Weight(partition.Index) / nodes.TotalWeight()
+		imbalances[i] = imbalancePercentage(partition.Runtime, share)
+		largestImbalance = max(largestImbalance, imbalances[i])
+	}
+
+	return imbalances, largestImbalance
+}
+
+// roundRobin hands out the partitions in turn. With weights, partitions are handed out as often as their weight
+// allows, spread out evenly (smooth weighted round-robin).
+type roundRobin struct {
+	nodes   config.PartitionNodes
+	count   int
+	current []float64
+}
+
+func newRoundRobin(nodes config.PartitionNodes) *roundRobin {
+	return &roundRobin{nodes: nodes, current: make([]float64, nodes.Total)}
+}
+
+func (rr *roundRobin) next() int {
+	if !rr.nodes.IsWeighted() {
+		index := rr.count % rr.nodes.Total
+		rr.count++
+		return index
+	}
+
+	selected := 0
+	for i := range rr.current {
+		rr.current[i] += rr.nodes.Weight(i)
+		if rr.current[i] > rr.current[selected] {
+			selected = i
+		}
+	}
+	rr.current[selected] -= rr.nodes.TotalWeight()
+
+	return selected
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+)
+
+// prefixWriterMutex serializes writes of all prefix writers, so lines of concurrent commands are never interleaved
+var prefixWriterMutex sync.Mutex
+
+// prefixWriter prefixes every line written to it before passing it on to the underlying writer. Incomplete lines are
+// buffered until they are either completed or flushed.
+type prefixWriter struct {
+	w      io.Writer
+	prefix []byte
+	buffer []byte
+}
+
+func newPrefixWriter(w io.Writer, prefix string) *prefixWriter {
+	// Prefix writers don't write to each other, as they'd wait on the mutex that they're already holding otherwise
+	if pw, ok := w.(*prefixWriter); ok {
+		return &prefixWriter{w: pw.w, prefix: append(append([]byte{}, pw.prefix...), prefix...)}
+	}
+
+	return &prefixWriter{w: w, prefix: []byte(prefix)}
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.buffer = append(pw.buffer, p...)
+
+	for {
+		i := bytes.IndexByte(pw.buffer, '\n')
+		if i < 0 {
+			break
+		}
+
+		if err := pw.writeLine(pw.buffer[:i+1]); err != nil {
+			return 0, err
+		}
+		pw.buffer = pw.buffer[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush writes out any incomplete line that is still buffered
+func (pw *prefixWriter) Flush() {
+	if len(pw.buffer) == 0 {
+		return
+	}
+
+	_ = pw.writeLine(append(pw.buffer, '\n'))
+	pw.buffer = nil
+}
+
+func (pw *prefixWriter) writeLine(line []byte) error {
+	prefixWriterMutex.Lock()
+	defer prefixWriterMutex.Unlock()
+
+	_, err := pw.w.Write(append(append([]byte{}, pw.prefix...), line...))
+	return errors.WithStack(err)
+}
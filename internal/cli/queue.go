@@ -0,0 +1,288 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/queue"
+	"github.com/rwx-research/captain-cli/internal/runpartition"
+	"github.com/rwx-research/captain-cli/internal/templating"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// QueueConfig configures the queue coordinator of `captain queue serve`
+type QueueConfig struct {
+	SuiteID       string
+	TestFilePaths []string
+	TrimPrefix    string
+	// Address is the address that the coordinator listens on, e.g. "0.0.0.0:8123"
+	Address   string
+	BatchSize int
+}
+
+func (qc QueueConfig) Validate() error {
+	if qc.SuiteID == "" {
+		return errors.NewConfigurationError(
+			"Missing suite ID",
+			"A suite ID is required in order to load the test file timings of the queue.",
+			"The suite ID can be set using the --suite-id flag or setting a CAPTAIN_SUITE_ID environment variable",
+		)
+	}
+
+	if len(qc.TestFilePaths) == 0 {
+		return errors.NewConfigurationError(
+			"Missing test file paths",
+			"No test file paths are provided.\n",
+			"Please specify the path or paths to your test files as arguments.\n\n"+
+				"\tcaptain queue serve [flags] <filepath>",
+		)
+	}
+
+	if qc.BatchSize < 1 {
+		return errors.NewConfigurationError(
+			"Unsupported batch size",
+			fmt.Sprintf("The queue needs to hand out at least one test file at a time, but the batch size is %d.", qc.BatchSize),
+			"Please set --batch-size to 1 or more.",
+		)
+	}
+
+	return nil
+}
+
+// ServeQueue hands out the test files in batches to `captain run --queue-url` until Captain is interrupted. The test
+// files are queued slowest first based on their historical timings, followed by the test files without timings.
+func (s Service) ServeQueue(ctx context.Context, cfg QueueConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	testFilePaths, err := s.FileSystem.GlobMany(cfg.TestFilePaths)
+	if err != nil {
+		return errors.NewSystemError("unable to expand filepath glob: %s", err)
+	}
+
+	fileTimingMatches, unmatchedFilepaths, err := s.matchFileTimings(
+		ctx,
+		PartitionConfig{SuiteID: cfg.SuiteID, TrimPrefix: cfg.TrimPrefix},
+		s.API,
+		testFilePaths,
+	)
+	if err != nil {
+		return err
+	}
+
+	queuedTestFilePaths := make([]string, 0, len(testFilePaths))
+	for _, fileTimingMatch := range fileTimingMatches {
+		queuedTestFilePaths = append(queuedTestFilePaths, fileTimingMatch.ClientFilepath)
+	}
+	queuedTestFilePaths = append(queuedTestFilePaths, unmatchedFilepaths...)
+
+	listener, err := net.Listen("tcp", cfg.Address)
+	if err != nil {
+		return errors.NewSystemError("unable to listen on %q: %s", cfg.Address, err)
+	}
+
+	server := &http.Server{
+		Handler:           queue.NewServer(queuedTestFilePaths, cfg.BatchSize, s.Log),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	interruptibleCtx, stopInterrupts := s.withInterrupts(ctx)
+	defer stopInterrupts()
+
+	go func() {
+		<-interruptibleCtx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	s.Log.Infof(
+		"Queueing %d test files (%d with timings) on http://%s",
+		len(queuedTestFilePaths),
+		len(fileTimingMatches),
+		listener.Addr(),
+	)
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.NewSystemError("unable to serve the queue: %s", err)
+	}
+
+	return nil
+}
+
+// queueWorker identifies this node towards the queue coordinator
+func queueWorker() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Sprintf("pid %d", os.Getpid())
+	}
+
+	return fmt.Sprintf("%s (pid %d)", hostname, os.Getpid())
+}
+
+// runQueuedBatches runs the partition command for batches of test files from the queue coordinator until the queue is
+// drained. The test results of all batches are merged, just like the test results of retries. When the run stops
+// before the queue is drained, e.g. because the coordinator went away, the batches that ran are still returned.
+func (s Service) runQueuedBatches(
+	ctx context.Context,
+	cfg RunConfig,
+	stdout io.Writer,
+) (*v1.TestResults, []string, error, error) {
+	client := queue.NewClient(cfg.QueueURL, queueWorker())
+
+	compiledTemplate, err := templating.CompileTemplate(cfg.PartitionCommandTemplate)
+	if err != nil {
+		return nil, nil, nil, errors.WithStack(err)
+	}
+
+	substitution := runpartition.DelimiterSubstitution{
+		Delimiter:  cfg.PartitionConfig.Delimiter,
+		FileSystem: s.FileSystem,
+	}
+	if err := substitution.ValidateTemplate(compiledTemplate); err != nil {
+		return nil, nil, nil, errors.WithStack(err)
+	}
+
+	ias, err := s.NewIntermediateArtifactStorage(cfg.IntermediateArtifactsPath)
+	if err != nil {
+		return nil, nil, nil, errors.WithStack(err)
+	}
+
+	var runErr error
+	var emptyTestResultsFiles []string
+	batchTestResults := make([]v1.TestResults, 0)
+	// queueErrors are the reasons why the queue wasn't drained, they're reported alongside the batches that ran
+	queueErrors := make([]v1.OtherError, 0)
+
+	for batchNumber := 1; ; batchNumber++ {
+		// Timeouts and interrupts can also stop the run in between two batches
+		if ctx.Err() != nil {
+			runErr = context.Cause(ctx)
+			if otherError, ok := terminationOtherError(runErr); ok {
+				queueErrors = append(queueErrors, otherError)
+			}
+			break
+		}
+
+		batch, err := client.NextBatch(ctx)
+		if err != nil {
+			if len(batchTestResults) == 0 {
+				return nil, nil, runErr, errors.WithStack(err)
+			}
+
+			// The batches that already ran are still reported, but the run fails as not every test file ran
+			s.Log.Errorf("Stopped pulling batches from the queue: %s", err.Error())
+			runErr = errors.WithStack(err)
+			queueErrors = append(queueErrors, v1.OtherError{Message: err.Error()})
+			break
+		}
+
+		if len(batch.TestFilePaths) == 0 {
+			s.Log.Debugf("The queue is drained after %d batches", batchNumber-1)
+			break
+		}
+
+		s.Log.Infof("Running batch %d with %d test files from the queue", batchNumber, len(batch.TestFilePaths))
+
+		substitutionValueLookup, err := substitution.SubstitutionLookupFor(compiledTemplate, batch.TestFilePaths)
+		if err != nil {
+			return nil, nil, runErr, errors.WithStack(err)
+		}
+
+		args, err := commandArgs(compiledTemplate.Substitute(substitutionValueLookup), cfg.Args)
+		if err != nil {
+			_ = substitution.CleanUp(substitutionValueLookup)
+			return nil, nil, runErr, err
+		}
+
+		ias.SetCommandID(batchNumber)
+		log := s.openCommandLog(cfg, ias, "command")
+		commandStdout, commandStderr := log.tee(stdout, cfg.commandStderr())
+
+		startedAt := time.Now()
+		_, cmdErr := s.runCommand(ctx, args, commandOptions{
+			stdout:      commandStdout,
+			stderr:      commandStderr,
+			env:         []string{},
+			timeout:     cfg.AttemptTimeout,
+			gracePeriod: cfg.TerminationGracePeriod,
+		})
+		if err := substitution.CleanUp(substitutionValueLookup); err != nil {
+			s.Log.Warn(err)
+		}
+		s.closeCommandLog(log)
+
+		batchCfg := cfg
+		batchCfg.TestResultsFileGlob = expandRetryCommandID(cfg.TestResultsFileGlob, os.Getenv(RetryCommandIDEnvVar))
+
+		testResults, testResultsFiles, batchEmptyTestResultsFiles, batchRunErr, err := s.handleCommandOutcome(
+			batchCfg,
+			cmdErr,
+			0,
+			startedAt,
+		)
+		if err != nil {
+			return nil, nil, runErr, err
+		}
+		emptyTestResultsFiles = append(emptyTestResultsFiles, batchEmptyTestResultsFiles...)
+		if runErr == nil {
+			runErr = batchRunErr
+		}
+
+		if otherError, ok := terminationOtherError(cmdErr); ok && cfg.TestResultsFileGlob != "" {
+			if testResults == nil {
+				testResults = v1.NewTestResults(v1.NewOtherFramework(nil, nil), []v1.Test{}, []v1.OtherError{})
+			}
+
+			testResults.OtherErrors = append(testResults.OtherErrors, otherError)
+			testResults.Summary = v1.NewSummary(testResults.Tests, testResults.OtherErrors)
+		}
+
+		recordCommandLog(testResults, log)
+
+		if testResults != nil {
+			if shouldPreserveAttachments() {
+				scope := filepath.Join(originalAttemptID, fmt.Sprintf("batch-%d", batchNumber))
+				if err := s.preserveAttachments(testResults, scope); err != nil {
+					return nil, nil, runErr, errors.WithStack(err)
+				}
+			}
+
+			// The next batch writes its test results and artifacts to the same paths
+			if err := ias.moveTestResults(testResultsFiles); err != nil {
+				return nil, nil, runErr, errors.WithStack(err)
+			}
+			if err := ias.MoveAdditionalArtifacts(cfg.AdditionalArtifactPaths); err != nil {
+				return nil, nil, runErr, errors.WithStack(err)
+			}
+
+			batchTestResults = append(batchTestResults, *testResults)
+		}
+
+		// Timeouts and interrupts stop the whole run, not only the current batch
+		if isTerminationError(cmdErr) {
+			runErr = cmdErr
+			break
+		}
+	}
+
+	if len(batchTestResults) == 0 {
+		return nil, emptyTestResultsFiles, runErr, nil
+	}
+
+	testResults := v1.Merge(batchTestResults)
+	if len(queueErrors) > 0 {
+		testResults.OtherErrors = append(testResults.OtherErrors, queueErrors...)
+		testResults.Summary = v1.NewSummary(testResults.Tests, testResults.OtherErrors)
+	}
+
+	return &testResults, emptyTestResultsFiles, runErr, nil
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rwx-research/captain-cli/internal/backend"
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/targetedretries"
+	"github.com/rwx-research/captain-cli/internal/templating"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// attemptRepeats runs every test that was executed by the original command again, until each of them ran
+// `cfg.Repeat` times in total. The repeats use the retry command template, and all of their attempts are merged into
+// the test results as past attempts.
+func (s Service) attemptRepeats(
+	ctx context.Context,
+	originalTestResults *v1.TestResults,
+	newlyExecutedTestResults *v1.TestResults,
+	cfg RunConfig,
+	startingRetryID int,
+) (*v1.TestResults, *v1.TestResults, int, error) {
+	if cfg.Repeat <= 1 {
+		return originalTestResults, newlyExecutedTestResults, startingRetryID, nil
+	}
+
+	if originalTestResults == nil {
+		return originalTestResults, newlyExecutedTestResults,
+			startingRetryID, errors.NewInternalError("No test results detected")
+	}
+
+	ias, err := s.NewIntermediateArtifactStorage(cfg.IntermediateArtifactsPath)
+	if err != nil {
+		return originalTestResults, newlyExecutedTestResults, startingRetryID, errors.WithStack(err)
+	}
+
+	if cfg.IntermediateArtifactsPath == "" {
+		defer func() {
+			if err := ias.delete(); err != nil {
+				s.Log.Warnf("Unable to clean up temporary files: %s", err.Error())
+			}
+		}()
+	}
+
+	compiledRetryTemplate, err := templating.CompileTemplate(cfg.RetryCommandTemplate)
+	if err != nil {
+		return originalTestResults, newlyExecutedTestResults, startingRetryID, errors.WithStack(err)
+	}
+
+	substitution, err := s.retrySubstitution(
+		cfg.SubstitutionsByFramework,
+		originalTestResults.Framework,
+		compiledRetryTemplate,
+	)
+	if err != nil {
+		return originalTestResults, newlyExecutedTestResults, startingRetryID, err
+	}
+
+	// Tests that were skipped by the original command are not repeated, as they wouldn't run either way
+	filter := func(test v1.Test) bool {
+		return !test.Attempt.Status.ImpliesSkipped()
+	}
+
+	flattenedTestResults := originalTestResults
+	flattenedNewlyExecutedTestResults := newlyExecutedTestResults
+	formattedRepeatTotal := fmt.Sprintf(" of %v", cfg.Repeat-1)
+
+	retryID := startingRetryID
+
+	for repeats := 1; repeats < cfg.Repeat; repeats++ {
+		if ctx.Err() != nil {
+			s.Log.Warnf("%s. Skipping any remaining repeats.", context.Cause(ctx))
+			break
+		}
+
+		retryID++
+		ias.SetRetryID(retryID)
+
+		allSubstitutions, err := s.retrySubstitutionsFor(
+			substitution,
+			compiledRetryTemplate,
+			*originalTestResults,
+			filter,
+			cfg.RetryBatchSize,
+		)
+		if err != nil {
+			return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, errors.Wrap(
+				err,
+				"Unable construct repeat substitutions",
+			)
+		}
+
+		if cfg.dryRunPlan != nil {
+			err := cfg.dryRunPlan.addRetryCommands("repeat", compiledRetryTemplate, allSubstitutions)
+			return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, err
+		}
+
+		allNewTestResults, terminationErr, err := s.runRetryCommands(
+			ctx,
+			cfg,
+			compiledRetryTemplate,
+			allSubstitutions,
+			ias,
+			retryRound{label: "Repeat", number: repeats, total: formattedRepeatTotal, retryID: retryID},
+		)
+		if err != nil {
+			return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, err
+		}
+		if cleanableSubstitution, ok := substitution.(targetedretries.CleanableSubstitution); ok {
+			if err := cleanableSubstitution.CleanUp(allSubstitutions); err != nil {
+				s.Log.Warn(err)
+			}
+		}
+
+		unfinishedTests, err := s.unfinishedRetriedTests(
+			cfg,
+			*originalTestResults,
+			filter,
+			allNewTestResults,
+			terminationErr,
+		)
+		if err != nil {
+			return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, err
+		}
+
+		if len(unfinishedTests) > 0 {
+			allNewTestResults = append(
+				allNewTestResults,
+				*v1.NewTestResults(flattenedTestResults.Framework, unfinishedTests, nil),
+			)
+		}
+
+		mergedTestResults := v1.Merge([]v1.TestResults{*flattenedTestResults}, allNewTestResults)
+		flattenedTestResults = &mergedTestResults
+
+		mergedNewlyExecutedTestResults := v1.Merge([]v1.TestResults{*flattenedNewlyExecutedTestResults}, allNewTestResults)
+		flattenedNewlyExecutedTestResults = &mergedNewlyExecutedTestResults
+	}
+
+	s.Log.Debugf("Repeats complete, summary: %v\n", flattenedTestResults.Summary)
+	return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, nil
+}
+
+// unstableTests returns the tests that failed in at least one of their attempts and aren't quarantined
+func (s Service) unstableTests(testResults *v1.TestResults, quarantinedTests []backend.Test) []v1.Test {
+	unstable := make([]v1.Test, 0)
+	if testResults == nil {
+		return unstable
+	}
+
+	for _, test := range testResults.Tests {
+		if s.isIdentifiedIn(test, quarantinedTests) {
+			continue
+		}
+
+		attempts := append([]v1.TestAttempt{test.Attempt}, test.PastAttempts...)
+		for _, attempt := range attempts {
+			if attempt.Status.ImpliesFailure() {
+				unstable = append(unstable, test)
+				break
+			}
+		}
+	}
+
+	return unstable
+}
@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rwx-research/captain-cli/internal/backend/local"
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/targetedretries"
+	"github.com/rwx-research/captain-cli/internal/templating"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// Rerun runs the tests that failed in the most recent test results stored under '.captain' again, using the retry
+// command template. The outcome is merged into the stored test results, so that consecutive reruns only run the tests
+// that are still failing.
+func (s Service) Rerun(ctx context.Context, cfg RerunConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	client, ok := s.API.(local.Client)
+	if !ok {
+		return errors.NewConfigurationError(
+			"Rerunning requires local storage",
+			"Captain reruns the failed tests of the test results stored under '.captain', but Cloud mode is enabled.",
+			"Please disable Cloud mode by setting 'cloud.disabled' in the Captain configuration file or by removing "+
+				"RWX_ACCESS_TOKEN from your environment.",
+		)
+	}
+
+	storedTestResults, err := client.GetTestResults(ctx, cfg.SuiteID)
+	if errors.Is(err, os.ErrNotExist) {
+		return errors.NewInputError(
+			"There are no stored test results for suite %q. Run 'captain run %v --update-stored-results' first.",
+			cfg.SuiteID,
+			cfg.SuiteID,
+		)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	filter := func(test v1.Test) bool {
+		return test.Attempt.Status.ImpliesFailure()
+	}
+
+	failedTests := 0
+	for _, test := range storedTestResults.Tests {
+		if filter(test) {
+			failedTests++
+		}
+	}
+
+	if failedTests == 0 {
+		s.Log.Infof("All tests of suite %q passed in the most recent run, there is nothing to rerun.", cfg.SuiteID)
+		return nil
+	}
+
+	runConfig := cfg.runConfig()
+
+	compiledRetryTemplate, err := templating.CompileTemplate(cfg.RetryCommandTemplate)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	substitution, err := s.retrySubstitution(
+		cfg.SubstitutionsByFramework,
+		storedTestResults.Framework,
+		compiledRetryTemplate,
+	)
+	if err != nil {
+		return err
+	}
+
+	ias, err := s.NewIntermediateArtifactStorage("")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		if err := ias.delete(); err != nil {
+			s.Log.Warnf("Unable to clean up temporary files: %s", err.Error())
+		}
+	}()
+
+	retryID := 1
+	ias.SetRetryID(retryID)
+
+	allSubstitutions, err := s.retrySubstitutionsFor(
+		substitution,
+		compiledRetryTemplate,
+		*storedTestResults,
+		filter,
+		cfg.RetryBatchSize,
+	)
+	if err != nil {
+		return errors.Wrap(err, "Unable construct rerun substitutions")
+	}
+
+	// Commands are subject to interrupts, which would otherwise not reach them as they run in their own process group
+	interruptibleCtx, stopInterrupts := s.withInterrupts(ctx)
+	defer stopInterrupts()
+
+	allNewTestResults, terminationErr, err := s.runRetryCommands(
+		interruptibleCtx,
+		runConfig,
+		compiledRetryTemplate,
+		allSubstitutions,
+		ias,
+		retryRound{label: "Rerun", number: 1, retryID: retryID},
+	)
+	if err != nil {
+		return err
+	}
+	if cleanableSubstitution, ok := substitution.(targetedretries.CleanableSubstitution); ok {
+		if err := cleanableSubstitution.CleanUp(allSubstitutions); err != nil {
+			s.Log.Warn(err)
+		}
+	}
+
+	unfinishedTests, err := s.unfinishedRetriedTests(
+		runConfig,
+		*storedTestResults,
+		filter,
+		allNewTestResults,
+		terminationErr,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(unfinishedTests) > 0 {
+		allNewTestResults = append(
+			allNewTestResults,
+			*v1.NewTestResults(storedTestResults.Framework, unfinishedTests, nil),
+		)
+	}
+
+	mergedTestResults := v1.Merge([]v1.TestResults{*storedTestResults}, allNewTestResults)
+	// The merged test results are mostly the stored ones, so their timings were already recorded
+	if _, err := client.StoreTestResults(ctx, cfg.SuiteID, mergedTestResults); err != nil {
+		return errors.Wrap(err, "unable to update the stored test results")
+	}
+
+	stillFailing := make([]string, 0)
+	for _, test := range mergedTestResults.Tests {
+		if filter(test) {
+			stillFailing = append(stillFailing, test.Name)
+		}
+	}
+
+	var report strings.Builder
+	report.WriteString(fmt.Sprintf(
+		"\n%v of %v previously failed %v passed when rerunning them",
+		failedTests-len(stillFailing),
+		failedTests,
+		pluralize(failedTests, "test", "tests"),
+	))
+	if len(stillFailing) > 0 {
+		report.WriteString(", these still fail:")
+	}
+	for _, name := range stillFailing {
+		report.WriteString(fmt.Sprintf("\n- %v", name))
+	}
+	s.Log.Infoln(report.String())
+
+	if terminationErr != nil {
+		return errors.WithStack(terminationErr)
+	}
+
+	if len(stillFailing) > 0 {
+		return errors.NewExecutionError(
+			1,
+			"%v %v still failed",
+			len(stillFailing),
+			pluralize(len(stillFailing), "test", "tests"),
+		)
+	}
+
+	return nil
+}
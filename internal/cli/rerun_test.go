@@ -0,0 +1,261 @@
+package cli_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/rwx-research/captain-cli/internal/backend/local"
+	"github.com/rwx-research/captain-cli/internal/cli"
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/exec"
+	"github.com/rwx-research/captain-cli/internal/fs"
+	"github.com/rwx-research/captain-cli/internal/mocks"
+	"github.com/rwx-research/captain-cli/internal/parsing"
+	"github.com/rwx-research/captain-cli/internal/targetedretries"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rerun", func() {
+	const resultsPath = ".captain/test/results.json"
+
+	var (
+		err             error
+		service         cli.Service
+		recordedLogs    *observer.ObservedLogs
+		rerunConfig     cli.RerunConfig
+		storedResults   *v1.TestResults
+		updatedResults  *mocks.File
+		commands        [][]string
+		passingOnRerun  []string
+		noStoredResults bool
+	)
+
+	newTest := func(id string, status v1.TestStatus) v1.Test {
+		return v1.Test{ID: &id, Name: id, Attempt: v1.TestAttempt{Status: status}}
+	}
+
+	storedTestResult := func() v1.TestResults {
+		testResults := v1.TestResults{}
+		Expect(json.Unmarshal([]byte(updatedResults.String()), &testResults)).To(Succeed())
+		return testResults
+	}
+
+	BeforeEach(func() {
+		err = nil
+		commands = make([][]string, 0)
+		passingOnRerun = []string{"a", "b"}
+		noStoredResults = false
+		updatedResults = &mocks.File{Builder: new(strings.Builder)}
+		storedResults = v1.NewTestResults(v1.RubyRSpecFramework, []v1.Test{
+			newTest("a", v1.NewFailedTestStatus(nil, nil, nil)),
+			newTest("b", v1.NewSuccessfulTestStatus()),
+			newTest("c", v1.NewFailedTestStatus(nil, nil, nil)),
+		}, nil)
+
+		var core zapcore.Core
+		core, recordedLogs = observer.New(zapcore.InfoLevel)
+		log := zaptest.NewLogger(GinkgoT(), zaptest.WrapOptions(
+			zap.WrapCore(func(_ zapcore.Core) zapcore.Core { return core }),
+		)).Sugar()
+
+		mockFileSystem := new(mocks.FileSystem)
+		mockFileSystem.MockOpen = func(name string) (fs.File, error) {
+			file := new(mocks.File)
+			switch name {
+			case resultsPath:
+				if noStoredResults {
+					return nil, os.ErrNotExist
+				}
+				encoded, err := json.Marshal(storedResults)
+				Expect(err).NotTo(HaveOccurred())
+				file.Reader = strings.NewReader(string(encoded))
+			default:
+				file.Reader = strings.NewReader("")
+			}
+			return file, nil
+		}
+		mockFileSystem.MockOpenFile = func(_ string, _ int, _ os.FileMode) (fs.File, error) {
+			return &mocks.File{Builder: new(strings.Builder)}, nil
+		}
+		mockFileSystem.MockCreate = func(name string) (fs.File, error) {
+			Expect(name).To(Equal(resultsPath))
+			return updatedResults, nil
+		}
+		mockFileSystem.MockGlob = func(pattern string) ([]string, error) {
+			return []string{pattern}, nil
+		}
+		mockFileSystem.MockGetwd = func() (string, error) {
+			return "/", nil
+		}
+		mockFileSystem.MockMkdirTemp = func(_, _ string) (string, error) {
+			return "/tmp/captain", nil
+		}
+		mockFileSystem.MockMkdirAll = func(_ string, _ os.FileMode) error {
+			return nil
+		}
+		mockFileSystem.MockRename = func(_, _ string) error {
+			return nil
+		}
+		mockFileSystem.MockRemoveAll = func(_ string) error {
+			return nil
+		}
+
+		mockTaskRunner := new(mocks.TaskRunner)
+		mockTaskRunner.MockNewCommand = func(_ context.Context, cfg exec.CommandConfig) (exec.Command, error) {
+			commands = append(commands, append([]string{cfg.Name}, cfg.Args...))
+			return &mocks.Command{
+				MockStart: func() error { return nil },
+				MockWait:  func() error { return nil },
+			}, nil
+		}
+		mockTaskRunner.MockNotifyInterrupts = func(_ chan<- os.Signal) (func(), error) {
+			return func() {}, nil
+		}
+
+		mockParser := new(mocks.Parser)
+		mockParser.MockParse = func(_ io.Reader) (*v1.TestResults, error) {
+			tests := make([]v1.Test, 0)
+			for _, arg := range commands[len(commands)-1][1:] {
+				status := v1.NewFailedTestStatus(nil, nil, nil)
+				for _, id := range passingOnRerun {
+					if arg == id {
+						status = v1.NewSuccessfulTestStatus()
+					}
+				}
+				tests = append(tests, newTest(arg, status))
+			}
+
+			return &v1.TestResults{Framework: v1.RubyRSpecFramework, Tests: tests}, nil
+		}
+
+		api, err := local.NewClient(
+			mockFileSystem,
+			".captain/test/flakes.yaml",
+			".captain/test/quarantines.yaml",
+			".captain/test/timings.yaml",
+			resultsPath,
+			"",
+			"",
+			local.TimingHistory{},
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		service = cli.Service{
+			API:        api,
+			Log:        log,
+			FileSystem: mockFileSystem,
+			TaskRunner: mockTaskRunner,
+			ParseConfig: parsing.Config{
+				MutuallyExclusiveParsers: []parsing.Parser{mockParser},
+				Logger:                   log,
+			},
+		}
+
+		rerunConfig = cli.RerunConfig{
+			Quiet:                true,
+			RetryCommandTemplate: "rspec {{ tests }}",
+			SubstitutionsByFramework: map[v1.Framework]targetedretries.Substitution{
+				v1.RubyRSpecFramework: new(targetedretries.RubyRSpecSubstitution),
+			},
+			SuiteID:             "test",
+			TestResultsFileGlob: "tmp/rspec.json",
+		}
+	})
+
+	JustBeforeEach(func() {
+		err = service.Rerun(context.Background(), rerunConfig)
+	})
+
+	logMessages := func() []string {
+		messages := make([]string, 0)
+		for _, log := range recordedLogs.All() {
+			messages = append(messages, log.Message)
+		}
+		return messages
+	}
+
+	It("only reruns the failed tests", func() {
+		Expect(commands).To(Equal([][]string{{"rspec", "a", "c"}}))
+	})
+
+	It("updates the stored test results", func() {
+		testResults := storedTestResult()
+		Expect(testResults.Tests).To(HaveLen(3))
+		Expect(testResults.Tests[0].Attempt.Status.Kind).To(Equal(v1.TestStatusSuccessful))
+		Expect(testResults.Tests[0].PastAttempts).To(HaveLen(1))
+		Expect(testResults.Tests[1].PastAttempts).To(BeEmpty())
+		Expect(testResults.Tests[2].Attempt.Status.Kind).To(Equal(v1.TestStatusFailed))
+		Expect(testResults.Tests[2].PastAttempts).To(HaveLen(1))
+	})
+
+	It("fails with the tests that are still failing", func() {
+		executionErr, ok := errors.AsExecutionError(err)
+		Expect(ok).To(BeTrue())
+		Expect(executionErr.Code).To(Equal(1))
+		Expect(logMessages()).To(ContainElement(And(
+			ContainSubstring("1 of 2 previously failed tests passed when rerunning them, these still fail:"),
+			ContainSubstring("\n- c"),
+		)))
+	})
+
+	Context("when all rerun tests pass", func() {
+		BeforeEach(func() {
+			passingOnRerun = []string{"a", "c"}
+		})
+
+		It("succeeds", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logMessages()).To(ContainElement(
+				ContainSubstring("2 of 2 previously failed tests passed when rerunning them"),
+			))
+		})
+	})
+
+	Context("when no tests failed", func() {
+		BeforeEach(func() {
+			storedResults = v1.NewTestResults(v1.RubyRSpecFramework, []v1.Test{
+				newTest("a", v1.NewSuccessfulTestStatus()),
+			}, nil)
+		})
+
+		It("doesn't run anything", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(commands).To(BeEmpty())
+		})
+	})
+
+	Context("when there are no stored test results", func() {
+		BeforeEach(func() {
+			noStoredResults = true
+		})
+
+		It("errs", func() {
+			_, ok := errors.AsInputError(err)
+			Expect(ok).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("captain run test --update-stored-results"))
+		})
+	})
+
+	Context("when there is no retry command", func() {
+		BeforeEach(func() {
+			rerunConfig.RetryCommandTemplate = ""
+		})
+
+		It("errs", func() {
+			_, ok := errors.AsConfigurationError(err)
+			Expect(ok).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("Missing retry command"))
+		})
+	})
+})
@@ -0,0 +1,496 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-shellwords"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/targetedretries"
+	"github.com/rwx-research/captain-cli/internal/templating"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// RetryCommandIDEnvVar is the environment variable that identifies a single retry command. The test results path may
+// reference it, so that retry commands running concurrently don't overwrite each other's test results.
+const RetryCommandIDEnvVar = "CAPTAIN_RETRY_COMMAND_ID"
+
+// retryRound identifies a single retry of the test suite, which may consist of several retry commands
+type retryRound struct {
+	// label is how the round is referred to in the output, e.g. "Retry"
+	label   string
+	number  int
+	total   string
+	retryID int
+}
+
+// env returns the environment variables that every command of the round receives
+func (r retryRound) env(cfg RunConfig) []string {
+	env := []string{fmt.Sprintf("CAPTAIN_RETRY_ATTEMPT_NUMBER=%v", r.number)}
+
+	// Retries run after all parallel workers finished, so they take the place of the first worker
+	if cfg.Parallel > 1 {
+		env = append(env, fmt.Sprintf("%s=0", WorkerIndexEnvVar))
+	}
+
+	return env
+}
+
+// retryCommand is a single invocation of the retry command template
+type retryCommand struct {
+	round retryRound
+	// index is the 0-based position of the command within its round
+	index         int
+	total         int
+	substitutions map[string]string
+	ias           *IntermediateArtifactStorage
+
+	// concurrent is set when other retry commands may be running at the same time. Concurrent commands share the
+	// pre- and post-retry commands as well as the additional artifacts of their round, see `runRetryCommands`.
+	concurrent bool
+}
+
+func (rc retryCommand) id() string {
+	return fmt.Sprintf("%v-%v", rc.round.number, rc.index+1)
+}
+
+// retryCommandOutcome holds everything about a retry command that is merged once all commands of a retry finished
+type retryCommandOutcome struct {
+	testResults *v1.TestResults
+	// terminationErr is set when Captain stopped the command before it finished
+	terminationErr error
+}
+
+// retrySubstitution returns the substitution that is able to fill in the retry command template. The JSON substitution
+// is preferred, falling back to the substitution of the test framework, either on the command line or through a file.
+func (s Service) retrySubstitution(
+	substitutionsByFramework map[v1.Framework]targetedretries.Substitution,
+	framework v1.Framework,
+	compiledRetryTemplate templating.CompiledTemplate,
+) (targetedretries.Substitution, error) {
+	var substitution targetedretries.Substitution = targetedretries.JSONSubstitution{FileSystem: s.FileSystem}
+	if err := substitution.ValidateTemplate(compiledRetryTemplate); err == nil {
+		return substitution, nil
+	}
+
+	frameworkSubstitution, ok := substitutionsByFramework[framework]
+	if !ok {
+		return nil, errors.NewInternalError("Unable to retry %q", framework)
+	}
+
+	if slices.Contains(compiledRetryTemplate.Keywords(), "testsFile") {
+		frameworkSubstitution = targetedretries.TestsFileSubstitution{
+			FileSystem:   s.FileSystem,
+			Substitution: frameworkSubstitution,
+		}
+	}
+
+	if err := frameworkSubstitution.ValidateTemplate(compiledRetryTemplate); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return frameworkSubstitution, nil
+}
+
+// retrySubstitutionsFor determines the substitutions of every retry command that retries the tests matching the
+// filter. With a batch size, the tests are split into batches of at most that many tests first, so that each retry
+// command retries a single batch at most.
+func (s Service) retrySubstitutionsFor(
+	substitution targetedretries.Substitution,
+	compiledRetryTemplate templating.CompiledTemplate,
+	testResults v1.TestResults,
+	filter func(v1.Test) bool,
+	batchSize int,
+) ([]map[string]string, error) {
+	if batchSize <= 0 {
+		substitutions, err := substitution.SubstitutionsFor(compiledRetryTemplate, testResults, filter)
+		return substitutions, errors.WithStack(err)
+	}
+
+	testsToRetry := make([]v1.Test, 0)
+	for _, test := range testResults.Tests {
+		if filter(test) {
+			testsToRetry = append(testsToRetry, test)
+		}
+	}
+
+	allSubstitutions := make([]map[string]string, 0)
+	for batch := range slices.Chunk(testsToRetry, batchSize) {
+		batchTestResults := testResults
+		batchTestResults.Tests = batch
+
+		substitutions, err := substitution.SubstitutionsFor(compiledRetryTemplate, batchTestResults, filter)
+		if err != nil {
+			if cleanableSubstitution, ok := substitution.(targetedretries.CleanableSubstitution); ok {
+				if err := cleanableSubstitution.CleanUp(allSubstitutions); err != nil {
+					s.Log.Warn(err)
+				}
+			}
+			return nil, errors.WithStack(err)
+		}
+
+		allSubstitutions = append(allSubstitutions, substitutions...)
+	}
+
+	return allSubstitutions, nil
+}
+
+// runRetryCommands runs one retry command per substitution, at most `cfg.RetryConcurrency` of them at the same time.
+// The test results of all commands are returned in the order of the substitutions, independent of when the commands
+// finished. If Captain stopped any of the commands before they finished, the reason is returned as well.
+func (s Service) runRetryCommands(
+	ctx context.Context,
+	cfg RunConfig,
+	compiledRetryTemplate templating.CompiledTemplate,
+	allSubstitutions []map[string]string,
+	ias *IntermediateArtifactStorage,
+	round retryRound,
+) ([]v1.TestResults, error, error) {
+	outcomes := make([]retryCommandOutcome, len(allSubstitutions))
+	concurrent := cfg.RetryConcurrency > 1 && len(allSubstitutions) > 1
+
+	// Pre- and post-retry commands (e.g. resetting a database) run once per round when the retry commands run at the
+	// same time, as they would otherwise interfere with the retry commands that are still running
+	var preRetryLogs []*commandLog
+	if concurrent {
+		stdout, closeStdout := s.retryStdout(cfg)
+		defer closeStdout()
+
+		var err error
+		preRetryLogs, err = s.runRetryHooks(ctx, cfg, cfg.PreRetryCommands, "pre-retry", loggedCommandOptions{
+			ias:    ias,
+			env:    round.env(cfg),
+			stdout: stdout,
+			stderr: cfg.commandStderr(),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// The group's context only stops further commands from being started once one of them failed, running
+	// commands are never canceled through it.
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(max(cfg.RetryConcurrency, 1))
+	for i, substitutions := range allSubstitutions {
+		commandIAS := *ias
+		commandIAS.SetCommandID(i + 1)
+
+		rc := retryCommand{
+			round:         round,
+			index:         i,
+			total:         len(allSubstitutions),
+			substitutions: substitutions,
+			ias:           &commandIAS,
+			concurrent:    concurrent,
+		}
+
+		eg.Go(func() error {
+			if ctx.Err() != nil {
+				outcomes[i].terminationErr = context.Cause(ctx)
+				return nil
+			}
+
+			if egCtx.Err() != nil {
+				return nil
+			}
+
+			outcome, err := s.runRetryCommand(ctx, cfg, compiledRetryTemplate, rc)
+			outcomes[i] = outcome
+			return err
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	if concurrent {
+		stdout, closeStdout := s.retryStdout(cfg)
+		defer closeStdout()
+
+		postRetryLogs, err := s.runRetryHooks(ctx, cfg, cfg.PostRetryCommands, "post-retry", loggedCommandOptions{
+			ias:    ias,
+			env:    round.env(cfg),
+			stdout: stdout,
+			stderr: cfg.commandStderr(),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, outcome := range outcomes {
+			recordCommandLogPaths(outcome.testResults, preRetryLogPathsMetaKey, preRetryLogs)
+			recordCommandLogPaths(outcome.testResults, postRetryLogPathsMetaKey, postRetryLogs)
+		}
+
+		// All commands of the round write to the same additional artifact paths, so they can only be told apart by
+		// the round
+		if err := ias.MoveAdditionalArtifacts(cfg.AdditionalArtifactPaths); err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+	}
+
+	allNewTestResults := make([]v1.TestResults, 0)
+	var terminationErr error
+	for _, outcome := range outcomes {
+		if outcome.testResults != nil {
+			allNewTestResults = append(allNewTestResults, *outcome.testResults)
+		}
+		if terminationErr == nil && isTerminationError(outcome.terminationErr) {
+			terminationErr = outcome.terminationErr
+		}
+	}
+
+	return allNewTestResults, terminationErr, nil
+}
+
+// unfinishedRetriedTests checks that every retried test reported a result. Tests that didn't report one because
+// Captain stopped the retry command are returned as unfinished, any other missing test is a sign of a misconfigured
+// retry command.
+func (s Service) unfinishedRetriedTests(
+	cfg RunConfig,
+	testResults v1.TestResults,
+	filter func(v1.Test) bool,
+	allNewTestResults []v1.TestResults,
+	terminationErr error,
+) ([]v1.Test, error) {
+	unfinishedTests := make([]v1.Test, 0)
+
+TESTS:
+	for _, originalTest := range testResults.Tests {
+		if !filter(originalTest) {
+			continue
+		}
+
+		for _, retriedResult := range allNewTestResults {
+			for _, retriedTest := range retriedResult.Tests {
+				if originalTest.Matches(retriedTest) {
+					continue TESTS
+				}
+			}
+		}
+
+		if test, ok := unfinishedTest(originalTest, terminationErr); ok {
+			unfinishedTests = append(unfinishedTests, test)
+			continue
+		}
+
+		missingTestResult := fmt.Sprintf(
+			"The retry command of suite %q appears to be misconfigured. "+
+				"Captain could not identify the original (failed) test in the output of the retry command.",
+			cfg.SuiteID,
+		)
+		if cfg.FailOnMisconfiguredRetry {
+			return nil, errors.NewRetryError("%s", missingTestResult)
+		}
+		s.Log.Warn(missingTestResult)
+	}
+
+	return unfinishedTests, nil
+}
+
+// expandRetryCommandID resolves any references to the retry command ID in the test results path
+func expandRetryCommandID(testResultsFileGlob string, commandID string) string {
+	return strings.NewReplacer(
+		fmt.Sprintf("${%s}", RetryCommandIDEnvVar), commandID,
+		fmt.Sprintf("$%s", RetryCommandIDEnvVar), commandID,
+	).Replace(testResultsFileGlob)
+}
+
+// runRetryCommand runs a single retry command including its pre- and post-retry commands, parses its test results
+// and moves them into the command's own intermediate artifacts scope.
+func (s Service) runRetryCommand(
+	ctx context.Context,
+	cfg RunConfig,
+	compiledRetryTemplate templating.CompiledTemplate,
+	rc retryCommand,
+) (retryCommandOutcome, error) {
+	command := compiledRetryTemplate.Substitute(rc.substitutions)
+	args, err := shellwords.Parse(command)
+	if err != nil {
+		return retryCommandOutcome{}, errors.Wrapf(err, "Unable to parse %q into shell arguments", command)
+	}
+
+	env := append(
+		rc.round.env(cfg),
+		fmt.Sprintf("CAPTAIN_RETRY_INVOCATION_NUMBER=%v", rc.index+1),
+		fmt.Sprintf("%s=%s", RetryCommandIDEnvVar, rc.id()),
+	)
+
+	s.Log.Infoln()
+	s.Log.Infoln(strings.Repeat("-", 80))
+	if rc.total == 1 {
+		s.Log.Infoln(fmt.Sprintf("- %v %v%v", rc.round.label, rc.round.number, rc.round.total))
+	} else {
+		s.Log.Infoln(fmt.Sprintf(
+			"- %v %v%v, command %v of %v",
+			rc.round.label,
+			rc.round.number,
+			rc.round.total,
+			rc.index+1,
+			rc.total,
+		))
+	}
+	for keyword, value := range rc.substitutions {
+		s.Log.Infoln(fmt.Sprintf("-   %v: %v", keyword, value))
+	}
+	s.Log.Infoln(strings.Repeat("-", 80))
+	s.Log.Infoln()
+
+	stdout, closeStdout := s.retryStdout(cfg)
+	defer closeStdout()
+	stderr := cfg.commandStderr()
+
+	// Concurrent commands share the terminal, so every line is prefixed with the command it originates from
+	if rc.concurrent {
+		prefix := fmt.Sprintf("[%v %v, command %v] ", strings.ToLower(rc.round.label), rc.round.number, rc.index+1)
+		prefixedStdout := newPrefixWriter(stdout, prefix)
+		defer prefixedStdout.Flush()
+		prefixedStderr := newPrefixWriter(stderr, prefix)
+		defer prefixedStderr.Flush()
+
+		stdout, stderr = prefixedStdout, prefixedStderr
+	}
+
+	loggedOptions := loggedCommandOptions{ias: rc.ias, env: env, stdout: stdout, stderr: stderr}
+
+	var preRetryLogs []*commandLog
+	if !rc.concurrent {
+		preRetryLogs, err = s.runRetryHooks(ctx, cfg, cfg.PreRetryCommands, "pre-retry", loggedOptions)
+		if err != nil {
+			return retryCommandOutcome{}, err
+		}
+	}
+
+	var outcome retryCommandOutcome
+
+	startedAt := time.Now()
+	log, cmdErr := s.runLoggedRetryCommand(ctx, cfg, args, "command", cfg.AttemptTimeout, loggedOptions)
+	if isTerminationError(cmdErr) {
+		outcome.terminationErr = cmdErr
+	}
+
+	var postRetryLogs []*commandLog
+	if !rc.concurrent {
+		postRetryLogs, err = s.runRetryHooks(ctx, cfg, cfg.PostRetryCommands, "post-retry", loggedOptions)
+		if err != nil {
+			return outcome, err
+		}
+	}
+
+	commandCfg := cfg
+	commandCfg.TestResultsFileGlob = expandRetryCommandID(expandWorkerIndex(cfg.TestResultsFileGlob, "0"), rc.id())
+
+	newTestResults, newTestResultsFiles, _, _, err := s.handleCommandOutcome(
+		commandCfg,
+		cmdErr,
+		rc.round.retryID,
+		startedAt,
+	)
+	if err != nil {
+		return outcome, err
+	}
+
+	recordCommandLog(newTestResults, log)
+	recordCommandLogPaths(newTestResults, preRetryLogPathsMetaKey, preRetryLogs)
+	recordCommandLogPaths(newTestResults, postRetryLogPathsMetaKey, postRetryLogs)
+
+	// Preserve this invocation's attachments before the next invocation overwrites them in place.
+	if shouldPreserveAttachments() {
+		if err := s.preserveAttachments(newTestResults, rc.ias.attemptScope()); err != nil {
+			return outcome, errors.WithStack(err)
+		}
+	}
+
+	outcome.testResults = newTestResults
+	if err := rc.ias.moveTestResults(newTestResultsFiles); err != nil {
+		return outcome, errors.WithStack(err)
+	}
+	// Concurrent commands would move each other's artifacts, they're moved once the whole round finished instead
+	if !rc.concurrent {
+		if err := rc.ias.MoveAdditionalArtifacts(cfg.AdditionalArtifactPaths); err != nil {
+			return outcome, errors.WithStack(err)
+		}
+	}
+
+	return outcome, nil
+}
+
+// loggedCommandOptions configures where the commands of a retry write their output and logs to
+type loggedCommandOptions struct {
+	ias    *IntermediateArtifactStorage
+	env    []string
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// runRetryHooks runs the given pre- or post-retry commands one after another, stopping at the first one that fails
+func (s Service) runRetryHooks(
+	ctx context.Context,
+	cfg RunConfig,
+	commands []string,
+	kind string,
+	opts loggedCommandOptions,
+) ([]*commandLog, error) {
+	logs := make([]*commandLog, 0, len(commands))
+	for i, command := range commands {
+		args, err := shellwords.Parse(command)
+		if err != nil {
+			return logs, errors.Wrapf(err, "Unable to parse %q into shell arguments", command)
+		}
+
+		log, err := s.runLoggedRetryCommand(ctx, cfg, args, fmt.Sprintf("%s-%d", kind, i+1), 0, opts)
+		if err != nil {
+			return logs, errors.Wrapf(err, "Error while executing %q", command)
+		}
+		logs = append(logs, log)
+	}
+
+	return logs, nil
+}
+
+// runLoggedRetryCommand runs a command of a retry. Every command's output is additionally teed into its own log file,
+// so that it can be told apart later on.
+func (s Service) runLoggedRetryCommand(
+	ctx context.Context,
+	cfg RunConfig,
+	args []string,
+	logName string,
+	timeout time.Duration,
+	opts loggedCommandOptions,
+) (*commandLog, error) {
+	log := s.openCommandLog(cfg, opts.ias, logName)
+	defer s.closeCommandLog(log)
+
+	commandStdout, commandStderr := log.tee(opts.stdout, opts.stderr)
+	_, err := s.runCommand(ctx, args, commandOptions{
+		stdout:      commandStdout,
+		stderr:      commandStderr,
+		env:         opts.env,
+		timeout:     timeout,
+		gracePeriod: cfg.TerminationGracePeriod,
+	})
+	return log, err
+}
+
+// retryStdout returns where retry commands write their output to. The returned function releases it again.
+func (s Service) retryStdout(cfg RunConfig) (io.Writer, func()) {
+	if !cfg.Quiet {
+		return cfg.commandStdout(), func() {}
+	}
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_APPEND|os.O_WRONLY, 0o666)
+	if err != nil {
+		s.Log.Warnf("Could not open %s for writing", os.DevNull)
+		return cfg.commandStdout(), func() {}
+	}
+
+	return devNull, func() { devNull.Close() }
+}
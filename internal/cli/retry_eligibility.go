@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// retryEligibilityMetaKey is the key under which the retry eligibility of a test is recorded in its attempt's meta
+const retryEligibilityMetaKey = "captain_retry_eligibility"
+
+// retryMessageRules decide whether a failed test may be retried based on its failure message, exception and backtrace
+type retryMessageRules struct {
+	onlyIfMatches  []*regexp.Regexp
+	neverIfMatches []*regexp.Regexp
+}
+
+// retryEligibility is the outcome of applying the retry message rules to a single test
+type retryEligibility struct {
+	eligible bool
+	reason   string
+}
+
+func newRetryMessageRules(onlyIfMatches []string, neverIfMatches []string) (retryMessageRules, error) {
+	var rules retryMessageRules
+
+	compile := func(option string, patterns []string) ([]*regexp.Regexp, error) {
+		compiled := make([]*regexp.Regexp, 0, len(patterns))
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, errors.NewConfigurationError(
+					fmt.Sprintf("Invalid retries.%s pattern", option),
+					fmt.Sprintf("Captain is unable to parse the regular expression %q: %s", pattern, err.Error()),
+					"Patterns are expected to use Go's regular expression syntax, see https://pkg.go.dev/regexp/syntax.",
+				)
+			}
+			compiled = append(compiled, re)
+		}
+		return compiled, nil
+	}
+
+	var err error
+	if rules.onlyIfMatches, err = compile("only-if-message-matches", onlyIfMatches); err != nil {
+		return rules, err
+	}
+	if rules.neverIfMatches, err = compile("never-if-message-matches", neverIfMatches); err != nil {
+		return rules, err
+	}
+
+	return rules, nil
+}
+
+func (r retryMessageRules) configured() bool {
+	return len(r.onlyIfMatches) > 0 || len(r.neverIfMatches) > 0
+}
+
+// evaluate checks a failed test against the rules. Patterns that rule out a retry take precedence over the ones that
+// allow it.
+func (r retryMessageRules) evaluate(test v1.Test) retryEligibility {
+	return r.evaluateStatus(test.Attempt.Status)
+}
+
+func (r retryMessageRules) evaluateStatus(status v1.TestStatus) retryEligibility {
+	if !r.configured() {
+		return retryEligibility{eligible: true}
+	}
+
+	parts := make([]string, 0, 2+len(status.Backtrace))
+	if status.Message != nil {
+		parts = append(parts, *status.Message)
+	}
+	if status.Exception != nil {
+		parts = append(parts, *status.Exception)
+	}
+	parts = append(parts, status.Backtrace...)
+	failure := strings.Join(parts, "\n")
+
+	for _, re := range r.neverIfMatches {
+		if re.MatchString(failure) {
+			return retryEligibility{
+				eligible: false,
+				reason:   fmt.Sprintf("The failure matches the never-if-message-matches pattern %q", re.String()),
+			}
+		}
+	}
+
+	if len(r.onlyIfMatches) == 0 {
+		return retryEligibility{eligible: true, reason: "The failure does not match any never-if-message-matches pattern"}
+	}
+
+	for _, re := range r.onlyIfMatches {
+		if re.MatchString(failure) {
+			return retryEligibility{
+				eligible: true,
+				reason:   fmt.Sprintf("The failure matches the only-if-message-matches pattern %q", re.String()),
+			}
+		}
+	}
+
+	return retryEligibility{
+		eligible: false,
+		reason:   "The failure does not match any only-if-message-matches pattern",
+	}
+}
+
+// recordRetryEligibility stores the outcome of the retry message rules on every failed attempt of the final test
+// results, including the ones that weren't retried at all
+func (r retryMessageRules) recordRetryEligibility(testResults *v1.TestResults) {
+	if testResults == nil || !r.configured() {
+		return
+	}
+
+	for i, test := range testResults.Tests {
+		testResults.Tests[i].Attempt = r.withRetryEligibility(test.Attempt)
+
+		if len(test.PastAttempts) == 0 {
+			continue
+		}
+
+		// Past attempts may be shared with other copies of the same test, so they must not be modified in place
+		pastAttempts := make([]v1.TestAttempt, len(test.PastAttempts))
+		for j, pastAttempt := range test.PastAttempts {
+			pastAttempts[j] = r.withRetryEligibility(pastAttempt)
+		}
+		testResults.Tests[i].PastAttempts = pastAttempts
+	}
+}
+
+func (r retryMessageRules) withRetryEligibility(attempt v1.TestAttempt) v1.TestAttempt {
+	if !attempt.Status.ImpliesFailure() {
+		return attempt
+	}
+
+	eligibility := r.evaluateStatus(attempt.Status)
+
+	// The meta map may be shared with other copies of the same test, so it must not be modified in place
+	meta := maps.Clone(attempt.Meta)
+	if meta == nil {
+		meta = map[string]any{}
+	}
+	meta[retryEligibilityMetaKey] = map[string]any{
+		"eligible": eligibility.eligible,
+		"reason":   eligibility.reason,
+	}
+	attempt.Meta = meta
+
+	return attempt
+}
@@ -7,8 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/mattn/go-shellwords"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/rwx-research/captain-cli/internal/backend"
@@ -24,13 +24,18 @@ import (
 	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
 )
 
-// RunSuite runs the specified build- or test-suite and optionally uploads the resulting test results file.
+// RunSuite runs the specified build- or test-suite and optionally uploads the resulting test results file. When the
+// suite stops before running its tests, e.g. because its partition is empty, a ShortCircuitError is returned.
 func (s Service) RunSuite(ctx context.Context, cfg RunConfig) (finalErr error) {
 	err := cfg.Validate(s.Log)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
+	if cfg.DryRun {
+		return s.dryRun(ctx, cfg)
+	}
+
 	// Fetch run configuration in the background
 	var apiConfiguration backend.RunConfiguration
 	eg, egCtx := errgroup.WithContext(ctx)
@@ -49,22 +54,44 @@ func (s Service) RunSuite(ctx context.Context, cfg RunConfig) (finalErr error) {
 		return nil
 	})
 
-	stdout := os.Stdout
+	// Reporting the test results replaces the stored ones, so the number of tests of the previous run is read upfront
+	expectedTestCount, countErr := s.expectedTestCount(ctx, cfg)
+	if countErr != nil {
+		return errors.WithStack(countErr)
+	}
+
+	stdout := cfg.commandStdout()
 	if cfg.Quiet {
 		// According to the documentation, passing in a nil pointer to `os.Exec`
 		// should also work - however, that seems to open /dev/null with the
 		// wrong flags, leading to errors like
 		// `cat: standard output: Bad file descriptor`
-		stdout, err = os.OpenFile(os.DevNull, os.O_APPEND|os.O_WRONLY, 0o666)
+		devNull, err := os.OpenFile(os.DevNull, os.O_APPEND|os.O_WRONLY, 0o666)
 		if err != nil {
 			s.Log.Warnf("Could not open %s for writing", os.DevNull)
+		} else {
+			stdout = devNull
 		}
 	}
 
+	// Commands (but not API calls) are subject to the run timeout and to interrupts, so results can still be reported
+	// once either of them has stopped the test suite.
+	interruptibleCtx, stopInterrupts := s.withInterrupts(ctx)
+	defer stopInterrupts()
+
+	commandCtx, cancelCommands := withTimeout(
+		interruptibleCtx,
+		cfg.RunTimeout,
+		"The test suite exceeded the run timeout of %s",
+		cfg.RunTimeout,
+	)
+	defer cancelCommands()
+
 	var runErr error
 	var testResults *v1.TestResults
 	var newlyExecutedTestResults *v1.TestResults
 	var testResultsFiles []string
+	var emptyTestResultsFiles []string
 
 	startingRetryID := 0
 	lastRetryID := 0
@@ -143,7 +170,7 @@ func (s Service) RunSuite(ctx context.Context, cfg RunConfig) (finalErr error) {
 
 			newlyExecutedTestResults = v1.NewTestResults(testResults.Framework, []v1.Test{}, []v1.OtherError{})
 			testResults, newlyExecutedTestResults, lastRetryID, err = s.attemptRetries(
-				ctx,
+				commandCtx,
 				testResults,
 				newlyExecutedTestResults,
 				cfg,
@@ -158,28 +185,86 @@ func (s Service) RunSuite(ctx context.Context, cfg RunConfig) (finalErr error) {
 			}
 		}
 	} else {
-		runCommand, err := s.makeRunCommand(ctx, cfg)
-		if err != nil {
-			return errors.Wrapf(err, "Failed to assemble run command")
+		if cfg.DeleteStaleTestResults {
+			if err := s.deleteStaleTestResultsFiles(cfg); err != nil {
+				return err
+			}
 		}
 
-		// Short circuit and print warning info (e.g attempting to run an empty partition)
-		if runCommand.shortCircuit {
-			s.Log.Warnf(runCommand.shortCircuitInfo)
-			os.Exit(0)
-		}
+		var log *commandLog
+		if cfg.QueueURL != "" {
+			testResults, emptyTestResultsFiles, runErr, err = s.runQueuedBatches(commandCtx, cfg, stdout)
+			if err != nil {
+				return err
+			}
+		} else if cfg.Parallel > 1 {
+			testResults, emptyTestResultsFiles, runErr, err = s.runParallelWorkers(commandCtx, cfg, stdout)
+			if err != nil {
+				return err
+			}
+		} else {
+			runCommand, err := s.makeRunCommand(ctx, cfg)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to assemble run command")
+			}
 
-		// Run sub-command
-		ctx, cmdErr := s.runCommand(ctx, runCommand.commandArgs, stdout, []string{})
-		testResults, testResultsFiles, runErr, err = s.handleCommandOutcome(cfg, cmdErr, lastRetryID)
-		if err != nil {
-			return err
-		}
+			// Short circuit (e.g attempting to run an empty partition), the caller decides how to handle it
+			if runCommand.shortCircuit {
+				runCommand.cleanUp()
+				return errors.NewShortCircuitError("%s", runCommand.shortCircuitInfo)
+			}
 
-		// Preserve this attempt's attachments before any retry re-invocation overwrites them in place.
-		if shouldPreserveAttachments() {
-			if err := s.preserveAttachments(testResults, originalAttemptID); err != nil {
-				return errors.WithStack(err)
+			if cfg.IntermediateArtifactsPath != "" {
+				ias, err := s.NewIntermediateArtifactStorage(cfg.IntermediateArtifactsPath)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				log = s.openCommandLog(cfg, ias, "command")
+			}
+			commandStdout, commandStderr := log.tee(stdout, cfg.commandStderr())
+
+			// Run sub-command
+			startedAt := time.Now()
+			_, cmdErr := s.runCommand(commandCtx, runCommand.commandArgs, commandOptions{
+				stdout:      commandStdout,
+				stderr:      commandStderr,
+				env:         []string{},
+				timeout:     cfg.AttemptTimeout,
+				gracePeriod: cfg.TerminationGracePeriod,
+			})
+			runCommand.cleanUp()
+			s.closeCommandLog(log)
+
+			// The original command inherits Captain's environment, including any retry command ID
+			originalCfg := cfg
+			originalCfg.TestResultsFileGlob = expandRetryCommandID(cfg.TestResultsFileGlob, os.Getenv(RetryCommandIDEnvVar))
+
+			testResults, testResultsFiles, emptyTestResultsFiles, runErr, err = s.handleCommandOutcome(
+				originalCfg,
+				cmdErr,
+				lastRetryID,
+				startedAt,
+			)
+			if err != nil {
+				return err
+			}
+
+			if otherError, ok := terminationOtherError(cmdErr); ok && cfg.TestResultsFileGlob != "" {
+				if testResults == nil {
+					testResults = v1.NewTestResults(v1.NewOtherFramework(nil, nil), []v1.Test{}, []v1.OtherError{})
+				}
+
+				testResults.OtherErrors = append(testResults.OtherErrors, otherError)
+				testResults.Summary = v1.NewSummary(testResults.Tests, testResults.OtherErrors)
+			}
+
+			recordCommandLog(testResults, log)
+
+			// Preserve this attempt's attachments before any retry re-invocation overwrites them in place.
+			if shouldPreserveAttachments() {
+				if err := s.preserveAttachments(testResults, originalAttemptID); err != nil {
+					return errors.WithStack(err)
+				}
 			}
 		}
 
@@ -195,6 +280,7 @@ func (s Service) RunSuite(ctx context.Context, cfg RunConfig) (finalErr error) {
 
 			newlyExecutedTestResults = v1.NewTestResults(testResults.Framework, tests, otherErrors)
 			newlyExecutedTestResults.DerivedFrom = derivedFrom
+			recordCommandLog(newlyExecutedTestResults, log)
 		}
 
 		// Wait until run configuration was fetched. Ignore any errors.
@@ -218,22 +304,45 @@ func (s Service) RunSuite(ctx context.Context, cfg RunConfig) (finalErr error) {
 			}
 		}
 
-		testResults, newlyExecutedTestResults, lastRetryID, err = s.attemptRetries(
-			ctx,
-			testResults,
-			newlyExecutedTestResults,
-			cfg,
-			apiConfiguration,
-			0,
-		)
-		if err != nil {
-			if _, ok := errors.AsRetryError(err); ok {
-				return errors.WithStack(err)
+		if cfg.Repeat > 1 {
+			testResults, newlyExecutedTestResults, lastRetryID, err = s.attemptRepeats(
+				commandCtx,
+				testResults,
+				newlyExecutedTestResults,
+				cfg,
+				0,
+			)
+			if err != nil {
+				if _, ok := errors.AsRetryError(err); ok {
+					return errors.WithStack(err)
+				}
+				s.Log.Warnf("An issue occurred while repeating your tests: %v", err)
+			}
+		} else {
+			testResults, newlyExecutedTestResults, lastRetryID, err = s.attemptRetries(
+				commandCtx,
+				testResults,
+				newlyExecutedTestResults,
+				cfg,
+				apiConfiguration,
+				0,
+			)
+			if err != nil {
+				if _, ok := errors.AsRetryError(err); ok {
+					return errors.WithStack(err)
+				}
+				s.Log.Warnf("An issue occurred while retrying your tests: %v", err)
 			}
-			s.Log.Warnf("An issue occurred while retrying your tests: %v", err)
 		}
 	}
 
+	messageRules, err := newRetryMessageRules(cfg.RetryOnlyIfMessageMatches, cfg.RetryNeverIfMessageMatches)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	messageRules.recordRetryEligibility(testResults)
+	messageRules.recordRetryEligibility(newlyExecutedTestResults)
+
 	quarantinedFailedTests := make([]v1.Test, 0)
 	unquarantinedFailedTests := make([]v1.Test, 0)
 	otherErrorCount := 0
@@ -386,6 +495,27 @@ func (s Service) RunSuite(ctx context.Context, cfg RunConfig) (finalErr error) {
 		)
 	}
 
+	// When repeating the tests, any test that didn't pass in every run fails the test suite, even if it passed
+	// in the last one
+	if cfg.Repeat > 1 && testResults != nil {
+		var report strings.Builder
+		if reportErr := reporting.WriteFlakinessReport(&report, *testResults); reportErr != nil {
+			s.Log.Warnf("Unable to write the flakiness report: %s", reportErr.Error())
+		} else {
+			s.Log.Infoln(strings.TrimSuffix(report.String(), "\n"))
+		}
+
+		if unstableTests := s.unstableTests(testResults, quarantinedTests); len(unstableTests) > 0 {
+			err = errors.NewExecutionError(
+				1,
+				"%v %v did not pass in every one of the %v runs",
+				len(unstableTests),
+				pluralize(len(unstableTests), "test", "tests"),
+				cfg.Repeat,
+			)
+		}
+	}
+
 	// Return the original exit code if there was a non-test error
 	if runErr != nil && otherErrorCount > 0 {
 		err = errors.WithStack(runErr)
@@ -402,6 +532,11 @@ func (s Service) RunSuite(ctx context.Context, cfg RunConfig) (finalErr error) {
 		err = errors.WithStack(runErr)
 	}
 
+	// Too few tests only fail the run if it would otherwise pass, any other failure is more relevant
+	if err == nil {
+		err = s.checkTestCount(cfg, testResults, expectedTestCount, emptyTestResultsFiles)
+	}
+
 	if uploadError != nil && cfg.FailOnUploadError {
 		err = uploadError
 	}
@@ -443,17 +578,22 @@ func (s Service) attemptRetries(
 		return originalTestResults, newlyExecutedTestResults, startingRetryID, nil
 	}
 
-	ias, err := s.NewIntermediateArtifactStorage(cfg.IntermediateArtifactsPath)
-	if err != nil {
-		return originalTestResults, newlyExecutedTestResults, startingRetryID, errors.WithStack(err)
-	}
+	// A dry run only plans the first retry, it neither runs commands nor stores their artifacts
+	var ias *IntermediateArtifactStorage
+	if cfg.dryRunPlan == nil {
+		var err error
+		ias, err = s.NewIntermediateArtifactStorage(cfg.IntermediateArtifactsPath)
+		if err != nil {
+			return originalTestResults, newlyExecutedTestResults, startingRetryID, errors.WithStack(err)
+		}
 
-	if cfg.IntermediateArtifactsPath == "" {
-		defer func() {
-			if err := ias.delete(); err != nil {
-				s.Log.Warnf("Unable to clean up temporary files: %s", err.Error())
-			}
-		}()
+		if cfg.IntermediateArtifactsPath == "" {
+			defer func() {
+				if err := ias.delete(); err != nil {
+					s.Log.Warnf("Unable to clean up temporary files: %s", err.Error())
+				}
+			}()
+		}
 	}
 
 	// if retries is set and flaky-retries is not, set flaky-retries to retries
@@ -474,20 +614,13 @@ func (s Service) attemptRetries(
 		return originalTestResults, newlyExecutedTestResults, startingRetryID, errors.WithStack(err)
 	}
 
-	framework := originalTestResults.Framework
-	var substitution targetedretries.Substitution = targetedretries.JSONSubstitution{FileSystem: s.FileSystem}
-	if err := substitution.ValidateTemplate(compiledRetryTemplate); err != nil {
-		frameworkSubstitution, ok := cfg.SubstitutionsByFramework[framework]
-		if !ok {
-			return originalTestResults, newlyExecutedTestResults,
-				startingRetryID, errors.NewInternalError("Unable to retry %q", framework)
-		}
-
-		if err := frameworkSubstitution.ValidateTemplate(compiledRetryTemplate); err != nil {
-			return originalTestResults, newlyExecutedTestResults, startingRetryID, errors.WithStack(err)
-		}
-
-		substitution = frameworkSubstitution
+	substitution, err := s.retrySubstitution(
+		cfg.SubstitutionsByFramework,
+		originalTestResults.Framework,
+		compiledRetryTemplate,
+	)
+	if err != nil {
+		return originalTestResults, newlyExecutedTestResults, startingRetryID, err
 	}
 
 	maxTestsToRetryCount, err := cfg.MaxTestsToRetryCount()
@@ -500,6 +633,16 @@ func (s Service) attemptRetries(
 		return originalTestResults, newlyExecutedTestResults, startingRetryID, errors.WithStack(err)
 	}
 
+	messageRules, err := newRetryMessageRules(cfg.RetryOnlyIfMessageMatches, cfg.RetryNeverIfMessageMatches)
+	if err != nil {
+		return originalTestResults, newlyExecutedTestResults, startingRetryID, errors.WithStack(err)
+	}
+
+	quarantinedTests := make([]backend.Test, len(apiConfiguration.QuarantinedTests))
+	for i, qt := range apiConfiguration.QuarantinedTests {
+		quarantinedTests[i] = qt.Test
+	}
+
 	maxRetries := nonFlakyRetries
 	if quarantinedTestRetries > maxRetries {
 		maxRetries = quarantinedTestRetries
@@ -519,15 +662,29 @@ func (s Service) attemptRetries(
 	retryID := startingRetryID
 
 	for retries := 0; retries < maxRetries; retries++ {
+		if ctx.Err() != nil {
+			s.Log.Warnf("%s. Skipping any remaining retries.", context.Cause(ctx))
+			break
+		}
+
 		remainingFlakyFailures := make([]v1.Test, 0)
 		remainingNonFlakyFailures := make([]v1.Test, 0)
 		remainingQuarantinedTestFailures := make([]v1.Test, 0)
+		// ineligibleFailures are failures that will never be retried due to their failure message
+		ineligibleFailures := 0
 
 		for _, test := range flattenedTestResults.Tests {
 			if !test.Attempt.Status.ImpliesFailure() {
 				continue
 			}
 
+			if !messageRules.evaluate(test).eligible {
+				if !s.isIdentifiedIn(test, quarantinedTests) {
+					ineligibleFailures++
+				}
+				continue
+			}
+
 			if s.isIdentifiedIn(test, apiConfiguration.FlakyTests) {
 				remainingFlakyFailures = append(remainingFlakyFailures, test)
 			} else {
@@ -574,18 +731,25 @@ func (s Service) attemptRetries(
 
 		// fail fast if we know we can't pass the build
 		if cfg.FailRetriesFast && ((nonFlakyAttemptsExhausted && len(remainingNonFlakyFailures) > 0) ||
-			(flakyAttemptsExhausted && len(remainingFlakyFailures) > 0)) {
+			(flakyAttemptsExhausted && len(remainingFlakyFailures) > 0) || ineligibleFailures > 0) {
 			break
 		}
 
-		filter := s.CreateRetryFilter(apiConfiguration, remainingFlakyFailures, retries, flakyRetries,
+		statusFilter := s.CreateRetryFilter(apiConfiguration, remainingFlakyFailures, retries, flakyRetries,
 			nonFlakyRetries, quarantinedTestRetries)
+		filter := func(test v1.Test) bool {
+			return statusFilter(test) && messageRules.evaluate(test).eligible
+		}
 
 		retryID++
-		ias.SetRetryID(retryID)
 
-		allNewTestResults := make([]v1.TestResults, 0)
-		allSubstitutions, err := substitution.SubstitutionsFor(compiledRetryTemplate, *flattenedTestResults, filter)
+		allSubstitutions, err := s.retrySubstitutionsFor(
+			substitution,
+			compiledRetryTemplate,
+			*flattenedTestResults,
+			filter,
+			cfg.RetryBatchSize,
+		)
 		if err != nil {
 			return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, errors.Wrap(
 				err,
@@ -593,144 +757,45 @@ func (s Service) attemptRetries(
 			)
 		}
 
-		for i, substitutions := range allSubstitutions {
-			command := compiledRetryTemplate.Substitute(substitutions)
-			args, err := shellwords.Parse(command)
-			if err != nil {
-				return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, errors.Wrapf(
-					err,
-					"Unable to parse %q into shell arguments",
-					command,
-				)
-			}
-
-			ias.SetCommandID(i + 1)
-			env := []string{
-				fmt.Sprintf("CAPTAIN_RETRY_ATTEMPT_NUMBER=%v", retries+1),
-				fmt.Sprintf("CAPTAIN_RETRY_INVOCATION_NUMBER=%v", i+1),
-				fmt.Sprintf("CAPTAIN_RETRY_COMMAND_ID=%v-%v", retries+1, i+1),
-			}
-
-			s.Log.Infoln()
-			s.Log.Infoln(strings.Repeat("-", 80))
-			if len(allSubstitutions) == 1 {
-				s.Log.Infoln(fmt.Sprintf("- Retry %v%v", retries+1, formattedRetryTotal))
-			} else {
-				s.Log.Infoln(fmt.Sprintf(
-					"- Retry %v%v, command %v of %v",
-					retries+1,
-					formattedRetryTotal,
-					i+1,
-					len(allSubstitutions),
-				))
-			}
-			for keyword, value := range substitutions {
-				s.Log.Infoln(fmt.Sprintf("-   %v: %v", keyword, value))
-			}
-			s.Log.Infoln(strings.Repeat("-", 80))
-			s.Log.Infoln()
-
-			stdout := os.Stdout
-			if cfg.Quiet {
-				stdout, err = os.OpenFile(os.DevNull, os.O_APPEND|os.O_WRONLY, 0o666)
-				if err != nil {
-					s.Log.Warnf("Could not open %s for writing", os.DevNull)
-				}
-			}
-
-			for _, preRetryCommand := range cfg.PreRetryCommands {
-				preRetryArgs, err := shellwords.Parse(preRetryCommand)
-				if err != nil {
-					return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, errors.Wrapf(
-						err,
-						"Unable to parse %q into shell arguments",
-						preRetryCommand,
-					)
-				}
-
-				if _, err := s.runCommand(ctx, preRetryArgs, stdout, env); err != nil {
-					return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, errors.Wrapf(
-						err,
-						"Error while executing %q",
-						preRetryCommand,
-					)
-				}
-			}
-
-			_, cmdErr := s.runCommand(ctx, args, stdout, env)
-
-			for _, postRetryCommand := range cfg.PostRetryCommands {
-				postRetryArgs, err := shellwords.Parse(postRetryCommand)
-				if err != nil {
-					return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, errors.Wrapf(
-						err,
-						"Unable to parse %q into shell arguments",
-						postRetryCommand,
-					)
-				}
-
-				if _, err := s.runCommand(ctx, postRetryArgs, stdout, env); err != nil {
-					return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, errors.Wrapf(
-						err,
-						"Error while executing %q",
-						postRetryCommand,
-					)
-				}
-			}
-
-			// +1 because it's 1-indexed
-			newTestResults, newTestResultsFiles, _, err := s.handleCommandOutcome(cfg, cmdErr, retryID)
-			if err != nil {
-				return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, err
-			}
-
-			// Preserve this invocation's attachments before the next invocation overwrites them in place.
-			if shouldPreserveAttachments() {
-				if err := s.preserveAttachments(newTestResults, ias.attemptScope()); err != nil {
-					return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, errors.WithStack(err)
-				}
-			}
+		if cfg.dryRunPlan != nil {
+			err := cfg.dryRunPlan.addRetryCommands("retry", compiledRetryTemplate, allSubstitutions)
+			return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, err
+		}
 
-			if newTestResults != nil {
-				allNewTestResults = append(allNewTestResults, *newTestResults)
-			}
-			if err := ias.moveTestResults(newTestResultsFiles); err != nil {
-				return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, errors.WithStack(err)
-			}
-			if err := ias.MoveAdditionalArtifacts(cfg.AdditionalArtifactPaths); err != nil {
-				return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, errors.WithStack(err)
-			}
+		ias.SetRetryID(retryID)
+		allNewTestResults, retryTerminationErr, err := s.runRetryCommands(
+			ctx,
+			cfg,
+			compiledRetryTemplate,
+			allSubstitutions,
+			ias,
+			retryRound{label: "Retry", number: retries + 1, total: formattedRetryTotal, retryID: retryID},
+		)
+		if err != nil {
+			return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, err
 		}
-		if jsonSubstitution, ok := substitution.(targetedretries.JSONSubstitution); ok {
-			if err := jsonSubstitution.CleanUp(allSubstitutions); err != nil {
+		if cleanableSubstitution, ok := substitution.(targetedretries.CleanableSubstitution); ok {
+			if err := cleanableSubstitution.CleanUp(allSubstitutions); err != nil {
 				s.Log.Warn(err)
 			}
 		}
 
-	FLATTENED_TEST_RESULTS:
-		for _, originalTest := range flattenedTestResults.Tests {
-			if !filter(originalTest) {
-				continue
-			}
-
-			for _, retriedResult := range allNewTestResults {
-				for _, retriedTest := range retriedResult.Tests {
-					if originalTest.Matches(retriedTest) {
-						continue FLATTENED_TEST_RESULTS
-					}
-				}
-			}
+		unfinishedTests, err := s.unfinishedRetriedTests(
+			cfg,
+			*flattenedTestResults,
+			filter,
+			allNewTestResults,
+			retryTerminationErr,
+		)
+		if err != nil {
+			return flattenedTestResults, flattenedNewlyExecutedTestResults, retryID, err
+		}
 
-			missingTestResult := fmt.Sprintf(
-				"The retry command of suite %q appears to be misconfigured. "+
-					"Captain could not identify the original (failed) test in the output of the retry command.",
-				cfg.SuiteID,
+		if len(unfinishedTests) > 0 {
+			allNewTestResults = append(
+				allNewTestResults,
+				*v1.NewTestResults(flattenedTestResults.Framework, unfinishedTests, nil),
 			)
-			if cfg.FailOnMisconfiguredRetry {
-				return flattenedTestResults, flattenedNewlyExecutedTestResults,
-					retryID, errors.NewRetryError("%s", missingTestResult)
-			}
-			s.Log.Warn(missingTestResult)
 		}
 
 		mergedTestResults := v1.Merge([]v1.TestResults{*flattenedTestResults}, allNewTestResults)
@@ -801,55 +866,89 @@ func (s Service) handleCommandOutcome(
 	cfg RunConfig,
 	cmdErr error,
 	retryID int,
-) (*v1.TestResults, []string, error, error) {
+	startedAt time.Time,
+) (*v1.TestResults, []string, []string, error, error) {
 	var runErr error
 	ok := true
 	if cmdErr != nil {
 		runErr, ok = errors.AsExecutionError(cmdErr)
 	}
 	if !ok {
-		return nil, nil, errors.WithStack(runErr), errors.WithStack(cmdErr)
+		return nil, nil, nil, errors.WithStack(runErr), errors.WithStack(cmdErr)
+	}
+
+	// Timeouts and interrupts are execution errors as well, but they carry additional context that should be surfaced
+	// to the user
+	if isTerminationError(cmdErr) {
+		runErr = cmdErr
 	}
 
 	// Return early if no testResultsFiles were defined over the CLI. If there was an error
 	// during execution, the exit Code is being passed along.
 	if cfg.TestResultsFileGlob == "" {
 		s.Log.Debug("No testResultsFile path provided, quitting")
-		return nil, nil, errors.WithStack(runErr), errors.WithStack(runErr)
+		return nil, nil, nil, errors.WithStack(runErr), errors.WithStack(runErr)
 	}
 
 	testResultsFiles, err := s.FileSystem.Glob(cfg.TestResultsFileGlob)
 	if err != nil {
 		return nil,
 			testResultsFiles,
+			nil,
 			errors.WithStack(runErr),
 			errors.NewSystemError("unable to expand filepath glob: %s", err)
 	}
+	testResultsFiles = s.withoutStaleTestResultsFiles(testResultsFiles, startedAt)
 
 	// retryID + 1 because the group numbers are 1-indexed
-	testResults, err := s.parse(testResultsFiles, retryID+1)
+	testResults, emptyTestResultsFiles, err := s.parseTestResultsFiles(testResultsFiles, retryID+1)
 	if err != nil {
 		return nil,
 			testResultsFiles,
+			nil,
 			errors.WithStack(runErr),
 			errors.WithStack(err)
 	}
 
-	return testResults, testResultsFiles, errors.WithStack(runErr), nil
+	return testResults, testResultsFiles, emptyTestResultsFiles, errors.WithStack(runErr), nil
+}
+
+// commandOptions configures how `runCommand` executes a sub-process.
+type commandOptions struct {
+	env    []string
+	stdout io.Writer
+	// stderr defaults to Captain's own stderr
+	stderr io.Writer
+
+	// timeout limits how long the command may run for. A timeout of 0 disables the limit.
+	timeout time.Duration
+	// gracePeriod is how long a command has to exit after being terminated before it is killed.
+	gracePeriod time.Duration
 }
 
 func (s Service) runCommand(
 	ctx context.Context,
 	args []string,
-	stdout io.Writer,
-	env []string,
+	opts commandOptions,
 ) (context.Context, error) {
-	cmd, err := s.TaskRunner.NewCommand(ctx, exec.CommandConfig{
-		Name:   args[0],
-		Args:   args[1:],
-		Env:    env,
-		Stdout: stdout,
-		Stderr: os.Stderr,
+	commandCtx, cancel := withTimeout(ctx, opts.timeout, "The command exceeded the attempt timeout of %s", opts.timeout)
+	defer cancel()
+
+	var stderr io.Writer = os.Stderr
+	if opts.stderr != nil {
+		stderr = opts.stderr
+	}
+
+	// Cancellation is handled by `waitForCommand` rather than the task runner, so that the process tree gets a chance
+	// to shut down gracefully. Commands run in their own process group, which means that signals like Ctrl-C only reach
+	// them when forwarded by Captain.
+	cmd, err := s.TaskRunner.NewCommand(context.WithoutCancel(commandCtx), exec.CommandConfig{
+		Name:            args[0],
+		Args:            args[1:],
+		Env:             opts.env,
+		Stdout:          opts.stdout,
+		Stderr:          stderr,
+		NewProcessGroup: true,
 	})
 	if err != nil {
 		return ctx, errors.NewSystemError("unable to spawn sub-process: %s", err)
@@ -861,7 +960,12 @@ func (s Service) runCommand(
 	}
 	defer s.Log.Debugf("Finished executing %q", strings.Join(args, " "))
 
-	if err := cmd.Wait(); err != nil {
+	err = s.waitForCommand(commandCtx, cmd, opts.gracePeriod)
+	if commandCtx.Err() != nil && isTerminationError(context.Cause(commandCtx)) {
+		return ctx, errors.WithStack(context.Cause(commandCtx))
+	}
+
+	if err != nil {
 		if code, e := s.TaskRunner.GetExitStatusFromError(err); e == nil {
 			return ctx, errors.NewExecutionError(code, "test suite exited with non-zero exit code")
 		}
@@ -995,8 +1099,20 @@ func (s Service) reportTestResults(
 		}
 	}
 
-	if _, ok := s.API.(local.Client); ok && !cfg.UpdateStoredResults {
-		return nil, nil
+	if localClient, ok := s.API.(local.Client); ok {
+		if !cfg.UpdateStoredResults {
+			return nil, nil
+		}
+
+		// Partitions and queued batches only run some of the test files, the timings of the others stay as they are
+		if cfg.IsRunningPartition() || cfg.QueueURL != "" {
+			result, err := localClient.UpdatePartialTestResults(ctx, cfg.SuiteID, newlyExecutedTestResults)
+			if err != nil {
+				return nil, errors.Wrap(err, "unable to update test results")
+			}
+
+			return result, nil
+		}
 	}
 
 	if _, ok := s.API.(remote.Client); ok && !cfg.UploadResults {
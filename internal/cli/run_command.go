@@ -9,6 +9,8 @@ import (
 	"github.com/rwx-research/captain-cli/internal/errors"
 	"github.com/rwx-research/captain-cli/internal/runpartition"
 	"github.com/rwx-research/captain-cli/internal/templating"
+	"github.com/rwx-research/captain-cli/internal/testing"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
 )
 
 // RunCommand represents the command that captain run ultimately execute.
@@ -17,6 +19,9 @@ type RunCommand struct {
 	commandArgs      []string
 	shortCircuit     bool
 	shortCircuitInfo string
+	cleanUp          func()
+	// partition is the partition of the test files that the command runs, if partitioning
+	partition *testing.TestPartition
 }
 
 func commandArgs(command string, args []string) ([]string, error) {
@@ -45,14 +50,38 @@ func (s Service) makeRunCommand(ctx context.Context, cfg RunConfig) (RunCommand,
 		if err != nil {
 			return RunCommand{}, err
 		}
-		return RunCommand{commandArgs: commandArgs, shortCircuit: false}, nil
+		return RunCommand{commandArgs: commandArgs, shortCircuit: false, cleanUp: func() {}}, nil
 	}
 
 	partitionResult, err := s.calculatePartition(ctx, cfg.PartitionConfig)
 	if err != nil {
 		return RunCommand{}, errors.WithStack(err)
 	}
-	partitionedTestFilePaths := partitionResult.partition.TestFilePaths
+
+	runCommand, err := s.makePartitionCommand(cfg, partitionResult.partition)
+	if err != nil {
+		return RunCommand{}, err
+	}
+
+	if partitionResult.partition.IsEmpty() {
+		// short circuit to avoid running the entire test suite in a single partition (e.g empty partition)
+		runCommand.shortCircuit = true
+		runCommand.shortCircuitInfo = fmt.Sprintf(
+			"Partition %v contained no test files. %d/%d partitions were utilized. "+
+				"We recommend you set --partition-total no more than %d",
+			cfg.PartitionConfig.PartitionNodes,
+			partitionResult.utilizedPartitionCount,
+			cfg.PartitionConfig.PartitionNodes.Total,
+			partitionResult.utilizedPartitionCount,
+		)
+	}
+
+	return runCommand, nil
+}
+
+// makePartitionCommand substitutes the test files of a partition into the partition command template
+func (s Service) makePartitionCommand(cfg RunConfig, partition testing.TestPartition) (RunCommand, error) {
+	partitionedTestFilePaths := partition.TestFilePaths
 
 	// compile template
 	compiledPartitionTemplate, err := templating.CompileTemplate(cfg.PartitionCommandTemplate)
@@ -61,7 +90,21 @@ func (s Service) makeRunCommand(ctx context.Context, cfg RunConfig) (RunCommand,
 	}
 
 	// validate template
-	substitution := runpartition.DelimiterSubstitution{Delimiter: cfg.PartitionConfig.Delimiter}
+	var substitution runpartition.Substitution = runpartition.DelimiterSubstitution{
+		Delimiter:  cfg.PartitionConfig.Delimiter,
+		FileSystem: s.FileSystem,
+	}
+	if cfg.PartitionConfig.SplitTests {
+		tests := make([]v1.Test, len(partition.Tests))
+		for i, testTimingMatch := range partition.Tests {
+			tests[i] = testTimingMatch.Test()
+		}
+
+		substitution = runpartition.TestSubstitution{
+			Framework: v1.CoerceFramework(s.ParseConfig.ProvidedFrameworkLanguage, s.ParseConfig.ProvidedFrameworkKind),
+			Tests:     tests,
+		}
+	}
 	if err := substitution.ValidateTemplate(compiledPartitionTemplate); err != nil {
 		return RunCommand{}, errors.WithStack(err)
 	}
@@ -73,23 +116,28 @@ func (s Service) makeRunCommand(ctx context.Context, cfg RunConfig) (RunCommand,
 	}
 	partitionCommand := compiledPartitionTemplate.Substitute(substitutionValueLookup)
 
+	// remove any temporary files that were created for the substitution once the command completed
+	cleanUp := func() {
+		cleanableSubstitution, ok := substitution.(runpartition.CleanableSubstitution)
+		if !ok {
+			return
+		}
+
+		if err := cleanableSubstitution.CleanUp(substitutionValueLookup); err != nil {
+			s.Log.Warn(err)
+		}
+	}
+
 	commandArgs, err := commandArgs(partitionCommand, cfg.Args)
 	if err != nil {
+		cleanUp()
 		return RunCommand{}, err
 	}
 
-	if len(partitionedTestFilePaths) == 0 {
-		infoMessage := fmt.Sprintf(
-			"Partition %v contained no test files. %d/%d partitions were utilized. "+
-				"We recommend you set --partition-total no more than %d",
-			cfg.PartitionConfig.PartitionNodes,
-			partitionResult.utilizedPartitionCount,
-			cfg.PartitionConfig.PartitionNodes.Total,
-			partitionResult.utilizedPartitionCount,
-		)
-		// short circuit to avoid running the entire test suite in a single partition (e.g empty partition)
-		return RunCommand{commandArgs: commandArgs, shortCircuit: true, shortCircuitInfo: infoMessage}, nil
-	}
-
-	return RunCommand{commandArgs: commandArgs, shortCircuit: false}, nil
+	return RunCommand{
+		commandArgs:  commandArgs,
+		shortCircuit: false,
+		cleanUp:      cleanUp,
+		partition:    &partition,
+	}, nil
 }
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+)
+
+// SuiteRun is a single test suite of a `captain run --all` invocation. Every suite comes with its own service, as the
+// backend client and parsers are configured per suite.
+type SuiteRun struct {
+	Service Service
+	Config  RunConfig
+}
+
+// RunSuites runs several test suites, at most `concurrency` of them at the same time. Every suite is run, retried and
+// reported exactly like a single `captain run` would. A failing suite doesn't stop the others from running; once all
+// of them finished, a combined summary is printed and an error is returned if any of the suites failed.
+// Suites that run at the same time share the terminal, so every line of their output is prefixed with the suite ID.
+func (s Service) RunSuites(ctx context.Context, suiteRuns []SuiteRun, concurrency int) error {
+	suiteErrs := make([]error, len(suiteRuns))
+
+	// Concurrent suites share a single signal handler, so the second signal terminates Captain regardless of the suite
+	ctx, stopListening := s.listenForInterrupts(ctx)
+	defer stopListening()
+
+	var eg errgroup.Group
+	eg.SetLimit(max(concurrency, 1))
+
+	concurrent := concurrency > 1 && len(suiteRuns) > 1
+
+	for i, suiteRun := range suiteRuns {
+		eg.Go(func() error {
+			cfg := suiteRun.Config
+			if concurrent {
+				prefix := fmt.Sprintf("[%v] ", cfg.SuiteID)
+				prefixedStdout := newPrefixWriter(cfg.commandStdout(), prefix)
+				defer prefixedStdout.Flush()
+				prefixedStderr := newPrefixWriter(cfg.commandStderr(), prefix)
+				defer prefixedStderr.Flush()
+
+				cfg.stdout, cfg.stderr = prefixedStdout, prefixedStderr
+			}
+
+			suiteErrs[i] = suiteRun.Service.RunSuite(ctx, cfg)
+			return nil
+		})
+	}
+
+	_ = eg.Wait()
+
+	var summary strings.Builder
+	failedSuiteIDs := make([]string, 0)
+	exitCode := 0
+
+	for i, suiteRun := range suiteRuns {
+		suiteErr := suiteErrs[i]
+		if suiteErr == nil {
+			fmt.Fprintf(&summary, "\n  %v: passed", suiteRun.Config.SuiteID)
+			continue
+		}
+
+		// Suites that didn't need to run, e.g. because of an empty partition, don't fail the others
+		if shortCircuitErr, ok := errors.AsShortCircuitError(suiteErr); ok {
+			s.Log.Warnf("%v: %v", suiteRun.Config.SuiteID, shortCircuitErr.Error())
+			fmt.Fprintf(&summary, "\n  %v: skipped", suiteRun.Config.SuiteID)
+			continue
+		}
+
+		failedSuiteIDs = append(failedSuiteIDs, suiteRun.Config.SuiteID)
+
+		if executionError, ok := errors.AsExecutionError(suiteErr); ok {
+			fmt.Fprintf(&summary, "\n  %v: failed (exit code %d)", suiteRun.Config.SuiteID, executionError.Code)
+			if exitCode == 0 {
+				exitCode = executionError.Code
+			}
+			continue
+		}
+
+		fmt.Fprintf(&summary, "\n  %v: errored (%v)", suiteRun.Config.SuiteID, suiteErr.Error())
+		if exitCode == 0 {
+			exitCode = 1
+		}
+	}
+
+	s.Log.Infoln(fmt.Sprintf("\nRan %d test suites:%v", len(suiteRuns), summary.String()))
+
+	if len(failedSuiteIDs) == 0 {
+		return nil
+	}
+
+	return errors.NewExecutionError(
+		exitCode,
+		"%d of %d test suites failed: %v",
+		len(failedSuiteIDs),
+		len(suiteRuns),
+		strings.Join(failedSuiteIDs, ", "),
+	)
+}
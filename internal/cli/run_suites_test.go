@@ -0,0 +1,264 @@
+package cli_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/rwx-research/captain-cli/internal/backend"
+	"github.com/rwx-research/captain-cli/internal/cli"
+	"github.com/rwx-research/captain-cli/internal/config"
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/exec"
+	"github.com/rwx-research/captain-cli/internal/mocks"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RunSuites", func() {
+	var (
+		err          error
+		service      cli.Service
+		recordedLogs *observer.ObservedLogs
+		suiteRuns    []cli.SuiteRun
+		concurrency  int
+		exitCodes    map[string]int
+		commandsRun  []string
+		commandsLock sync.Mutex
+		handlers     []string
+		printOutput  bool
+	)
+
+	newSuiteRun := func(suiteID string) cli.SuiteRun {
+		suiteService := cli.Service{
+			API:        new(mocks.API),
+			Log:        service.Log,
+			FileSystem: new(mocks.FileSystem),
+			TaskRunner: new(mocks.TaskRunner),
+		}
+
+		suiteService.API.(*mocks.API).MockGetRunConfiguration = func(
+			_ context.Context,
+			_ string,
+		) (backend.RunConfiguration, error) {
+			return backend.RunConfiguration{}, nil
+		}
+		suiteService.API.(*mocks.API).MockGetQuarantinedTests = func(
+			_ context.Context,
+			_ string,
+		) ([]backend.Test, error) {
+			return []backend.Test{}, nil
+		}
+
+		suiteService.TaskRunner.(*mocks.TaskRunner).MockNewCommand = func(
+			_ context.Context,
+			cfg exec.CommandConfig,
+		) (exec.Command, error) {
+			mockCommand := new(mocks.Command)
+			mockCommand.MockStart = func() error {
+				commandsLock.Lock()
+				defer commandsLock.Unlock()
+				commandsRun = append(commandsRun, cfg.Name)
+				if printOutput {
+					_, err := cfg.Stdout.Write([]byte("output of " + cfg.Name + "\n"))
+					return err
+				}
+				return nil
+			}
+			mockCommand.MockWait = func() error {
+				if exitCodes[cfg.Name] != 0 {
+					return errors.NewSystemError("exit status %d", exitCodes[cfg.Name])
+				}
+				return nil
+			}
+			return mockCommand, nil
+		}
+		suiteService.TaskRunner.(*mocks.TaskRunner).MockGetExitStatusFromError = func(_ error) (int, error) {
+			return exitCodes[suiteID], nil
+		}
+		suiteService.TaskRunner.(*mocks.TaskRunner).MockNotifyInterrupts = func(_ chan<- os.Signal) (func(), error) {
+			handlers = append(handlers, suiteID)
+			return func() {}, nil
+		}
+
+		return cli.SuiteRun{
+			Service: suiteService,
+			Config: cli.RunConfig{
+				Command: suiteID,
+				SuiteID: suiteID,
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		var core zapcore.Core
+		core, recordedLogs = observer.New(zapcore.InfoLevel)
+		log := zaptest.NewLogger(GinkgoT(), zaptest.WrapOptions(
+			zap.WrapCore(func(_ zapcore.Core) zapcore.Core { return core }),
+		)).Sugar()
+
+		service = cli.Service{Log: log, TaskRunner: new(mocks.TaskRunner)}
+		service.TaskRunner.(*mocks.TaskRunner).MockNotifyInterrupts = func(_ chan<- os.Signal) (func(), error) {
+			handlers = append(handlers, "run-suites")
+			return func() {}, nil
+		}
+		handlers = []string{}
+		concurrency = 1
+		exitCodes = map[string]int{}
+		commandsRun = []string{}
+		printOutput = false
+		suiteRuns = []cli.SuiteRun{newSuiteRun("suite-a"), newSuiteRun("suite-b"), newSuiteRun("suite-c")}
+	})
+
+	JustBeforeEach(func() {
+		err = service.RunSuites(context.Background(), suiteRuns, concurrency)
+	})
+
+	It("runs every test suite in order", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(commandsRun).To(Equal([]string{"suite-a", "suite-b", "suite-c"}))
+	})
+
+	It("installs a single signal handler for all test suites", func() {
+		Expect(handlers).To(Equal([]string{"run-suites"}))
+	})
+
+	It("prints a combined summary", func() {
+		logMessages := make([]string, 0)
+		for _, log := range recordedLogs.All() {
+			logMessages = append(logMessages, log.Message)
+		}
+
+		Expect(logMessages).To(ContainElement(
+			"\nRan 3 test suites:\n  suite-a: passed\n  suite-b: passed\n  suite-c: passed",
+		))
+	})
+
+	Context("when some of the test suites fail", func() {
+		BeforeEach(func() {
+			exitCodes["suite-b"] = 2
+			exitCodes["suite-c"] = 3
+		})
+
+		It("still runs the remaining test suites", func() {
+			Expect(commandsRun).To(Equal([]string{"suite-a", "suite-b", "suite-c"}))
+		})
+
+		It("returns the exit code of the first failed test suite", func() {
+			executionError, ok := errors.AsExecutionError(err)
+			Expect(ok).To(BeTrue())
+			Expect(executionError.Code).To(Equal(2))
+			Expect(executionError.Error()).To(Equal("2 of 3 test suites failed: suite-b, suite-c"))
+		})
+
+		It("prints a combined summary", func() {
+			logMessages := make([]string, 0)
+			for _, log := range recordedLogs.All() {
+				logMessages = append(logMessages, log.Message)
+			}
+
+			Expect(logMessages).To(ContainElement(
+				"\nRan 3 test suites:\n  suite-a: passed\n  suite-b: failed (exit code 2)\n  suite-c: failed (exit code 3)",
+			))
+		})
+	})
+
+	Context("when a test suite is misconfigured", func() {
+		BeforeEach(func() {
+			suiteRuns[0].Config.Retries = 1
+		})
+
+		It("reports the error and exits with 1", func() {
+			executionError, ok := errors.AsExecutionError(err)
+			Expect(ok).To(BeTrue())
+			Expect(executionError.Code).To(Equal(1))
+			Expect(commandsRun).To(Equal([]string{"suite-b", "suite-c"}))
+
+			logMessages := make([]string, 0)
+			for _, log := range recordedLogs.All() {
+				logMessages = append(logMessages, log.Message)
+			}
+			Expect(logMessages).To(ContainElement(ContainSubstring("suite-a: errored (Missing retry command)")))
+		})
+	})
+
+	Context("when the partition of a test suite is empty", func() {
+		BeforeEach(func() {
+			suiteRuns[1].Config.Command = ""
+			suiteRuns[1].Config.PartitionCommandTemplate = "suite-b {{ testFiles }}"
+			suiteRuns[1].Config.PartitionConfig = cli.PartitionConfig{
+				SuiteID:        "suite-b",
+				TestFilePaths:  []string{"*.test"},
+				PartitionNodes: config.PartitionNodes{Index: 1, Total: 2},
+				Delimiter:      " ",
+				RoundRobin:     true,
+			}
+			suiteRuns[1].Service.FileSystem.(*mocks.FileSystem).MockGlob = func(_ string) ([]string, error) {
+				return []string{"a.test"}, nil
+			}
+		})
+
+		It("skips the test suite and still runs the others", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(commandsRun).To(Equal([]string{"suite-a", "suite-c"}))
+
+			logMessages := make([]string, 0)
+			for _, log := range recordedLogs.All() {
+				logMessages = append(logMessages, log.Message)
+			}
+			Expect(logMessages).To(ContainElement(
+				"\nRan 3 test suites:\n  suite-a: passed\n  suite-b: skipped\n  suite-c: passed",
+			))
+		})
+	})
+
+	Context("with bounded parallelism", func() {
+		BeforeEach(func() {
+			concurrency = 2
+			exitCodes["suite-c"] = 4
+		})
+
+		It("runs every test suite", func() {
+			Expect(commandsRun).To(ConsistOf("suite-a", "suite-b", "suite-c"))
+
+			executionError, ok := errors.AsExecutionError(err)
+			Expect(ok).To(BeTrue())
+			Expect(executionError.Code).To(Equal(4))
+		})
+
+		Context("when the test suites print output", func() {
+			var stdout *os.File
+
+			BeforeEach(func() {
+				printOutput = true
+
+				var err error
+				stdout, err = os.CreateTemp(GinkgoT().TempDir(), "stdout")
+				Expect(err).NotTo(HaveOccurred())
+
+				originalStdout := os.Stdout
+				os.Stdout = stdout
+				DeferCleanup(func() {
+					os.Stdout = originalStdout
+				})
+			})
+
+			It("prefixes every line with the suite ID", func() {
+				output, err := os.ReadFile(stdout.Name())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(strings.Split(strings.TrimSpace(string(output)), "\n")).To(ConsistOf(
+					"[suite-a] output of suite-a",
+					"[suite-b] output of suite-b",
+					"[suite-c] output of suite-c",
+				))
+			})
+		})
+	})
+})
@@ -2,12 +2,17 @@ package cli_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	iofs "io/fs"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -23,6 +28,7 @@ import (
 	"github.com/rwx-research/captain-cli/internal/fs"
 	"github.com/rwx-research/captain-cli/internal/mocks"
 	"github.com/rwx-research/captain-cli/internal/parsing"
+	"github.com/rwx-research/captain-cli/internal/queue"
 	"github.com/rwx-research/captain-cli/internal/targetedretries"
 	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
 
@@ -102,11 +108,14 @@ var _ = Describe("Run", func() {
 		}
 		service.TaskRunner.(*mocks.TaskRunner).MockNewCommand = newCommand
 
+		// Test results files without any tests fail the run, see "when checking the number of tests"
 		service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(_ io.Reader) (
 			*v1.TestResults,
 			error,
 		) {
-			return &v1.TestResults{}, nil
+			return v1.NewTestResults(v1.RubyRSpecFramework, []v1.Test{
+				{Name: "passing test", Attempt: v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()}},
+			}, nil), nil
 		}
 
 		// Caution: This needs to be an existing file. We don't actually read from it, however the glob expansion
@@ -180,9 +189,14 @@ var _ = Describe("Run", func() {
 		})
 	})
 
-	Context("under expected conditions", func() {
+	Context("when checking the number of tests", func() {
+		var expectedTestCount int
+
 		BeforeEach(func() {
-			mockUploadTestResults := func(
+			expectedTestCount = 0
+			testResultsFileUploaded = false
+
+			service.API.(*mocks.API).MockUpdateTestResults = func(
 				_ context.Context,
 				_ string,
 				_ v1.TestResults,
@@ -190,165 +204,725 @@ var _ = Describe("Run", func() {
 				testResultsFileUploaded = true
 				return []backend.TestResultsUploadResult{{OriginalPaths: []string{testResultsFilePath}, Uploaded: true}}, nil
 			}
-			service.API.(*mocks.API).MockUpdateTestResults = mockUploadTestResults
-
-			mockGetRunConfiguration := func(
+			service.API.(*mocks.API).MockGetRunConfiguration = func(
 				_ context.Context,
 				_ string,
 			) (backend.RunConfiguration, error) {
-				fetchedRunConfiguration = true
 				return backend.RunConfiguration{}, nil
 			}
-			service.API.(*mocks.API).MockGetRunConfiguration = mockGetRunConfiguration
+			service.API.(*mocks.API).MockGetTestResults = func(_ context.Context, _ string) (*v1.TestResults, error) {
+				if expectedTestCount == 0 {
+					return nil, os.ErrNotExist
+				}
+
+				tests := make([]v1.Test, expectedTestCount)
+				for i := range tests {
+					tests[i] = v1.Test{Name: fmt.Sprintf("test %d", i), Attempt: v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()}}
+				}
+				return v1.NewTestResults(v1.RubyRSpecFramework, tests, nil), nil
+			}
 
 			mockCommand.MockWait = func() error {
-				commandFinished = true
 				return nil
 			}
-		})
 
-		It("doesn't return an error", func() {
-			Expect(err).ToNot(HaveOccurred())
+			service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(_ io.Reader) (
+				*v1.TestResults,
+				error,
+			) {
+				return v1.NewTestResults(v1.RubyRSpecFramework, []v1.Test{
+					{Name: "first", Attempt: v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()}},
+					{Name: "second", Attempt: v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()}},
+				}, nil), nil
+			}
 		})
 
-		It("runs the supplied command", func() {
-			Expect(commandStarted).To(BeTrue())
-		})
+		Context("with a minimum number of tests", func() {
+			BeforeEach(func() {
+				runConfig.MinTests = 2
+			})
 
-		It("waits until the supplied command stopped running", func() {
-			Expect(commandFinished).To(BeTrue())
+			It("passes when enough tests ran", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			Context("when fewer tests ran", func() {
+				BeforeEach(func() {
+					runConfig.MinTests = 3
+				})
+
+				It("fails after reporting the test results", func() {
+					configurationError, ok := errors.AsConfigurationError(err)
+					Expect(ok).To(BeTrue())
+					Expect(configurationError.Error()).To(Equal("Fewer tests than expected"))
+					Expect(configurationError.Description()).To(ContainSubstring("reported 2 tests, but at least 3"))
+					Expect(testResultsFileUploaded).To(BeTrue())
+				})
+			})
+
+			Context("when a test results file doesn't contain any tests", func() {
+				BeforeEach(func() {
+					service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(_ io.Reader) (
+						*v1.TestResults,
+						error,
+					) {
+						return v1.NewTestResults(v1.RubyRSpecFramework, []v1.Test{}, nil), nil
+					}
+				})
+
+				It("fails after reporting the test results", func() {
+					configurationError, ok := errors.AsConfigurationError(err)
+					Expect(ok).To(BeTrue())
+					Expect(configurationError.Error()).To(Equal("Test results without any tests"))
+					Expect(testResultsFileUploaded).To(BeTrue())
+				})
+			})
 		})
 
-		It("fetches the run configuration with the quarantined tests", func() {
-			Expect(fetchedRunConfiguration).To(BeTrue())
+		Context("with a maximum drop in the number of tests", func() {
+			BeforeEach(func() {
+				maxTestDropPercent := 25.0
+				runConfig.MaxTestDropPercent = &maxTestDropPercent
+			})
+
+			It("passes when the number of tests of recent runs is unknown", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			Context("when the backend doesn't store test results", func() {
+				BeforeEach(func() {
+					// Only the methods of the backend interface are promoted, just like with the remote backend
+					service.API = struct{ backend.Client }{service.API}
+				})
+
+				It("rejects the option before running any command", func() {
+					configurationError, ok := errors.AsConfigurationError(err)
+					Expect(ok).To(BeTrue())
+					Expect(configurationError.Error()).To(Equal("Unsupported option --max-test-drop-percent"))
+					Expect(commandStarted).To(BeFalse())
+					Expect(testResultsFileUploaded).To(BeFalse())
+				})
+			})
+
+			Context("when the number of tests dropped by less than the allowed percentage", func() {
+				BeforeEach(func() {
+					expectedTestCount = 2
+				})
+
+				It("passes", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
+			Context("when the number of tests dropped by more than the allowed percentage", func() {
+				BeforeEach(func() {
+					expectedTestCount = 4
+				})
+
+				It("fails", func() {
+					configurationError, ok := errors.AsConfigurationError(err)
+					Expect(ok).To(BeTrue())
+					Expect(configurationError.Description()).To(
+						ContainSubstring("50.0% fewer than the 4 tests of recent runs"),
+					)
+				})
+			})
 		})
 
-		It("reads the test results file", func() {
-			Expect(filesOpened).To(ContainElement(testResultsFilePath))
+		Context("without any check and a test results file without tests", func() {
+			var uploadedTestResults v1.TestResults
+
+			BeforeEach(func() {
+				service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(_ io.Reader) (
+					*v1.TestResults,
+					error,
+				) {
+					return v1.NewTestResults(v1.RubyRSpecFramework, []v1.Test{}, nil), nil
+				}
+				service.API.(*mocks.API).MockUpdateTestResults = func(
+					_ context.Context,
+					_ string,
+					testResults v1.TestResults,
+				) ([]backend.TestResultsUploadResult, error) {
+					testResultsFileUploaded = true
+					uploadedTestResults = testResults
+					return []backend.TestResultsUploadResult{{OriginalPaths: []string{testResultsFilePath}, Uploaded: true}}, nil
+				}
+			})
+
+			It("fails after reporting the unchanged test results", func() {
+				configurationError, ok := errors.AsConfigurationError(err)
+				Expect(ok).To(BeTrue())
+				Expect(configurationError.Error()).To(Equal("Test results without any tests"))
+				Expect(configurationError.Description()).To(ContainSubstring(testResultsFilePath))
+
+				Expect(testResultsFileUploaded).To(BeTrue())
+				Expect(uploadedTestResults.OtherErrors).To(BeEmpty())
+			})
 		})
+	})
 
-		It("uploads the test results file", func() {
-			Expect(testResultsFileUploaded).To(BeTrue())
+	Context("with test results files from earlier runs", func() {
+		var (
+			modifiedAt   time.Time
+			filesRemoved []string
+		)
+
+		BeforeEach(func() {
+			modifiedAt = time.Now().Add(-time.Hour)
+			filesRemoved = make([]string, 0)
+
+			service.API.(*mocks.API).MockUpdateTestResults = func(
+				_ context.Context,
+				_ string,
+				_ v1.TestResults,
+			) ([]backend.TestResultsUploadResult, error) {
+				testResultsFileUploaded = true
+				return []backend.TestResultsUploadResult{{OriginalPaths: []string{testResultsFilePath}, Uploaded: true}}, nil
+			}
+			service.API.(*mocks.API).MockGetRunConfiguration = func(
+				_ context.Context,
+				_ string,
+			) (backend.RunConfiguration, error) {
+				return backend.RunConfiguration{}, nil
+			}
+			service.FileSystem.(*mocks.FileSystem).MockStat = func(name string) (os.FileInfo, error) {
+				Expect(name).To(Equal(testResultsFilePath))
+				return mocks.FileInfo{FileName: name, ModifiedAt: modifiedAt}, nil
+			}
+			service.FileSystem.(*mocks.FileSystem).MockRemove = func(name string) error {
+				if !commandStarted {
+					filesRemoved = append(filesRemoved, name)
+				}
+				return nil
+			}
+
+			mockCommand.MockWait = func() error {
+				return nil
+			}
 		})
 
-		It("logs the uploaded files", func() {
-			logMessages := make([]string, 0)
+		It("ignores test results files that were last modified before the command started", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filesOpened).To(BeEmpty())
+			Expect(testResultsFileUploaded).To(BeFalse())
 
-			for _, log := range recordedLogs.All() {
+			logMessages := make([]string, 0)
+			for _, log := range recordedLogs.FilterLevelExact(zapcore.WarnLevel).All() {
 				logMessages = append(logMessages, log.Message)
 			}
-
-			Expect(logMessages).To(ContainElement(ContainSubstring("Found 1 test result file")))
 			Expect(logMessages).To(ContainElement(ContainSubstring(
-				fmt.Sprintf("- Updated Captain with results from %v", testResultsFilePath),
+				fmt.Sprintf("Ignoring %q as it was last modified at", testResultsFilePath),
 			)))
 		})
 
-		Context("With updating disabled and a local client", func() {
+		Context("when the test results file was written by the command", func() {
 			BeforeEach(func() {
-				runConfig = cli.RunConfig{
-					Command:             arg,
-					TestResultsFileGlob: testResultsFilePath,
-					SuiteID:             "test",
-					UpdateStoredResults: false,
-				}
-				service.API = local.Client{}
+				modifiedAt = time.Now().Add(time.Second)
 			})
 
-			It("runs the supplied command", func() {
-				Expect(commandStarted).To(BeTrue())
+			It("parses the test results file", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filesOpened).To(ContainElement(testResultsFilePath))
+				Expect(testResultsFileUploaded).To(BeTrue())
 			})
+		})
 
-			It("waits until the supplied command stopped running", func() {
-				Expect(commandFinished).To(BeTrue())
+		Context("when it's unknown when the test results file was last modified", func() {
+			BeforeEach(func() {
+				service.FileSystem.(*mocks.FileSystem).MockStat = func(_ string) (os.FileInfo, error) {
+					return nil, errors.NewSystemError("no such file")
+				}
 			})
 
-			It("reads the test results file", func() {
+			It("parses the test results file", func() {
+				Expect(err).NotTo(HaveOccurred())
 				Expect(filesOpened).To(ContainElement(testResultsFilePath))
 			})
+		})
 
-			It("does not upload the test results file", func() {
-				Expect(testResultsFileUploaded).To(BeFalse())
+		Context("when deleting stale test results files", func() {
+			BeforeEach(func() {
+				runConfig.DeleteStaleTestResults = true
 			})
 
-			It("does not log the uploaded files", func() {
-				logMessages := make([]string, 0)
+			It("deletes matching files before running the command", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filesRemoved).To(ConsistOf(testResultsFilePath))
+				Expect(commandStarted).To(BeTrue())
+			})
 
-				for _, log := range recordedLogs.All() {
-					logMessages = append(logMessages, log.Message)
-				}
+			Context("when a file can't be deleted", func() {
+				BeforeEach(func() {
+					service.FileSystem.(*mocks.FileSystem).MockRemove = func(_ string) error {
+						return errors.NewSystemError("permission denied")
+					}
+				})
 
-				Expect(logMessages).NotTo(ContainElement(ContainSubstring("Found 1 test result file")))
-				Expect(logMessages).NotTo(ContainElement(ContainSubstring(
-					fmt.Sprintf("- Updated Captain with results from %v", testResultsFilePath),
-				)))
+				It("errs without running the command", func() {
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("unable to delete stale test results file"))
+					Expect(commandStarted).To(BeFalse())
+				})
 			})
 		})
+	})
 
-		Context("With uploading disabled and a remote client", func() {
-			var mockRoundTripper func(*http.Request) (*http.Response, error)
+	Context("when pulling test files from a queue", func() {
+		var (
+			queueServer      *httptest.Server
+			commandArgs      [][]string
+			uploadedResults  v1.TestResults
+			commandExitCodes []int
+		)
 
-			BeforeEach(func() {
-				runConfig = cli.RunConfig{
-					Command:             arg,
-					TestResultsFileGlob: testResultsFilePath,
-					SuiteID:             "test",
-					UploadResults:       false,
-				}
-				mockRoundTripper = func(req *http.Request) (*http.Response, error) {
-					var resp http.Response
+		BeforeEach(func() {
+			commandArgs = make([][]string, 0)
+			commandExitCodes = []int{0, 0}
 
-					Expect(req.Method).To(Equal(http.MethodGet))
+			queueServer = httptest.NewServer(queue.NewServer(
+				[]string{"slow.test", "medium.test", "fast.test"},
+				2,
+				zaptest.NewLogger(GinkgoT()).Sugar(),
+			))
+			DeferCleanup(queueServer.Close)
 
-					if strings.HasSuffix(req.URL.Path, "quarantined_tests") {
-						resp.Body = io.NopCloser(strings.NewReader(`[]`))
-					} else {
-						Expect(req.URL.Path).To(HaveSuffix("run_configuration"))
-						resp.Body = io.NopCloser(strings.NewReader(`{}`))
-					}
+			runConfig.Command = ""
+			runConfig.QueueURL = queueServer.URL
+			runConfig.PartitionCommandTemplate = arg + " {{ testFiles }}"
+			runConfig.PartitionConfig.Delimiter = " "
 
-					return &resp, nil
+			service.TaskRunner.(*mocks.TaskRunner).MockNewCommand = func(
+				_ context.Context,
+				cfg exec.CommandConfig,
+			) (exec.Command, error) {
+				Expect(cfg.Name).To(Equal(arg))
+				commandArgs = append(commandArgs, cfg.Args)
+
+				exitCode := commandExitCodes[len(commandArgs)-1]
+				command := new(mocks.Command)
+				command.MockStart = func() error { return nil }
+				command.MockWait = func() error {
+					if exitCode != 0 {
+						return errors.NewExecutionError(exitCode, "exited")
+					}
+					return nil
 				}
-				service.API = remote.Client{RoundTrip: mockRoundTripper}
+				return command, nil
+			}
+			service.TaskRunner.(*mocks.TaskRunner).MockGetExitStatusFromError = func(err error) (int, error) {
+				executionError, ok := errors.AsExecutionError(err)
+				Expect(ok).To(BeTrue())
+				return executionError.Code, nil
+			}
+
+			service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(_ io.Reader) (
+				*v1.TestResults,
+				error,
+			) {
+				batch := strings.Join(commandArgs[len(commandArgs)-1], " ")
+				return v1.NewTestResults(v1.RubyRSpecFramework, []v1.Test{
+					{Name: batch, Attempt: v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()}},
+				}, nil), nil
+			}
+
+			service.API.(*mocks.API).MockUpdateTestResults = func(
+				_ context.Context,
+				_ string,
+				testResults v1.TestResults,
+			) ([]backend.TestResultsUploadResult, error) {
+				uploadedResults = testResults
+				return []backend.TestResultsUploadResult{{OriginalPaths: []string{testResultsFilePath}, Uploaded: true}}, nil
+			}
+			service.API.(*mocks.API).MockGetRunConfiguration = func(
+				_ context.Context,
+				_ string,
+			) (backend.RunConfiguration, error) {
+				return backend.RunConfiguration{}, nil
+			}
+		})
+
+		It("runs batches from the queue until it is drained", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(commandArgs).To(Equal([][]string{{"slow.test", "medium.test"}, {"fast.test"}}))
+		})
+
+		It("merges the test results of all batches", func() {
+			Expect(uploadedResults.Tests).To(HaveLen(2))
+			Expect(uploadedResults.Tests[0].Name).To(Equal("slow.test medium.test"))
+			Expect(uploadedResults.Tests[1].Name).To(Equal("fast.test"))
+		})
+
+		Context("when a batch fails", func() {
+			BeforeEach(func() {
+				commandExitCodes = []int{3, 0}
 			})
 
-			It("runs the supplied command", func() {
-				Expect(commandStarted).To(BeTrue())
+			It("keeps running the remaining batches and exits with the batch's exit code", func() {
+				Expect(commandArgs).To(HaveLen(2))
+				executionError, ok := errors.AsExecutionError(err)
+				Expect(ok).To(BeTrue())
+				Expect(executionError.Code).To(Equal(3))
 			})
+		})
 
-			It("waits until the supplied command stopped running", func() {
-				Expect(commandFinished).To(BeTrue())
+		Context("without a partition command", func() {
+			BeforeEach(func() {
+				runConfig.PartitionCommandTemplate = ""
 			})
 
-			It("reads the test results file", func() {
-				Expect(filesOpened).To(ContainElement(testResultsFilePath))
+			It("errs", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Missing partition command"))
+				Expect(commandArgs).To(BeEmpty())
 			})
+		})
 
-			It("does not upload the test results file", func() {
-				Expect(testResultsFileUploaded).To(BeFalse())
+		Context("with parallel workers", func() {
+			BeforeEach(func() {
+				runConfig.Parallel = 2
 			})
 
-			It("does not log the uploaded files", func() {
-				logMessages := make([]string, 0)
+			It("errs", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Conflicting partitioning options"))
+				Expect(commandArgs).To(BeEmpty())
+			})
+		})
 
-				for _, log := range recordedLogs.All() {
-					logMessages = append(logMessages, log.Message)
+		Context("when the queue goes away after a batch", func() {
+			BeforeEach(func() {
+				newCommand := service.TaskRunner.(*mocks.TaskRunner).MockNewCommand
+				service.TaskRunner.(*mocks.TaskRunner).MockNewCommand = func(
+					ctx context.Context,
+					cfg exec.CommandConfig,
+				) (exec.Command, error) {
+					queueServer.Close()
+					return newCommand(ctx, cfg)
 				}
+			})
 
-				Expect(logMessages).NotTo(ContainElement(ContainSubstring("Found 1 test result file")))
-				Expect(logMessages).NotTo(ContainElement(ContainSubstring(
-					fmt.Sprintf("- Updated Captain with results from %v", testResultsFilePath),
-				)))
+			It("reports the batches that ran and fails the run", func() {
+				Expect(commandArgs).To(HaveLen(1))
+				Expect(uploadedResults.Tests).To(HaveLen(1))
+				Expect(uploadedResults.OtherErrors).To(HaveLen(1))
+				Expect(uploadedResults.OtherErrors[0].Message).To(ContainSubstring("unable to request the next batch"))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("unable to request the next batch"))
 			})
 		})
+	})
+
+	Context("when running partitions in parallel", func() {
+		var (
+			mutex            sync.Mutex
+			commandArgs      map[string][]string
+			uploadedResults  v1.TestResults
+			commandExitCodes map[string]int
+		)
+
+		BeforeEach(func() {
+			commandArgs = make(map[string][]string)
+			commandExitCodes = make(map[string]int)
+
+			runConfig.Command = ""
+			runConfig.Parallel = 2
+			runConfig.TestResultsFileGlob = "results-$CAPTAIN_WORKER_INDEX.json"
+			runConfig.PartitionCommandTemplate = arg + " {{ testFiles }}"
+			runConfig.PartitionConfig = cfgWithGlobAndRoundRobin(0, 0, "*.test")
+			runConfig.PartitionConfig.SuiteID = runConfig.SuiteID
+
+			service.FileSystem.(*mocks.FileSystem).MockGlob = func(pattern string) ([]string, error) {
+				if pattern == "*.test" {
+					return []string{"a.test", "b.test", "c.test"}, nil
+				}
+				return []string{pattern}, nil
+			}
+			service.FileSystem.(*mocks.FileSystem).MockOpen = func(name string) (fs.File, error) {
+				return &mocks.File{Reader: strings.NewReader(name)}, nil
+			}
+
+			service.TaskRunner.(*mocks.TaskRunner).MockNewCommand = func(
+				_ context.Context,
+				cfg exec.CommandConfig,
+			) (exec.Command, error) {
+				Expect(cfg.Name).To(Equal(arg))
+				Expect(cfg.Env).To(HaveLen(1))
+				_, workerIndex, _ := strings.Cut(cfg.Env[0], "CAPTAIN_WORKER_INDEX=")
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				commandArgs[workerIndex] = cfg.Args
+				exitCode := commandExitCodes[workerIndex]
+
+				command := new(mocks.Command)
+				command.MockStart = func() error { return nil }
+				command.MockWait = func() error {
+					if exitCode != 0 {
+						return errors.NewExecutionError(exitCode, "exited")
+					}
+					return nil
+				}
+				return command, nil
+			}
+			service.TaskRunner.(*mocks.TaskRunner).MockGetExitStatusFromError = func(err error) (int, error) {
+				executionError, ok := errors.AsExecutionError(err)
+				Expect(ok).To(BeTrue())
+				return executionError.Code, nil
+			}
+
+			service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(reader io.Reader) (
+				*v1.TestResults,
+				error,
+			) {
+				name, err := io.ReadAll(reader)
+				Expect(err).ToNot(HaveOccurred())
+
+				return v1.NewTestResults(v1.RubyRSpecFramework, []v1.Test{
+					{Name: string(name), Attempt: v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()}},
+				}, nil), nil
+			}
+
+			service.API.(*mocks.API).MockUpdateTestResults = func(
+				_ context.Context,
+				_ string,
+				testResults v1.TestResults,
+			) ([]backend.TestResultsUploadResult, error) {
+				uploadedResults = testResults
+				return []backend.TestResultsUploadResult{{OriginalPaths: []string{testResultsFilePath}, Uploaded: true}}, nil
+			}
+			service.API.(*mocks.API).MockGetRunConfiguration = func(
+				_ context.Context,
+				_ string,
+			) (backend.RunConfiguration, error) {
+				return backend.RunConfiguration{}, nil
+			}
+		})
+
+		It("runs a worker per partition", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(commandArgs).To(Equal(map[string][]string{
+				"0": {"a.test", "c.test"},
+				"1": {"b.test"},
+			}))
+		})
+
+		It("merges the test results of all workers", func() {
+			Expect(uploadedResults.Tests).To(HaveLen(2))
+			Expect(uploadedResults.Tests[0].Name).To(Equal("results-0.json"))
+			Expect(uploadedResults.Tests[1].Name).To(Equal("results-1.json"))
+		})
+
+		Context("when a worker fails", func() {
+			BeforeEach(func() {
+				commandExitCodes["1"] = 3
+			})
+
+			It("exits with the worker's exit code", func() {
+				Expect(commandArgs).To(HaveLen(2))
+				executionError, ok := errors.AsExecutionError(err)
+				Expect(ok).To(BeTrue())
+				Expect(executionError.Code).To(Equal(3))
+			})
+		})
+
+		Context("with the sticky partitions and weights of CI nodes", func() {
+			BeforeEach(func() {
+				runConfig.PartitionConfig.PartitionNodes.Weights = []float64{1, 3}
+				runConfig.PartitionConfig.Sticky = true
+			})
+
+			It("splits the test files evenly between the workers", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(commandArgs).To(Equal(map[string][]string{
+					"0": {"a.test", "c.test"},
+					"1": {"b.test"},
+				}))
+			})
+		})
+
+		Context("when the test results path doesn't reference the worker index", func() {
+			BeforeEach(func() {
+				runConfig.TestResultsFileGlob = testResultsFilePath
+			})
+
+			It("errs", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Parallel workers would overwrite each other's test results"))
+				Expect(commandArgs).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("under expected conditions", func() {
+		BeforeEach(func() {
+			mockUploadTestResults := func(
+				_ context.Context,
+				_ string,
+				_ v1.TestResults,
+			) ([]backend.TestResultsUploadResult, error) {
+				testResultsFileUploaded = true
+				return []backend.TestResultsUploadResult{{OriginalPaths: []string{testResultsFilePath}, Uploaded: true}}, nil
+			}
+			service.API.(*mocks.API).MockUpdateTestResults = mockUploadTestResults
+
+			mockGetRunConfiguration := func(
+				_ context.Context,
+				_ string,
+			) (backend.RunConfiguration, error) {
+				fetchedRunConfiguration = true
+				return backend.RunConfiguration{}, nil
+			}
+			service.API.(*mocks.API).MockGetRunConfiguration = mockGetRunConfiguration
+
+			mockCommand.MockWait = func() error {
+				commandFinished = true
+				return nil
+			}
+		})
+
+		It("doesn't return an error", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("runs the supplied command", func() {
+			Expect(commandStarted).To(BeTrue())
+		})
+
+		It("waits until the supplied command stopped running", func() {
+			Expect(commandFinished).To(BeTrue())
+		})
+
+		It("fetches the run configuration with the quarantined tests", func() {
+			Expect(fetchedRunConfiguration).To(BeTrue())
+		})
+
+		It("reads the test results file", func() {
+			Expect(filesOpened).To(ContainElement(testResultsFilePath))
+		})
+
+		It("uploads the test results file", func() {
+			Expect(testResultsFileUploaded).To(BeTrue())
+		})
+
+		It("logs the uploaded files", func() {
+			logMessages := make([]string, 0)
+
+			for _, log := range recordedLogs.All() {
+				logMessages = append(logMessages, log.Message)
+			}
+
+			Expect(logMessages).To(ContainElement(ContainSubstring("Found 1 test result file")))
+			Expect(logMessages).To(ContainElement(ContainSubstring(
+				fmt.Sprintf("- Updated Captain with results from %v", testResultsFilePath),
+			)))
+		})
+
+		Context("With updating disabled and a local client", func() {
+			BeforeEach(func() {
+				runConfig = cli.RunConfig{
+					Command:             arg,
+					TestResultsFileGlob: testResultsFilePath,
+					SuiteID:             "test",
+					UpdateStoredResults: false,
+				}
+				service.API = local.Client{}
+			})
+
+			It("runs the supplied command", func() {
+				Expect(commandStarted).To(BeTrue())
+			})
+
+			It("waits until the supplied command stopped running", func() {
+				Expect(commandFinished).To(BeTrue())
+			})
+
+			It("reads the test results file", func() {
+				Expect(filesOpened).To(ContainElement(testResultsFilePath))
+			})
+
+			It("does not upload the test results file", func() {
+				Expect(testResultsFileUploaded).To(BeFalse())
+			})
+
+			It("does not log the uploaded files", func() {
+				logMessages := make([]string, 0)
+
+				for _, log := range recordedLogs.All() {
+					logMessages = append(logMessages, log.Message)
+				}
+
+				Expect(logMessages).NotTo(ContainElement(ContainSubstring("Found 1 test result file")))
+				Expect(logMessages).NotTo(ContainElement(ContainSubstring(
+					fmt.Sprintf("- Updated Captain with results from %v", testResultsFilePath),
+				)))
+			})
+		})
+
+		Context("With uploading disabled and a remote client", func() {
+			var mockRoundTripper func(*http.Request) (*http.Response, error)
+
+			BeforeEach(func() {
+				runConfig = cli.RunConfig{
+					Command:             arg,
+					TestResultsFileGlob: testResultsFilePath,
+					SuiteID:             "test",
+					UploadResults:       false,
+				}
+				mockRoundTripper = func(req *http.Request) (*http.Response, error) {
+					var resp http.Response
+
+					Expect(req.Method).To(Equal(http.MethodGet))
+
+					if strings.HasSuffix(req.URL.Path, "quarantined_tests") {
+						resp.Body = io.NopCloser(strings.NewReader(`[]`))
+					} else {
+						Expect(req.URL.Path).To(HaveSuffix("run_configuration"))
+						resp.Body = io.NopCloser(strings.NewReader(`{}`))
+					}
+
+					return &resp, nil
+				}
+				service.API = remote.Client{RoundTrip: mockRoundTripper}
+			})
+
+			It("runs the supplied command", func() {
+				Expect(commandStarted).To(BeTrue())
+			})
+
+			It("waits until the supplied command stopped running", func() {
+				Expect(commandFinished).To(BeTrue())
+			})
+
+			It("reads the test results file", func() {
+				Expect(filesOpened).To(ContainElement(testResultsFilePath))
+			})
+
+			It("does not upload the test results file", func() {
+				Expect(testResultsFileUploaded).To(BeFalse())
+			})
+
+			It("does not log the uploaded files", func() {
+				logMessages := make([]string, 0)
+
+				for _, log := range recordedLogs.All() {
+					logMessages = append(logMessages, log.Message)
+				}
+
+				Expect(logMessages).NotTo(ContainElement(ContainSubstring("Found 1 test result file")))
+				Expect(logMessages).NotTo(ContainElement(ContainSubstring(
+					fmt.Sprintf("- Updated Captain with results from %v", testResultsFilePath),
+				)))
+			})
+		})
+
+		Context("RWX OTEL span attributes", func() {
+			var (
+				otelSpanPath string
+				createdFiles map[string]*strings.Builder
+				existingFile map[string]bool
+			)
 
-		Context("RWX OTEL span attributes", func() {
-			var (
-				otelSpanPath string
-				createdFiles map[string]*strings.Builder
-				existingFile map[string]bool
-			)
-
 			BeforeEach(func() {
 				otelSpanPath = "/tmp/rwx-otel-span"
 				Expect(os.Setenv("RWX_OTEL_SPAN", otelSpanPath)).To(Succeed())
@@ -1381,6 +1955,76 @@ var _ = Describe("Run", func() {
 						runConfig.IntermediateArtifactsPath, testResultsFilePath)),
 				)
 			})
+
+			Context("with the output of the commands", func() {
+				var logFiles map[string]*mocks.File
+
+				BeforeEach(func() {
+					logFiles = make(map[string]*mocks.File)
+					runConfig.PreRetryCommands = []string{"pre-retry"}
+					// Quiet commands only write their output to the log files
+					runConfig.Quiet = true
+
+					service.FileSystem.(*mocks.FileSystem).MockCreate = func(name string) (fs.File, error) {
+						file := &mocks.File{Builder: new(strings.Builder), Reader: strings.NewReader("")}
+						logFiles[name] = file
+						return file, nil
+					}
+
+					service.TaskRunner.(*mocks.TaskRunner).MockNewCommand = func(
+						_ context.Context,
+						cfg exec.CommandConfig,
+					) (exec.Command, error) {
+						command := new(mocks.Command)
+						command.MockStart = func() error {
+							_, err := fmt.Fprintf(cfg.Stdout, "output of %v", cfg.Name)
+							return err
+						}
+						command.MockWait = mockCommand.MockWait
+						if cfg.Name == "pre-retry" {
+							command.MockWait = func() error { return nil }
+						}
+						return command, nil
+					}
+				})
+
+				It("tees the output of every command into a log file", func() {
+					Expect(err).NotTo(HaveOccurred())
+
+					logPath := func(scope, name string) string {
+						return fmt.Sprintf("%s/%s/logs/%s.log", runConfig.IntermediateArtifactsPath, scope, name)
+					}
+					Expect(logFiles).To(HaveKey(logPath("original-attempt", "command")))
+					Expect(logFiles[logPath("original-attempt", "command")].String()).To(Equal("output of " + arg))
+					Expect(logFiles).To(HaveKey(logPath("retry-1/command-1", "pre-retry-1")))
+					Expect(logFiles[logPath("retry-1/command-1", "pre-retry-1")].String()).To(Equal("output of pre-retry"))
+					Expect(logFiles).To(HaveKey(logPath("retry-2/command-1", "command")))
+					Expect(logFiles[logPath("retry-2/command-1", "command")].String()).To(Equal("output of retry"))
+				})
+
+				It("links the log files from the test results", func() {
+					Expect(uploadedTestResults).ToNot(BeNil())
+					Expect(uploadedTestResults.Meta).To(HaveKeyWithValue(
+						"captain_log_path",
+						fmt.Sprintf("%s/original-attempt/logs/command.log", runConfig.IntermediateArtifactsPath),
+					))
+
+					test := uploadedTestResults.Tests[0]
+					Expect(test.PastAttempts).To(HaveLen(2))
+					for i, attempt := range append(test.PastAttempts, test.Attempt) {
+						scope := "original-attempt"
+						if i > 0 {
+							scope = fmt.Sprintf("retry-%d/command-1", i)
+						}
+
+						logPath, ok := attempt.Tagged(v1.LogPathTag)
+						Expect(ok).To(BeTrue())
+						Expect(logPath).To(Equal(
+							fmt.Sprintf("%s/%s/logs/command.log", runConfig.IntermediateArtifactsPath, scope),
+						))
+					}
+				})
+			})
 		})
 
 		Context("when there are failures left after all retries", func() {
@@ -1414,6 +2058,837 @@ var _ = Describe("Run", func() {
 			})
 		})
 
+		Context("when the retry command exceeds the attempt timeout", func() {
+			var retrySignals []os.Signal
+
+			BeforeEach(func() {
+				runConfig.Retries = 1
+				runConfig.AttemptTimeout = 50 * time.Millisecond
+				runConfig.TerminationGracePeriod = 10 * time.Millisecond
+				retrySignals = nil
+
+				newCommand := func(_ context.Context, cfg exec.CommandConfig) (exec.Command, error) {
+					if cfg.Name != "retry" {
+						return mockCommand, nil
+					}
+
+					Expect(cfg.NewProcessGroup).To(BeTrue())
+
+					terminated := make(chan struct{})
+					mockRetryCommand := new(mocks.Command)
+					mockRetryCommand.MockStart = func() error {
+						return nil
+					}
+					mockRetryCommand.MockWait = func() error {
+						<-terminated
+						return errors.NewInternalError("signal: terminated")
+					}
+					mockRetryCommand.MockSignal = func(sig os.Signal) error {
+						retrySignals = append(retrySignals, sig)
+						close(terminated)
+						return nil
+					}
+					return mockRetryCommand, nil
+				}
+				service.TaskRunner.(*mocks.TaskRunner).MockNewCommand = newCommand
+
+				service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(_ io.Reader) (
+					*v1.TestResults,
+					error,
+				) {
+					parseCount++
+
+					tests := []v1.Test{
+						{
+							ID:       &firstTestDescription,
+							Name:     firstTestDescription,
+							Location: &v1.Location{File: "/path/to/file.test"},
+							Attempt:  v1.TestAttempt{Status: v1.NewFailedTestStatus(nil, nil, nil)},
+						},
+					}
+
+					if parseCount == 1 {
+						tests = append(tests, v1.Test{
+							ID:       &secondTestDescription,
+							Name:     secondTestDescription,
+							Location: &v1.Location{File: "/path/to/file.test"},
+							Attempt:  v1.TestAttempt{Status: v1.NewFailedTestStatus(nil, nil, nil)},
+						})
+					}
+
+					return &v1.TestResults{Framework: v1.RubyRSpecFramework, Tests: tests}, nil
+				}
+			})
+
+			It("terminates the retry command", func() {
+				Expect(retrySignals).To(Equal([]os.Signal{syscall.SIGTERM}))
+			})
+
+			It("marks the tests that did not report a result as timed out", func() {
+				Expect(err).To(HaveOccurred())
+
+				Expect(uploadedTestResults).ToNot(BeNil())
+				Expect(uploadedTestResults.Summary.Tests).To(Equal(2))
+				Expect(uploadedTestResults.Summary.TimedOut).To(Equal(1))
+
+				Expect(uploadedTestResults.Tests[0].Attempt.Status.Kind).To(Equal(v1.TestStatusFailed))
+				Expect(uploadedTestResults.Tests[1].Attempt.Status.Kind).To(Equal(v1.TestStatusTimedOut))
+				Expect(*uploadedTestResults.Tests[1].Attempt.Status.Message).To(
+					ContainSubstring("The command exceeded the attempt timeout of 50ms"),
+				)
+				Expect(uploadedTestResults.Tests[1].PastAttempts).To(HaveLen(1))
+				Expect(uploadedTestResults.Tests[1].PastAttempts[0].Status.Kind).To(Equal(v1.TestStatusFailed))
+			})
+		})
+
+		Context("when the run timeout has passed before retrying", func() {
+			BeforeEach(func() {
+				runConfig.Retries = 3
+				runConfig.RunTimeout = 20 * time.Millisecond
+
+				mockCommand.MockWait = func() error {
+					time.Sleep(50 * time.Millisecond)
+					return nil
+				}
+				mockCommand.MockSignal = func(os.Signal) error {
+					return nil
+				}
+			})
+
+			It("does not retry", func() {
+				Expect(parseCount).To(Equal(1))
+				Expect(uploadedTestResults).ToNot(BeNil())
+				Expect(uploadedTestResults.Summary.Retries).To(Equal(0))
+			})
+
+			It("reports the timeout", func() {
+				Expect(err).To(HaveOccurred())
+				timeoutErr, ok := errors.AsTimeoutError(err)
+				Expect(ok).To(BeTrue(), "Error is a timeout error")
+				Expect(timeoutErr.Timeout).To(Equal(20 * time.Millisecond))
+
+				executionError, ok := errors.AsExecutionError(err)
+				Expect(ok).To(BeTrue(), "Error is an execution error")
+				Expect(executionError.Code).To(Equal(124))
+
+				Expect(uploadedTestResults.OtherErrors).To(HaveLen(1))
+				Expect(uploadedTestResults.OtherErrors[0].Message).To(
+					ContainSubstring("The test suite exceeded the run timeout of 20ms"),
+				)
+			})
+		})
+
+		Context("when Captain is interrupted during the original attempt", func() {
+			var (
+				forwardedSignals  []os.Signal
+				interruptsStopped bool
+			)
+
+			BeforeEach(func() {
+				runConfig.Retries = 3
+				forwardedSignals = nil
+				interruptsStopped = false
+
+				var interrupts chan<- os.Signal
+				service.TaskRunner.(*mocks.TaskRunner).MockNotifyInterrupts = func(signals chan<- os.Signal) (func(), error) {
+					interrupts = signals
+					return func() { interruptsStopped = true }, nil
+				}
+
+				stopped := make(chan struct{})
+				mockCommand.MockStart = func() error {
+					interrupts <- os.Interrupt
+					return nil
+				}
+				mockCommand.MockWait = func() error {
+					<-stopped
+					return errors.NewInternalError("signal: interrupt")
+				}
+				mockCommand.MockSignal = func(sig os.Signal) error {
+					forwardedSignals = append(forwardedSignals, sig)
+					close(stopped)
+					return nil
+				}
+			})
+
+			It("forwards the signal to the command", func() {
+				Expect(forwardedSignals).To(Equal([]os.Signal{os.Interrupt}))
+				Expect(interruptsStopped).To(BeTrue())
+			})
+
+			It("does not retry", func() {
+				Expect(parseCount).To(Equal(1))
+			})
+
+			It("still reports the results", func() {
+				Expect(err).To(HaveOccurred())
+				interruptErr, ok := errors.AsInterruptError(err)
+				Expect(ok).To(BeTrue(), "Error is an interrupt error")
+				Expect(interruptErr.Signal).To(Equal(os.Interrupt))
+
+				executionError, ok := errors.AsExecutionError(err)
+				Expect(ok).To(BeTrue(), "Error is an execution error")
+				Expect(executionError.Code).To(Equal(130))
+
+				Expect(uploadedTestResults).ToNot(BeNil())
+				Expect(uploadedTestResults.Summary.Tests).To(Equal(3))
+				Expect(uploadedTestResults.Summary.Retries).To(Equal(0))
+				Expect(uploadedTestResults.OtherErrors).To(HaveLen(1))
+				Expect(uploadedTestResults.OtherErrors[0].Message).To(ContainSubstring("Captain was interrupted (interrupt)"))
+			})
+		})
+
+		Context("when Captain is interrupted during a retry", func() {
+			var forwardedSignals []os.Signal
+
+			BeforeEach(func() {
+				runConfig.Retries = 3
+				forwardedSignals = nil
+
+				var interrupts chan<- os.Signal
+				service.TaskRunner.(*mocks.TaskRunner).MockNotifyInterrupts = func(signals chan<- os.Signal) (func(), error) {
+					interrupts = signals
+					return func() {}, nil
+				}
+
+				newCommand := func(_ context.Context, cfg exec.CommandConfig) (exec.Command, error) {
+					if cfg.Name != "retry" {
+						return mockCommand, nil
+					}
+
+					stopped := make(chan struct{})
+					mockRetryCommand := new(mocks.Command)
+					mockRetryCommand.MockStart = func() error {
+						interrupts <- syscall.SIGTERM
+						return nil
+					}
+					mockRetryCommand.MockWait = func() error {
+						<-stopped
+						return errors.NewInternalError("signal: terminated")
+					}
+					mockRetryCommand.MockSignal = func(sig os.Signal) error {
+						forwardedSignals = append(forwardedSignals, sig)
+						close(stopped)
+						return nil
+					}
+					return mockRetryCommand, nil
+				}
+				service.TaskRunner.(*mocks.TaskRunner).MockNewCommand = newCommand
+
+				service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(_ io.Reader) (
+					*v1.TestResults,
+					error,
+				) {
+					parseCount++
+
+					tests := []v1.Test{
+						{
+							ID:       &firstTestDescription,
+							Name:     firstTestDescription,
+							Location: &v1.Location{File: "/path/to/file.test"},
+							Attempt:  v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()},
+						},
+					}
+
+					if parseCount == 1 {
+						tests[0].Attempt.Status = v1.NewFailedTestStatus(nil, nil, nil)
+						tests = append(tests, v1.Test{
+							ID:       &secondTestDescription,
+							Name:     secondTestDescription,
+							Location: &v1.Location{File: "/path/to/file.test"},
+							Attempt:  v1.TestAttempt{Status: v1.NewFailedTestStatus(nil, nil, nil)},
+						})
+					}
+
+					return &v1.TestResults{Framework: v1.RubyRSpecFramework, Tests: tests}, nil
+				}
+			})
+
+			It("forwards the signal to the retry command and stops retrying", func() {
+				Expect(forwardedSignals).To(Equal([]os.Signal{syscall.SIGTERM}))
+				Expect(parseCount).To(Equal(2))
+			})
+
+			It("marks the tests that did not report a result as canceled", func() {
+				Expect(err).To(HaveOccurred())
+
+				Expect(uploadedTestResults).ToNot(BeNil())
+				Expect(uploadedTestResults.Summary.Tests).To(Equal(2))
+				Expect(uploadedTestResults.Summary.Canceled).To(Equal(1))
+
+				Expect(uploadedTestResults.Tests[0].Attempt.Status.Kind).To(Equal(v1.TestStatusSuccessful))
+				Expect(uploadedTestResults.Tests[1].Attempt.Status.Kind).To(Equal(v1.TestStatusCanceled))
+				Expect(uploadedTestResults.Tests[1].Attempt.Meta).To(HaveKeyWithValue("signal", "terminated"))
+				Expect(uploadedTestResults.Tests[1].PastAttempts).To(HaveLen(1))
+				Expect(uploadedTestResults.Tests[1].PastAttempts[0].Status.Kind).To(Equal(v1.TestStatusFailed))
+			})
+		})
+
+		Context("when retry commands run concurrently", func() {
+			var (
+				globbedPaths       []string
+				specsByCommandID   map[string]string
+				ranConcurrently    bool
+				commandIDs         []string
+				retryCommandsMutex sync.Mutex
+			)
+
+			BeforeEach(func() {
+				globbedPaths = nil
+				specsByCommandID = map[string]string{}
+				ranConcurrently = true
+				commandIDs = nil
+
+				runConfig.Retries = 1
+				runConfig.RetryConcurrency = 2
+				runConfig.TestResultsFileGlob = "results-${CAPTAIN_RETRY_COMMAND_ID}.json"
+				runConfig.RetryCommandTemplate = "retry {{ spec }}"
+				runConfig.SubstitutionsByFramework = map[v1.Framework]targetedretries.Substitution{
+					v1.JavaScriptCypressFramework: new(targetedretries.JavaScriptCypressSubstitution),
+				}
+
+				service.FileSystem.(*mocks.FileSystem).MockGlob = func(pattern string) ([]string, error) {
+					retryCommandsMutex.Lock()
+					defer retryCommandsMutex.Unlock()
+
+					globbedPaths = append(globbedPaths, pattern)
+					return []string{pattern}, nil
+				}
+				service.FileSystem.(*mocks.FileSystem).MockOpen = func(name string) (fs.File, error) {
+					file := new(mocks.File)
+					file.Reader = strings.NewReader(name)
+					return file, nil
+				}
+
+				var bothStarted sync.WaitGroup
+				bothStarted.Add(2)
+
+				newCommand := func(_ context.Context, cfg exec.CommandConfig) (exec.Command, error) {
+					if cfg.Name != "retry" {
+						return mockCommand, nil
+					}
+
+					retryCommandsMutex.Lock()
+					defer retryCommandsMutex.Unlock()
+
+					commandID := ""
+					for _, env := range cfg.Env {
+						if id, ok := strings.CutPrefix(env, "CAPTAIN_RETRY_COMMAND_ID="); ok {
+							commandID = id
+						}
+					}
+					commandIDs = append(commandIDs, commandID)
+					specsByCommandID[commandID] = cfg.Args[0]
+
+					mockRetryCommand := new(mocks.Command)
+					mockRetryCommand.MockStart = func() error {
+						bothStarted.Done()
+						return nil
+					}
+					mockRetryCommand.MockWait = func() error {
+						started := make(chan struct{})
+						go func() {
+							bothStarted.Wait()
+							close(started)
+						}()
+
+						select {
+						case <-started:
+						case <-time.After(time.Second):
+							retryCommandsMutex.Lock()
+							ranConcurrently = false
+							retryCommandsMutex.Unlock()
+						}
+
+						return nil
+					}
+					return mockRetryCommand, nil
+				}
+				service.TaskRunner.(*mocks.TaskRunner).MockNewCommand = newCommand
+
+				service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(r io.Reader) (
+					*v1.TestResults,
+					error,
+				) {
+					content, err := io.ReadAll(r)
+					Expect(err).NotTo(HaveOccurred())
+
+					firstTest := v1.Test{
+						ID:       &firstTestDescription,
+						Name:     firstTestDescription,
+						Location: &v1.Location{File: "cypress/first.cy.js"},
+						Attempt:  v1.TestAttempt{Status: v1.NewFailedTestStatus(nil, nil, nil)},
+					}
+					secondTest := v1.Test{
+						ID:       &secondTestDescription,
+						Name:     secondTestDescription,
+						Location: &v1.Location{File: "cypress/second.cy.js"},
+						Attempt:  v1.TestAttempt{Status: v1.NewFailedTestStatus(nil, nil, nil)},
+					}
+
+					commandID := strings.TrimSuffix(strings.TrimPrefix(string(content), "results-"), ".json")
+					if commandID == "" {
+						return &v1.TestResults{
+							Framework: v1.JavaScriptCypressFramework,
+							Tests:     []v1.Test{firstTest, secondTest},
+						}, nil
+					}
+
+					retryCommandsMutex.Lock()
+					spec := specsByCommandID[commandID]
+					retryCommandsMutex.Unlock()
+
+					retriedTest := firstTest
+					if spec == secondTest.Location.File {
+						retriedTest = secondTest
+					}
+					retriedTest.Attempt.Status = v1.NewSuccessfulTestStatus()
+
+					return &v1.TestResults{Framework: v1.JavaScriptCypressFramework, Tests: []v1.Test{retriedTest}}, nil
+				}
+			})
+
+			It("runs the retry commands at the same time", func() {
+				Expect(ranConcurrently).To(BeTrue())
+				Expect(commandIDs).To(ConsistOf("1-1", "1-2"))
+			})
+
+			It("reads the test results of every command from its own path", func() {
+				Expect(globbedPaths).To(ConsistOf("results-.json", "results-1-1.json", "results-1-2.json"))
+			})
+
+			It("merges the results of all commands", func() {
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(uploadedTestResults).ToNot(BeNil())
+				Expect(uploadedTestResults.Summary.Tests).To(Equal(2))
+				Expect(uploadedTestResults.Summary.Successful).To(Equal(2))
+				Expect(uploadedTestResults.Summary.Retries).To(Equal(2))
+
+				Expect(uploadedTestResults.Tests[0].Name).To(Equal(firstTestDescription))
+				Expect(uploadedTestResults.Tests[0].Attempt.Status.Kind).To(Equal(v1.TestStatusSuccessful))
+				Expect(uploadedTestResults.Tests[1].Name).To(Equal(secondTestDescription))
+				Expect(uploadedTestResults.Tests[1].Attempt.Status.Kind).To(Equal(v1.TestStatusSuccessful))
+			})
+
+			Context("with pre- and post-retry commands", func() {
+				var commandNames []string
+
+				BeforeEach(func() {
+					commandNames = nil
+					runConfig.PreRetryCommands = []string{"reset-database"}
+					runConfig.PostRetryCommands = []string{"collect-logs"}
+
+					newCommand := service.TaskRunner.(*mocks.TaskRunner).MockNewCommand
+					service.TaskRunner.(*mocks.TaskRunner).MockNewCommand = func(
+						ctx context.Context,
+						cfg exec.CommandConfig,
+					) (exec.Command, error) {
+						retryCommandsMutex.Lock()
+						commandNames = append(commandNames, cfg.Name)
+						retryCommandsMutex.Unlock()
+
+						if cfg.Name == "reset-database" || cfg.Name == "collect-logs" {
+							return &mocks.Command{
+								MockStart: func() error { return nil },
+								MockWait:  func() error { return nil },
+							}, nil
+						}
+
+						return newCommand(ctx, cfg)
+					}
+				})
+
+				It("runs them once for the whole retry", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ranConcurrently).To(BeTrue())
+					Expect(commandNames).To(HaveLen(5))
+					Expect(commandNames[1]).To(Equal("reset-database"))
+					Expect(commandNames[2:4]).To(ConsistOf("retry", "retry"))
+					Expect(commandNames[4]).To(Equal("collect-logs"))
+				})
+			})
+		})
+
+		Context("when retries depend on the failure message", func() {
+			BeforeEach(func() {
+				runConfig.Retries = 1
+				runConfig.RetryOnlyIfMessageMatches = []string{"Timeout"}
+				runConfig.RetryNeverIfMessageMatches = []string{"^expected"}
+
+				timeoutMessage := "Net::ReadTimeout"
+				assertionMessage := "expected 1 to eq 2"
+				otherException := "NoMethodError"
+
+				service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(_ io.Reader) (
+					*v1.TestResults,
+					error,
+				) {
+					parseCount++
+
+					firstTest := v1.Test{
+						ID:       &firstTestDescription,
+						Name:     firstTestDescription,
+						Location: &v1.Location{File: "/path/to/file.test"},
+						Attempt:  v1.TestAttempt{Status: v1.NewFailedTestStatus(&timeoutMessage, nil, nil)},
+					}
+
+					if parseCount > 1 {
+						firstTest.Attempt.Status = v1.NewSuccessfulTestStatus()
+						return &v1.TestResults{Framework: v1.RubyRSpecFramework, Tests: []v1.Test{firstTest}}, nil
+					}
+
+					return &v1.TestResults{
+						Framework: v1.RubyRSpecFramework,
+						Tests: []v1.Test{
+							firstTest,
+							{
+								ID:       &secondTestDescription,
+								Name:     secondTestDescription,
+								Location: &v1.Location{File: "/path/to/file.test"},
+								Attempt: v1.TestAttempt{
+									Status: v1.NewFailedTestStatus(&assertionMessage, nil, []string{"Timeout.rb:1"}),
+								},
+							},
+							{
+								ID:       &thirdTestDescription,
+								Name:     thirdTestDescription,
+								Location: &v1.Location{File: "/other/path/to/file.test"},
+								Attempt:  v1.TestAttempt{Status: v1.NewFailedTestStatus(nil, &otherException, nil)},
+							},
+						},
+					}, nil
+				}
+			})
+
+			It("only retries the eligible tests", func() {
+				Expect(err).To(HaveOccurred())
+
+				Expect(uploadedTestResults).ToNot(BeNil())
+				Expect(uploadedTestResults.Summary.Tests).To(Equal(3))
+				Expect(uploadedTestResults.Summary.Successful).To(Equal(1))
+				Expect(uploadedTestResults.Summary.Failed).To(Equal(2))
+				Expect(uploadedTestResults.Summary.Retries).To(Equal(1))
+
+				Expect(uploadedTestResults.Tests[0].Attempt.Status.Kind).To(Equal(v1.TestStatusSuccessful))
+				Expect(uploadedTestResults.Tests[0].PastAttempts).To(HaveLen(1))
+				Expect(uploadedTestResults.Tests[1].PastAttempts).To(HaveLen(0))
+				Expect(uploadedTestResults.Tests[2].PastAttempts).To(HaveLen(0))
+			})
+
+			It("records the retry eligibility of the failed tests", func() {
+				Expect(uploadedTestResults).ToNot(BeNil())
+
+				Expect(uploadedTestResults.Tests[0].PastAttempts[0].Meta).To(HaveKeyWithValue(
+					"captain_retry_eligibility",
+					map[string]any{
+						"eligible": true,
+						"reason":   `The failure matches the only-if-message-matches pattern "Timeout"`,
+					},
+				))
+				Expect(uploadedTestResults.Tests[1].Attempt.Meta).To(HaveKeyWithValue(
+					"captain_retry_eligibility",
+					map[string]any{
+						"eligible": false,
+						"reason":   `The failure matches the never-if-message-matches pattern "^expected"`,
+					},
+				))
+				Expect(uploadedTestResults.Tests[2].Attempt.Meta).To(HaveKeyWithValue(
+					"captain_retry_eligibility",
+					map[string]any{
+						"eligible": false,
+						"reason":   "The failure does not match any only-if-message-matches pattern",
+					},
+				))
+			})
+
+			Context("without retries", func() {
+				BeforeEach(func() {
+					runConfig.Retries = 0
+				})
+
+				It("still records the retry eligibility of the failed tests", func() {
+					Expect(uploadedTestResults).ToNot(BeNil())
+					Expect(uploadedTestResults.Summary.Retries).To(Equal(0))
+
+					Expect(uploadedTestResults.Tests[0].Attempt.Meta).To(HaveKeyWithValue(
+						"captain_retry_eligibility",
+						map[string]any{
+							"eligible": true,
+							"reason":   `The failure matches the only-if-message-matches pattern "Timeout"`,
+						},
+					))
+					Expect(uploadedTestResults.Tests[1].Attempt.Meta).To(HaveKey("captain_retry_eligibility"))
+					Expect(uploadedTestResults.Tests[2].Attempt.Meta).To(HaveKey("captain_retry_eligibility"))
+				})
+			})
+		})
+
+		Context("when doing a dry run", func() {
+			var commandsCreated []string
+			var temporaryDirectoriesCreated int
+
+			BeforeEach(func() {
+				commandsCreated = make([]string, 0)
+				temporaryDirectoriesCreated = 0
+				runConfig.DryRun = true
+				service.FileSystem.(*mocks.FileSystem).MockMkdirTemp = func(_, _ string) (string, error) {
+					temporaryDirectoriesCreated++
+					return "/tmp/captain-test", nil
+				}
+				runConfig.DryRunResultsPath = "previous-results.json"
+				service.FileSystem.(*mocks.FileSystem).MockRemoveAll = func(string) error {
+					return nil
+				}
+
+				service.TaskRunner.(*mocks.TaskRunner).MockNewCommand = func(
+					_ context.Context,
+					cfg exec.CommandConfig,
+				) (exec.Command, error) {
+					commandsCreated = append(commandsCreated, cfg.Name)
+					return mockCommand, nil
+				}
+
+				service.FileSystem.(*mocks.FileSystem).MockOpen = func(name string) (fs.File, error) {
+					Expect(name).To(Equal("previous-results.json"))
+
+					testResults := v1.NewTestResults(v1.RubyRSpecFramework, []v1.Test{
+						{
+							ID:       &firstTestDescription,
+							Name:     firstTestDescription,
+							Location: &v1.Location{File: "/path/to/file.test"},
+							Attempt:  v1.TestAttempt{Status: v1.NewFailedTestStatus(nil, nil, nil)},
+						},
+						{
+							ID:       &secondTestDescription,
+							Name:     secondTestDescription,
+							Location: &v1.Location{File: "/path/to/file.test"},
+							Attempt:  v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()},
+						},
+					}, nil)
+					encoded, err := json.Marshal(testResults)
+					Expect(err).NotTo(HaveOccurred())
+
+					file := new(mocks.File)
+					file.Reader = strings.NewReader(string(encoded))
+					return file, nil
+				}
+			})
+
+			It("does not run any commands", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(commandsCreated).To(BeEmpty())
+				Expect(commandStarted).To(BeFalse())
+				Expect(testResultsFileUploaded).To(BeFalse())
+			})
+
+			It("does not create any storage for the artifacts of retries", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(temporaryDirectoriesCreated).To(Equal(0))
+			})
+
+			It("prints the command and the retry commands that would run", func() {
+				logMessages := make([]string, 0)
+				for _, log := range recordedLogs.All() {
+					logMessages = append(logMessages, log.Message)
+				}
+
+				Expect(logMessages).To(ContainElement(fmt.Sprintf(
+					"Captain would run:\n  %v\n\nThe first retry would run 1 command:\n  retry %v\n    - tests: '%v'",
+					arg,
+					firstTestDescription,
+					firstTestDescription,
+				)))
+			})
+
+			Context("with the JSON format", func() {
+				BeforeEach(func() {
+					runConfig.DryRunFormat = "json"
+				})
+
+				It("prints the plan as JSON", func() {
+					logMessages := make([]string, 0)
+					for _, log := range recordedLogs.All() {
+						logMessages = append(logMessages, log.Message)
+					}
+
+					Expect(logMessages).To(ContainElement(MatchJSON(fmt.Sprintf(`{
+						"command": [%q],
+						"retries": [{"command": ["retry", %q], "substitutions": {"tests": "'%v'"}}]
+					}`, arg, firstTestDescription, firstTestDescription))))
+				})
+			})
+
+			Context("without previous test results", func() {
+				BeforeEach(func() {
+					runConfig.DryRunResultsPath = ""
+				})
+
+				It("only prints the command", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(commandsCreated).To(BeEmpty())
+
+					logMessages := make([]string, 0)
+					for _, log := range recordedLogs.All() {
+						logMessages = append(logMessages, log.Message)
+					}
+					Expect(logMessages).To(ContainElement(fmt.Sprintf("Captain would run:\n  %v", arg)))
+				})
+			})
+		})
+
+		Context("when retrying in batches", func() {
+			var retryCommands [][]string
+
+			BeforeEach(func() {
+				retryCommands = make([][]string, 0)
+				runConfig.RetryBatchSize = 2
+
+				service.TaskRunner.(*mocks.TaskRunner).MockNewCommand = func(
+					_ context.Context,
+					cfg exec.CommandConfig,
+				) (exec.Command, error) {
+					if cfg.Name == "retry" {
+						retryCommands = append(retryCommands, cfg.Args)
+					}
+					return mockCommand, nil
+				}
+			})
+
+			It("runs one retry command per batch of tests", func() {
+				Expect(retryCommands).To(Equal([][]string{
+					{firstTestDescription, secondTestDescription},
+					{thirdTestDescription},
+				}))
+
+				Expect(uploadedTestResults).ToNot(BeNil())
+				Expect(uploadedTestResults.Summary.Tests).To(Equal(3))
+				Expect(uploadedTestResults.Summary.Retries).To(Equal(3))
+			})
+
+			It("prints which command of the retry is running", func() {
+				logMessages := make([]string, 0)
+				for _, log := range recordedLogs.All() {
+					logMessages = append(logMessages, log.Message)
+				}
+
+				Expect(logMessages).To(ContainElement(ContainSubstring("- Retry 1 of 1, command 1 of 2")))
+				Expect(logMessages).To(ContainElement(ContainSubstring("- Retry 1 of 1, command 2 of 2")))
+			})
+		})
+
+		Context("when repeating the tests", func() {
+			var retryCommands []string
+
+			BeforeEach(func() {
+				retryCommands = make([]string, 0)
+				runConfig.Retries = 0
+				runConfig.Repeat = 3
+
+				service.TaskRunner.(*mocks.TaskRunner).MockNewCommand = func(
+					_ context.Context,
+					cfg exec.CommandConfig,
+				) (exec.Command, error) {
+					if cfg.Name == "retry" {
+						retryCommands = append(retryCommands, strings.Join(cfg.Args, " "))
+					}
+					return mockCommand, nil
+				}
+
+				mockCommand.MockWait = func() error {
+					return nil
+				}
+
+				service.ParseConfig.MutuallyExclusiveParsers[0].(*mocks.Parser).MockParse = func(_ io.Reader) (
+					*v1.TestResults,
+					error,
+				) {
+					parseCount++
+					message := fmt.Sprintf("expected run %d to pass", parseCount)
+
+					secondStatus := v1.NewSuccessfulTestStatus()
+					if parseCount == 3 {
+						secondStatus = v1.NewFailedTestStatus(&message, nil, nil)
+					}
+
+					tests := []v1.Test{
+						{
+							ID:       &firstTestDescription,
+							Name:     firstTestDescription,
+							Location: &v1.Location{File: "/path/to/file.test"},
+							Attempt:  v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()},
+						},
+						{
+							ID:       &secondTestDescription,
+							Name:     secondTestDescription,
+							Location: &v1.Location{File: "/path/to/file.test"},
+							Attempt:  v1.TestAttempt{Status: secondStatus},
+						},
+					}
+
+					if parseCount == 1 {
+						tests = append(tests, v1.Test{
+							ID:       &thirdTestDescription,
+							Name:     thirdTestDescription,
+							Location: &v1.Location{File: "/other/path/to/file.test"},
+							Attempt:  v1.TestAttempt{Status: v1.NewSkippedTestStatus(nil)},
+						})
+					}
+
+					return &v1.TestResults{Framework: v1.RubyRSpecFramework, Tests: tests}, nil
+				}
+			})
+
+			It("runs every test that wasn't skipped the configured number of times", func() {
+				Expect(retryCommands).To(HaveLen(2))
+				Expect(retryCommands[0]).To(ContainSubstring(firstTestDescription))
+				Expect(retryCommands[0]).To(ContainSubstring(secondTestDescription))
+				Expect(retryCommands[0]).NotTo(ContainSubstring(thirdTestDescription))
+
+				Expect(uploadedTestResults).ToNot(BeNil())
+				Expect(uploadedTestResults.Tests[0].Attempt.Status.Kind).To(Equal(v1.TestStatusSuccessful))
+				Expect(uploadedTestResults.Tests[0].PastAttempts).To(HaveLen(2))
+				Expect(uploadedTestResults.Tests[1].Attempt.Status.Kind).To(Equal(v1.TestStatusSuccessful))
+				Expect(uploadedTestResults.Tests[1].PastAttempts).To(HaveLen(2))
+				Expect(uploadedTestResults.Tests[1].Flaky()).To(BeTrue())
+				Expect(uploadedTestResults.Tests[2].PastAttempts).To(HaveLen(0))
+			})
+
+			It("fails when a test didn't pass in every run", func() {
+				Expect(err).To(HaveOccurred())
+
+				executionErr, ok := errors.AsExecutionError(err)
+				Expect(ok).To(BeTrue())
+				Expect(executionErr.Code).To(Equal(1))
+			})
+
+			It("prints a flakiness report", func() {
+				logMessages := make([]string, 0)
+				for _, log := range recordedLogs.All() {
+					logMessages = append(logMessages, log.Message)
+				}
+
+				Expect(logMessages).To(ContainElement(ContainSubstring("- Repeat 1 of 2")))
+				Expect(logMessages).To(ContainElement(ContainSubstring("- Repeat 2 of 2")))
+				Expect(logMessages).To(ContainElement(And(
+					ContainSubstring(fmt.Sprintf("- %s: passed 2 of 3 runs (67%%)", secondTestDescription)),
+					ContainSubstring("1x expected run 3 to pass"),
+					ContainSubstring("1 of 2 tests passed in every run, 1 did not"),
+				)))
+			})
+
+			Context("when every test passes in every run", func() {
+				BeforeEach(func() {
+					runConfig.Repeat = 2
+				})
+
+				It("succeeds", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(retryCommands).To(HaveLen(1))
+				})
+			})
+		})
+
 		Context("when quarantining is set up", func() {
 			BeforeEach(func() {
 				runConfig.Retries = 1
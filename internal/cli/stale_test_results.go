@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"os"
+	"time"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+)
+
+// staleTestResultsTolerance accounts for file systems that store modification times with a coarse resolution, e.g. of
+// one or two seconds.
+const staleTestResultsTolerance = 2 * time.Second
+
+// withoutStaleTestResultsFiles drops the test results files that were last modified before the command started. They
+// are usually left over from an earlier run in the same workspace and would hide failures or report phantom ones.
+// Files that can't be inspected are kept, the parser will report any problems with them.
+func (s Service) withoutStaleTestResultsFiles(testResultsFiles []string, startedAt time.Time) []string {
+	if startedAt.IsZero() {
+		return testResultsFiles
+	}
+
+	freshTestResultsFiles := make([]string, 0, len(testResultsFiles))
+	for _, testResultsFile := range testResultsFiles {
+		info, err := s.FileSystem.Stat(testResultsFile)
+		if err != nil {
+			s.Log.Debugf("Unable to determine when %q was last modified: %s", testResultsFile, err.Error())
+			freshTestResultsFiles = append(freshTestResultsFiles, testResultsFile)
+			continue
+		}
+
+		if info.ModTime().Before(startedAt.Add(-staleTestResultsTolerance)) {
+			s.Log.Warnf(
+				"Ignoring %q as it was last modified at %s, before the command started. It is likely left over from "+
+					"an earlier run, use --delete-stale-test-results to delete such files before running the tests.",
+				testResultsFile,
+				info.ModTime().Format(time.RFC3339),
+			)
+			continue
+		}
+
+		freshTestResultsFiles = append(freshTestResultsFiles, testResultsFile)
+	}
+
+	return freshTestResultsFiles
+}
+
+// deleteStaleTestResultsFiles deletes any files matching the test results path before the tests run, so that only
+// test results of this run can be picked up.
+func (s Service) deleteStaleTestResultsFiles(cfg RunConfig) error {
+	if cfg.TestResultsFileGlob == "" {
+		return nil
+	}
+
+	testResultsFileGlob := expandRetryCommandID(cfg.TestResultsFileGlob, os.Getenv(RetryCommandIDEnvVar))
+	if cfg.Parallel > 1 {
+		testResultsFileGlob = expandWorkerIndex(testResultsFileGlob, "*")
+	}
+	testResultsFiles, err := s.FileSystem.Glob(testResultsFileGlob)
+	if err != nil {
+		return errors.NewSystemError("unable to expand filepath glob: %s", err)
+	}
+
+	for _, testResultsFile := range testResultsFiles {
+		if err := s.FileSystem.Remove(testResultsFile); err != nil {
+			return errors.NewSystemError("unable to delete stale test results file %q: %s", testResultsFile, err)
+		}
+
+		s.Log.Debugf("Deleted stale test results file %q", testResultsFile)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// testResultsStore is implemented by backends that store the test results of the previous run. Only the local backend
+// does so, the number of tests of recent runs is unknown otherwise and --max-test-drop-percent is rejected.
+type testResultsStore interface {
+	GetTestResults(ctx context.Context, suiteID string) (*v1.TestResults, error)
+}
+
+const testCountResolution = "Check the test suite for focused tests (e.g. '.only' or 'fit') and make sure that the " +
+	"test results path matches all test results files. If tests were removed on purpose, adjust --min-tests or " +
+	"--max-test-drop-percent."
+
+// checkTestCount fails the run when fewer tests ran than expected or when a test results file doesn't contain any
+// tests. Focused tests or a test results path that misses some of the test results files otherwise go unnoticed, as
+// the tests that did run pass.
+func (s Service) checkTestCount(
+	cfg RunConfig,
+	testResults *v1.TestResults,
+	expectedTestCount int,
+	emptyTestResultsFiles []string,
+) error {
+	if len(emptyTestResultsFiles) > 0 {
+		quotedPaths := make([]string, len(emptyTestResultsFiles))
+		for i, path := range emptyTestResultsFiles {
+			quotedPaths[i] = fmt.Sprintf("%q", path)
+		}
+
+		return errors.NewConfigurationError(
+			"Test results without any tests",
+			fmt.Sprintf(
+				"The test results %s %s %s not contain any tests.",
+				pluralize(len(quotedPaths), "file", "files"),
+				strings.Join(quotedPaths, ", "),
+				pluralize(len(quotedPaths), "does", "do"),
+			),
+			testCountResolution,
+		)
+	}
+
+	if !cfg.guardsTestCount() {
+		return nil
+	}
+
+	testCount := 0
+	if testResults != nil {
+		testCount = testResults.Summary.Tests
+	}
+
+	if testCount < cfg.MinTests {
+		return errors.NewConfigurationError(
+			"Fewer tests than expected",
+			fmt.Sprintf(
+				"The test suite reported %d %s, but at least %d are expected to run.",
+				testCount,
+				pluralize(testCount, "test", "tests"),
+				cfg.MinTests,
+			),
+			testCountResolution,
+		)
+	}
+
+	if cfg.MaxTestDropPercent == nil {
+		return nil
+	}
+
+	// Recent runs may have run every partition, so their test count isn't comparable
+	if cfg.IsRunningPartition() || cfg.QueueURL != "" {
+		s.Log.Debugf("Not comparing the number of tests with recent runs, as only a partition of the tests ran")
+		return nil
+	}
+
+	if expectedTestCount == 0 {
+		s.Log.Debugf("Not comparing the number of tests with the previous run, as no test results of it are stored")
+		return nil
+	}
+
+	dropPercent := float64(expectedTestCount-testCount) / float64(expectedTestCount) * 100
+	if dropPercent > *cfg.MaxTestDropPercent {
+		return errors.NewConfigurationError(
+			"Fewer tests than expected",
+			fmt.Sprintf(
+				"The test suite reported %d %s, %.1f%% fewer than the %d tests of recent runs. The number of tests is "+
+					"allowed to drop by %v%% at most.",
+				testCount,
+				pluralize(testCount, "test", "tests"),
+				dropPercent,
+				expectedTestCount,
+				*cfg.MaxTestDropPercent,
+			),
+			testCountResolution,
+		)
+	}
+
+	return nil
+}
+
+// expectedTestCount returns the number of tests of the previous run, which is only known when the backend stores test
+// results. It needs to be read before the test results of this run are stored.
+func (s Service) expectedTestCount(ctx context.Context, cfg RunConfig) (int, error) {
+	if cfg.MaxTestDropPercent == nil {
+		return 0, nil
+	}
+
+	store, ok := s.API.(testResultsStore)
+	if !ok {
+		return 0, errors.NewConfigurationError(
+			"Unsupported option --max-test-drop-percent",
+			"Captain can only compare the number of tests with the previous run when using the local backend, as "+
+				"Captain Cloud does not provide the number of tests of recent runs.",
+			"Please remove --max-test-drop-percent (or 'results.max-test-drop-percent' in your configuration file) or "+
+				"use --min-tests instead.",
+		)
+	}
+
+	testResults, err := store.GetTestResults(ctx, cfg.SuiteID)
+	if err != nil {
+		s.Log.Debugf("Unable to read the stored test results: %s", err.Error())
+		return 0, nil
+	}
+
+	return testResults.Summary.Tests, nil
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/exec"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// defaultTerminationGracePeriod is how long Captain waits for a timed out command to exit after sending it SIGTERM,
+// before killing it forcefully.
+const defaultTerminationGracePeriod = 10 * time.Second
+
+// timeoutExitCode is the exit code Captain reports for commands it terminated due to a timeout. It matches the exit
+// code of the coreutils `timeout` command.
+const timeoutExitCode = 124
+
+// withTimeout returns a context that is canceled with a TimeoutError once the timeout has passed. A timeout of 0
+// disables the timeout altogether.
+func withTimeout(
+	ctx context.Context,
+	timeout time.Duration,
+	msg string,
+	a ...any,
+) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeoutCause(ctx, timeout, errors.NewTimeoutError(timeout, timeoutExitCode, msg, a...))
+}
+
+// waitForCommand waits for the command to exit. If the context is done before that, the command is asked to terminate
+// with SIGTERM (or the interrupt Captain received) and is killed once the grace period has passed.
+func (s Service) waitForCommand(ctx context.Context, cmd exec.Command, gracePeriod time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	if gracePeriod <= 0 {
+		gracePeriod = defaultTerminationGracePeriod
+	}
+
+	// Interrupts are forwarded as-is, anything else asks the command to terminate
+	var sig os.Signal = syscall.SIGTERM
+	if interruptErr, ok := errors.AsInterruptError(context.Cause(ctx)); ok {
+		sig = interruptErr.Signal
+	}
+
+	s.Log.Warnf("%s. Stopping the command...", context.Cause(ctx))
+	if err := cmd.Signal(sig); err != nil {
+		s.Log.Debugf("Unable to send %s to the command: %s", sig, err.Error())
+	}
+
+	timer := time.NewTimer(gracePeriod)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+	}
+
+	s.Log.Warnf("The command did not exit within %s after being stopped. Killing it...", gracePeriod)
+	if err := cmd.Signal(os.Kill); err != nil {
+		s.Log.Debugf("Unable to kill the command: %s", err.Error())
+	}
+
+	return <-done
+}
+
+// timeoutOtherError records a timeout of the original test command. Captain cannot know which tests were supposed to
+// run, so the timeout is reported as an error outside the test suite instead.
+func timeoutOtherError(timeoutErr errors.TimeoutError) v1.OtherError {
+	return v1.OtherError{
+		Message: fmt.Sprintf(
+			"%s. Captain terminated the test suite, any tests that did not report a result are missing.",
+			timeoutErr.Error(),
+		),
+		Meta: map[string]any{"timeout": timeoutErr.Timeout.String()},
+	}
+}
+
+// timedOutTest returns a copy of a test that was supposed to be retried, but didn't report a result before the retry
+// command was terminated.
+func timedOutTest(test v1.Test, timeoutErr errors.TimeoutError) v1.Test {
+	message := fmt.Sprintf("%s. Captain terminated the retry before this test reported a result.", timeoutErr.Error())
+
+	return v1.Test{
+		ID:       test.ID,
+		Name:     test.Name,
+		Scope:    test.Scope,
+		Lineage:  test.Lineage,
+		Location: test.Location,
+		Attempt: v1.TestAttempt{
+			Status: v1.NewTimedOutTestStatus(&message, nil, nil),
+		},
+	}
+}
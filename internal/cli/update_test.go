@@ -67,7 +67,7 @@ var _ = Describe("Update Captain", func() {
 	})
 
 	JustBeforeEach(func() {
-		api, err := local.NewClient(mockedFS, flakesPath, quarantinesPath, timingsPath)
+		api, err := local.NewClient(mockedFS, flakesPath, quarantinesPath, timingsPath, "", "", "", local.TimingHistory{})
 		Expect(err).NotTo(HaveOccurred())
 
 		service = cli.Service{
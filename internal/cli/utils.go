@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/rwx-research/captain-cli/internal/errors"
 	"github.com/rwx-research/captain-cli/internal/fs"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
 )
 
 const originalAttemptID = "original-attempt"
@@ -207,3 +209,19 @@ func (ias *IntermediateArtifactStorage) attemptScope() string {
 
 	return ias.retryID
 }
+
+// readTestResultsFile reads test results in the RWX v1 format, as written by the 'rwx-v1-json' reporter
+func (s Service) readTestResultsFile(path string) (*v1.TestResults, error) {
+	file, err := s.FileSystem.Open(path)
+	if err != nil {
+		return nil, errors.NewSystemError("unable to open file: %s", err)
+	}
+	defer file.Close()
+
+	var testResults v1.TestResults
+	if err := json.NewDecoder(file).Decode(&testResults); err != nil {
+		return nil, errors.NewInputError("Unable to parse %q as RWX v1 test results: %s", path, err)
+	}
+
+	return &testResults, nil
+}
@@ -1,12 +1,70 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+)
 
 type PartitionNodes struct {
 	Total int
 	Index int
+	// Weights are the relative capacities of the partition nodes, e.g. [1, 1, 4] if the third node is four times as
+	// fast as the others. Without weights, all nodes have the same capacity.
+	Weights []float64
 }
 
 func (pn PartitionNodes) String() string {
 	return fmt.Sprintf("%d/%d", pn.Index, pn.Total)
 }
+
+// IsWeighted returns whether the partition nodes have different capacities
+func (pn PartitionNodes) IsWeighted() bool {
+	return len(pn.Weights) > 0
+}
+
+// Weight returns the relative capacity of the partition node at the index
+func (pn PartitionNodes) Weight(index int) float64 {
+	if index < 0 || index >= len(pn.Weights) {
+		return 1
+	}
+
+	return pn.Weights[index]
+}
+
+// TotalWeight returns the sum of the capacities of all partition nodes
+func (pn PartitionNodes) TotalWeight() float64 {
+	total := 0.0
+	for i := 0; i < pn.Total; i++ {
+		total += pn.Weight(i)
+	}
+
+	return total
+}
+
+// ParsePartitionWeights parses a comma-separated list of partition node weights, e.g. "1,1,4"
+func ParsePartitionWeights(value string) ([]float64, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	fields := strings.Split(value, ",")
+	weights := make([]float64, len(fields))
+	for i, field := range fields {
+		weight, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || math.IsNaN(weight) || math.IsInf(weight, 0) || weight <= 0 {
+			return nil, errors.NewConfigurationError(
+				"Invalid partition weights",
+				fmt.Sprintf("Captain is unable to use %q as the weight of partition %d.", strings.TrimSpace(field), i),
+				"Please specify the weights as a comma-separated list of positive numbers, one per partition, e.g. 1,1,4",
+			)
+		}
+
+		weights[i] = weight
+	}
+
+	return weights, nil
+}
@@ -0,0 +1,36 @@
+package config_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rwx-research/captain-cli/internal/config"
+)
+
+var _ = Describe("ParsePartitionWeights", func() {
+	It("parses a comma-separated list of weights", func() {
+		weights, err := config.ParsePartitionWeights("1, 1,4.5")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(weights).To(Equal([]float64{1, 1, 4.5}))
+	})
+
+	It("returns no weights for an empty value", func() {
+		weights, err := config.ParsePartitionWeights(" ")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(weights).To(BeNil())
+	})
+
+	DescribeTable("rejects invalid weights",
+		func(value string) {
+			_, err := config.ParsePartitionWeights(value)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid partition weights"))
+		},
+		Entry("zero", "1,0"),
+		Entry("negative", "1,-2"),
+		Entry("not a number", "1,fast"),
+		Entry("NaN", "1,NaN"),
+		Entry("infinity", "1,+Inf"),
+		Entry("negative infinity", "-Inf,1"),
+	)
+})
@@ -2,7 +2,12 @@
 // This package ensures that all errors have a correct category & collect stack-traces.
 package errors
 
-import "github.com/pkg/errors"
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
 
 // ConfigurationError represent a configuration error. When used, it should ideally also point towards the configuration
 // value that caused this error to occur.
@@ -64,6 +69,60 @@ func AsExecutionError(err error) (ExecutionError, bool) {
 	return e, ok
 }
 
+// TimeoutError is returned when Captain terminated a sub-process because it exceeded a configured timeout. It wraps the
+// ExecutionError of the terminated process, so it can otherwise be handled like any other failed execution.
+type TimeoutError struct {
+	E       error
+	Timeout time.Duration
+}
+
+func (e TimeoutError) Error() string {
+	return e.E.Error()
+}
+
+func (e TimeoutError) Unwrap() error {
+	return e.E
+}
+
+// NewTimeoutError returns a new TimeoutError
+func NewTimeoutError(timeout time.Duration, code int, msg string, a ...any) error {
+	return WithStack(TimeoutError{E: ExecutionError{Code: code, E: errors.Errorf(msg, a...)}, Timeout: timeout})
+}
+
+// AsTimeoutError checks whether the error is a timeout error
+func AsTimeoutError(err error) (TimeoutError, bool) {
+	var e TimeoutError
+	ok := As(err, &e)
+	return e, ok
+}
+
+// InterruptError is returned when Captain terminated a sub-process because Captain itself received a signal (e.g.
+// Ctrl-C or a cancelled CI job). Like a TimeoutError, it wraps the ExecutionError of the terminated process.
+type InterruptError struct {
+	E      error
+	Signal os.Signal
+}
+
+func (e InterruptError) Error() string {
+	return e.E.Error()
+}
+
+func (e InterruptError) Unwrap() error {
+	return e.E
+}
+
+// NewInterruptError returns a new InterruptError
+func NewInterruptError(signal os.Signal, code int, msg string, a ...any) error {
+	return WithStack(InterruptError{E: ExecutionError{Code: code, E: errors.Errorf(msg, a...)}, Signal: signal})
+}
+
+// AsInterruptError checks whether the error is an interrupt error
+func AsInterruptError(err error) (InterruptError, bool) {
+	var e InterruptError
+	ok := As(err, &e)
+	return e, ok
+}
+
 // InputError is an error caused by user input
 type InputError struct {
 	E error
@@ -192,3 +251,25 @@ func AsDuplicateTestIDError(err error) (DuplicateTestIDError, bool) {
 	ok := As(err, &e)
 	return e, ok
 }
+
+// ShortCircuitError is returned when a test suite stopped before running its tests, e.g. because its partition
+// contained no test files. It isn't a failure, it's up to the caller to decide how to handle it.
+type ShortCircuitError struct {
+	E error
+}
+
+func (e ShortCircuitError) Error() string {
+	return e.E.Error()
+}
+
+// NewShortCircuitError returns a new ShortCircuitError
+func NewShortCircuitError(msg string, a ...any) error {
+	return WithStack(ShortCircuitError{errors.Errorf(msg, a...)})
+}
+
+// AsShortCircuitError checks whether the error is a short circuit error
+func AsShortCircuitError(err error) (ShortCircuitError, bool) {
+	var e ShortCircuitError
+	ok := As(err, &e)
+	return e, ok
+}
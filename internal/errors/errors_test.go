@@ -2,6 +2,8 @@ package errors_test
 
 import (
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/rwx-research/captain-cli/internal/errors"
 
@@ -46,6 +48,44 @@ var _ = Describe("Errors", func() {
 		})
 	})
 
+	Describe("TimeoutError", func() {
+		It("behaves like an execution error", func() {
+			err := errors.NewTimeoutError(time.Minute, 124, "some error %v", "some value")
+			Expect(err.Error()).To(Equal("some error some value"))
+			Expect(fmt.Sprintf("%+v", err)).To(ContainSubstring("/errors_test.go"))
+
+			timeoutErr, ok := errors.AsTimeoutError(err)
+
+			Expect(ok).To(Equal(true))
+			Expect(timeoutErr).To(Equal(errors.Unwrap(err)))
+			Expect(timeoutErr.Timeout).To(Equal(time.Minute))
+
+			executionErr, ok := errors.AsExecutionError(err)
+
+			Expect(ok).To(Equal(true))
+			Expect(executionErr.Code).To(Equal(124))
+		})
+	})
+
+	Describe("InterruptError", func() {
+		It("behaves like an execution error", func() {
+			err := errors.NewInterruptError(os.Interrupt, 130, "some error %v", "some value")
+			Expect(err.Error()).To(Equal("some error some value"))
+			Expect(fmt.Sprintf("%+v", err)).To(ContainSubstring("/errors_test.go"))
+
+			interruptErr, ok := errors.AsInterruptError(err)
+
+			Expect(ok).To(Equal(true))
+			Expect(interruptErr).To(Equal(errors.Unwrap(err)))
+			Expect(interruptErr.Signal).To(Equal(os.Interrupt))
+
+			executionErr, ok := errors.AsExecutionError(err)
+
+			Expect(ok).To(Equal(true))
+			Expect(executionErr.Code).To(Equal(130))
+		})
+	})
+
 	Describe("InputError", func() {
 		It("behaves like an error", func() {
 			err := errors.NewInputError("some error %v", "some value")
@@ -99,4 +139,21 @@ var _ = Describe("Errors", func() {
 			Expect(internalErr.E).To(BeNil())
 		})
 	})
+
+	Describe("ShortCircuitError", func() {
+		It("behaves like an error", func() {
+			err := errors.NewShortCircuitError("some error %v", "some value")
+			Expect(err.Error()).To(Equal("some error some value"))
+
+			shortCircuitErr, ok := errors.AsShortCircuitError(err)
+
+			Expect(ok).To(Equal(true))
+			Expect(shortCircuitErr).To(Equal(errors.Unwrap(err)))
+
+			executionErr, ok := errors.AsExecutionError(err)
+
+			Expect(ok).To(Equal(false))
+			Expect(executionErr.E).To(BeNil())
+		})
+	})
 })
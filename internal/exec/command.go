@@ -1,8 +1,11 @@
 package exec
 
+import "os"
+
 // Command is a generic interface that represents a command that is being executed. This is modelled after the default
 // `exec.Cmd` from the `os/exec` package.
 type Command interface {
+	Signal(os.Signal) error
 	Start() error
 	Wait() error
 }
@@ -9,4 +9,8 @@ type CommandConfig struct {
 	Name   string
 	Stderr io.Writer
 	Stdout io.Writer
+
+	// NewProcessGroup starts the command in its own process group, so that any signal sent to the command is also
+	// delivered to all processes spawned by it.
+	NewProcessGroup bool
 }
@@ -5,7 +5,10 @@ package exec
 
 import (
 	"context"
+	"os"
 	"os/exec"
+	"os/signal"
+	"syscall"
 
 	"github.com/rwx-research/captain-cli/internal/errors"
 )
@@ -13,6 +16,12 @@ import (
 // Local is a local executioner. It wraps `os/exec`
 type Local struct{}
 
+// localCommand is a command executed by the local task runner.
+type localCommand struct {
+	*exec.Cmd
+	processGroup bool
+}
+
 // NewCommand returns a new command that can then be executed.
 func (l Local) NewCommand(ctx context.Context, cfg CommandConfig) (Command, error) {
 	//nolint:gosec // Spawning a user-configurable sub-process is expected here.
@@ -25,7 +34,21 @@ func (l Local) NewCommand(ctx context.Context, cfg CommandConfig) (Command, erro
 		cmd.Env = append(cmd.Environ(), override)
 	}
 
-	return cmd, nil
+	if cfg.NewProcessGroup {
+		configureProcessGroup(cmd)
+	}
+
+	return &localCommand{Cmd: cmd, processGroup: cfg.NewProcessGroup}, nil
+}
+
+// Signal sends a signal to the command. If the command was started in its own process group, the signal is delivered
+// to the entire group.
+func (c *localCommand) Signal(sig os.Signal) error {
+	if c.Process == nil {
+		return errors.NewInternalError("Unable to signal a command that has not been started")
+	}
+
+	return errors.WithStack(signalProcess(c.Process, c.processGroup, sig))
 }
 
 // GetExitStatus extracts the exit code from an error
@@ -37,3 +60,11 @@ func (l Local) GetExitStatusFromError(err error) (int, error) {
 
 	return 0, errors.NewInternalError("Expected error to be of type exec.ExitError, received %T", err)
 }
+
+// NotifyInterrupts relays any SIGINT or SIGTERM received by Captain to the provided channel instead of terminating
+// Captain right away. Calling the returned function restores the default behavior.
+func (l Local) NotifyInterrupts(signals chan<- os.Signal) (func(), error) {
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	return func() { signal.Stop(signals) }, nil
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package exec
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func configureProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func signalProcess(process *os.Process, processGroup bool, sig os.Signal) error {
+	signal, ok := sig.(syscall.Signal)
+	if !processGroup || !ok {
+		return process.Signal(sig) //nolint:wrapcheck
+	}
+
+	// A negative PID addresses every process in the group led by that PID
+	return syscall.Kill(-process.Pid, signal) //nolint:wrapcheck
+}
@@ -0,0 +1,85 @@
+//go:build !windows
+
+package exec_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rwx-research/captain-cli/internal/exec"
+)
+
+var _ = Describe("Process groups", func() {
+	const gracePeriod = 200 * time.Millisecond
+
+	var (
+		command exec.Command
+		exited  chan error
+		// groupExited is closed once every process of the group exited, as all of them hold the write end of the pipe
+		groupExited chan struct{}
+	)
+
+	// start runs the script in a shell of its own process group and waits until the script started its background job
+	start := func(script string) {
+		reader, writer, err := os.Pipe()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(reader.Close)
+
+		command, err = exec.Local{}.NewCommand(context.Background(), exec.CommandConfig{
+			Name:            "sh",
+			Args:            []string{"-c", script},
+			Stdout:          writer,
+			Stderr:          GinkgoWriter,
+			NewProcessGroup: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(command.Start()).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+		DeferCleanup(func() { _ = command.Signal(os.Kill) })
+
+		exited = make(chan error, 1)
+		go func() {
+			exited <- command.Wait()
+		}()
+
+		output := bufio.NewReader(reader)
+		line, err := output.ReadString('\n')
+		Expect(err).NotTo(HaveOccurred())
+		Expect(line).To(Equal("started\n"))
+
+		groupExited = make(chan struct{})
+		go func() {
+			_, _ = io.Copy(io.Discard, output)
+			close(groupExited)
+		}()
+	}
+
+	It("terminates every process of the group", func() {
+		start("sleep 30 & echo started; wait")
+
+		Expect(command.Signal(syscall.SIGTERM)).To(Succeed())
+
+		Eventually(exited).Should(Receive(HaveOccurred()))
+		Eventually(groupExited).Should(BeClosed())
+	})
+
+	It("kills every process of the group that ignores the termination after the grace period", func() {
+		start(`trap "" TERM; sleep 30 & echo started; wait`)
+
+		Expect(command.Signal(syscall.SIGTERM)).To(Succeed())
+		Consistently(groupExited, gracePeriod).ShouldNot(BeClosed())
+		Expect(exited).NotTo(Receive())
+
+		Expect(command.Signal(os.Kill)).To(Succeed())
+
+		Eventually(exited).Should(Receive(HaveOccurred()))
+		Eventually(groupExited).Should(BeClosed())
+	})
+})
@@ -0,0 +1,16 @@
+//go:build windows
+
+package exec
+
+import (
+	"os"
+	"os/exec"
+)
+
+// configureProcessGroup is a no-op on Windows, as there is no equivalent to POSIX process groups that we could signal.
+func configureProcessGroup(_ *exec.Cmd) {}
+
+// signalProcess kills the process on Windows, as `os.Kill` is the only signal that can be delivered there.
+func signalProcess(process *os.Process, _ bool, _ os.Signal) error {
+	return process.Kill() //nolint:wrapcheck
+}
@@ -14,9 +14,11 @@ type API struct {
 	MockGetRunConfiguration   func(context.Context, string) (backend.RunConfiguration, error)
 	MockGetQuarantinedTests   func(context.Context, string) ([]backend.Test, error)
 	MockGetTestTimingManifest func(context.Context, string) ([]testing.TestFileTiming, error)
+	MockGetTestTimings        func(context.Context, string) ([]testing.TestTiming, error)
 	MockUpdateTestResults     func(context.Context, string, v1.TestResults) (
 		[]backend.TestResultsUploadResult, error,
 	)
+	MockGetTestResults func(context.Context, string) (*v1.TestResults, error)
 }
 
 // GetRunConfiguration either calls the configured mock of itself or returns an error if that doesn't exist.
@@ -55,6 +57,18 @@ func (a *API) GetTestTimingManifest(
 	return nil, errors.NewInternalError("MockGetTestTimingManifest was not configured")
 }
 
+// GetTestTimings either calls the configured mock of itself or returns an error if that doesn't exist.
+func (a *API) GetTestTimings(
+	ctx context.Context,
+	testSuiteIdentifier string,
+) ([]testing.TestTiming, error) {
+	if a.MockGetTestTimings != nil {
+		return a.MockGetTestTimings(ctx, testSuiteIdentifier)
+	}
+
+	return nil, errors.NewInternalError("MockGetTestTimings was not configured")
+}
+
 // UploadTestResults either calls the configured mock of itself or returns an error if that doesn't exist.
 func (a *API) UpdateTestResults(
 	ctx context.Context,
@@ -67,3 +81,15 @@ func (a *API) UpdateTestResults(
 
 	return nil, errors.NewInternalError("MockUpdateTestResults was not configured")
 }
+
+// GetTestResults either calls the configured mock of itself or returns an error if that doesn't exist.
+func (a *API) GetTestResults(
+	ctx context.Context,
+	testSuiteID string,
+) (*v1.TestResults, error) {
+	if a.MockGetTestResults != nil {
+		return a.MockGetTestResults(ctx, testSuiteID)
+	}
+
+	return nil, errors.NewInternalError("MockGetTestResults was not configured")
+}
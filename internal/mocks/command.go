@@ -1,11 +1,25 @@
 package mocks
 
-import "github.com/rwx-research/captain-cli/internal/errors"
+import (
+	"os"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+)
 
 // API is a mocked implementation of 'exec.Command'.
 type Command struct {
-	MockStart func() error
-	MockWait  func() error
+	MockSignal func(os.Signal) error
+	MockStart  func() error
+	MockWait   func() error
+}
+
+// Signal either calls the configured mock of itself or returns an error if that doesn't exist.
+func (c *Command) Signal(sig os.Signal) error {
+	if c.MockSignal != nil {
+		return c.MockSignal(sig)
+	}
+
+	return errors.NewInternalError("MockSignal was not configured")
 }
 
 // Start either calls the configured mock of itself or returns an error if that doesn't exist.
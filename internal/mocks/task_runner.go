@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"os"
 
 	"github.com/rwx-research/captain-cli/internal/errors"
 	"github.com/rwx-research/captain-cli/internal/exec"
@@ -11,6 +12,7 @@ import (
 type TaskRunner struct {
 	MockNewCommand             func(ctx context.Context, cfg exec.CommandConfig) (exec.Command, error)
 	MockGetExitStatusFromError func(error) (int, error)
+	MockNotifyInterrupts       func(chan<- os.Signal) (func(), error)
 }
 
 // NewCommand either calls the configured mock of itself or returns an error if that doesn't exist.
@@ -30,3 +32,12 @@ func (t *TaskRunner) GetExitStatusFromError(err error) (int, error) {
 
 	return 0, errors.NewInternalError("MockGetExitStatusFromError was not configured")
 }
+
+// NotifyInterrupts either calls the configured mock of itself or returns an error if that doesn't exist.
+func (t *TaskRunner) NotifyInterrupts(signals chan<- os.Signal) (func(), error) {
+	if t.MockNotifyInterrupts != nil {
+		return t.MockNotifyInterrupts(signals)
+	}
+
+	return nil, errors.NewInternalError("MockNotifyInterrupts was not configured")
+}
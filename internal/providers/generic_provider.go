@@ -16,6 +16,8 @@ type GenericEnv struct {
 	Title          string
 	PartitionIndex int `env:"CAPTAIN_PARTITION_INDEX" envDefault:"-1"`
 	PartitionTotal int `env:"CAPTAIN_PARTITION_TOTAL" envDefault:"-1"`
+	// PartitionWeights is a comma-separated list of the relative capacities of the partition nodes, e.g. "1,1,4"
+	PartitionWeights string `env:"CAPTAIN_PARTITION_WEIGHTS"`
 }
 
 func (cfg GenericEnv) MakeProvider() Provider {
@@ -41,5 +43,6 @@ func MergeGeneric(into GenericEnv, from GenericEnv) GenericEnv {
 	into.CommitMessage = firstNonempty(from.CommitMessage, into.CommitMessage)
 	into.BuildURL = firstNonempty(from.BuildURL, into.BuildURL)
 	into.Title = firstNonempty(from.Title, into.Title)
+	into.PartitionWeights = firstNonempty(from.PartitionWeights, into.PartitionWeights)
 	return into
 }
@@ -26,6 +26,8 @@ type Provider struct {
 	TimingManifestKey string
 	Title             string
 	PartitionNodes    config.PartitionNodes
+	// PartitionWeights holds the unparsed weights of the partition nodes. They're only validated when partitioning.
+	PartitionWeights string
 }
 
 func Validate(p Provider) error {
@@ -138,6 +140,9 @@ func (env Env) MakeProvider() (Provider, error) {
 
 	mergedWithGeneric := Merge(detectedProvider, env.Generic.MakeProvider())
 
+	// None of the CI providers know about the capacities of their nodes, so the weights are always Captain-specific
+	mergedWithGeneric.PartitionWeights = env.Generic.PartitionWeights
+
 	if mergedWithGeneric.Title == "" {
 		mergedWithGeneric.Title = strings.Split(mergedWithGeneric.CommitMessage, "\n")[0]
 	}
@@ -245,6 +245,22 @@ var _ = Describe("MakeProvider", func() {
 			})
 		})
 
+		It("keeps the partition weights", func() {
+			env.Generic.PartitionWeights = "1, 1,4"
+
+			provider, err := env.MakeProvider()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(provider.PartitionWeights).To(Equal("1, 1,4"))
+		})
+
+		It("doesn't validate the partition weights", func() {
+			env.Generic.PartitionWeights = "1,0"
+
+			provider, err := env.MakeProvider()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(provider.PartitionWeights).To(Equal("1,0"))
+		})
+
 		Context("but with an invalid generic provider", func() {
 			It("returns the invalid generic provider", func() {
 				// different commands have different concepts of what a valid generic provider is
@@ -0,0 +1,69 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+)
+
+// requestTimeout bounds a single request for a batch, so that a coordinator that stopped responding doesn't block the
+// node indefinitely. The coordinator answers from memory, so this is generous.
+const requestTimeout = 30 * time.Second
+
+// Client requests batches of test files from a queue coordinator
+type Client struct {
+	URL    string
+	Worker string
+	HTTP   *http.Client
+}
+
+// NewClient returns a client for the coordinator at the URL, e.g. "http://10.0.0.1:8123"
+func NewClient(url string, worker string) Client {
+	return Client{
+		URL:    strings.TrimSuffix(url, "/"),
+		Worker: worker,
+		HTTP:   &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// NextBatch requests the next batch of test files. An empty batch means that the queue is drained.
+func (c Client) NextBatch(ctx context.Context) (Batch, error) {
+	body, err := json.Marshal(BatchRequest{Worker: c.Worker})
+	if err != nil {
+		return Batch{}, errors.NewInternalError("unable to construct JSON object for request: %s", err)
+	}
+
+	endpoint := c.URL + batchesPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return Batch{}, errors.NewInternalError("unable to construct HTTP request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(req) //nolint:gosec // request URL is from application config
+	if err != nil {
+		return Batch{}, errors.NewSystemError("unable to request the next batch from the queue at %q: %s", c.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return Batch{}, errors.NewSystemError(
+			"the queue at %q responded with status code %d. Is %q a Captain queue?",
+			c.URL,
+			resp.StatusCode,
+			c.URL,
+		)
+	}
+
+	var batch Batch
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		return Batch{}, errors.NewSystemError("unable to parse the batch from the queue at %q: %s", c.URL, err)
+	}
+
+	return batch, nil
+}
@@ -0,0 +1,91 @@
+package queue_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+
+	"github.com/rwx-research/captain-cli/internal/queue"
+)
+
+var _ = Describe("Queue", func() {
+	var (
+		ctx        context.Context
+		server     *queue.Server
+		httpServer *httptest.Server
+		client     queue.Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = queue.NewServer([]string{"a.test", "b.test", "c.test"}, 2, zap.NewNop().Sugar())
+		httpServer = httptest.NewServer(server)
+		client = queue.NewClient(httpServer.URL+"/", "worker")
+	})
+
+	AfterEach(func() {
+		httpServer.Close()
+	})
+
+	It("hands out the test files in order and in batches", func() {
+		batch, err := client.NextBatch(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(batch.TestFilePaths).To(Equal([]string{"a.test", "b.test"}))
+		Expect(server.Status()).To(Equal(queue.Status{Remaining: 1, HandedOut: 2}))
+
+		batch, err = client.NextBatch(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(batch.TestFilePaths).To(Equal([]string{"c.test"}))
+		Expect(server.Status()).To(Equal(queue.Status{Remaining: 0, HandedOut: 3}))
+	})
+
+	It("hands out empty batches once the queue is drained", func() {
+		for range 2 {
+			_, err := client.NextBatch(ctx)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		batch, err := client.NextBatch(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(batch.TestFilePaths).To(BeEmpty())
+	})
+
+	It("reports its status", func() {
+		resp, err := http.Get(httpServer.URL + "/status")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+	})
+
+	It("errors when the URL doesn't point at a queue", func() {
+		client = queue.NewClient(httpServer.URL+"/not-a-queue", "worker")
+
+		_, err := client.NextBatch(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("responded with status code 404"))
+	})
+
+	It("errors when the coordinator doesn't respond in time", func() {
+		unblock := make(chan struct{})
+		hangingServer := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			<-unblock
+		}))
+		defer hangingServer.Close()
+		defer close(unblock)
+
+		client = queue.NewClient(hangingServer.URL, "worker")
+		Expect(client.HTTP.Timeout).To(BeNumerically(">", 0))
+		client.HTTP.Timeout = 10 * time.Millisecond
+
+		_, err := client.NextBatch(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("unable to request the next batch"))
+	})
+})
@@ -0,0 +1,104 @@
+// Package queue holds a small HTTP coordinator that hands out the test files of a test suite in batches. Instead of
+// deciding upfront which partition runs which test files, every node asks the coordinator for more work once it's done
+// with its previous batch, so that nodes that happen to be faster than expected take over work from slower ones.
+package queue
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const (
+	batchesPath = "/batches"
+	statusPath  = "/status"
+)
+
+// BatchRequest is the body of a request for the next batch of test files
+type BatchRequest struct {
+	// Worker identifies the node that requests the batch. It's only used for logging.
+	Worker string `json:"worker,omitempty"`
+}
+
+// Batch is the next set of test files that a node should run. An empty batch means that the queue is drained.
+type Batch struct {
+	TestFilePaths []string `json:"testFilePaths"`
+}
+
+// Status describes the progress of the queue
+type Status struct {
+	Remaining int `json:"remaining"`
+	HandedOut int `json:"handedOut"`
+}
+
+// Server hands out the test files it was loaded with, in order, in batches of a fixed size
+type Server struct {
+	batchSize     int
+	log           *zap.SugaredLogger
+	mutex         sync.Mutex
+	testFilePaths []string
+	handedOut     int
+}
+
+// NewServer returns a queue coordinator for the test files. They should be sorted by their expected runtime, slowest
+// first, so that the slowest test files don't end up being picked up last.
+func NewServer(testFilePaths []string, batchSize int, log *zap.SugaredLogger) *Server {
+	return &Server{
+		batchSize:     max(batchSize, 1),
+		log:           log,
+		testFilePaths: testFilePaths,
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == batchesPath && r.Method == http.MethodPost:
+		var request BatchRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				http.Error(w, "unable to parse the batch request", http.StatusBadRequest)
+				return
+			}
+		}
+
+		s.writeJSON(w, s.nextBatch(request.Worker))
+	case r.URL.Path == statusPath && r.Method == http.MethodGet:
+		s.writeJSON(w, s.Status())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Status returns how many test files are remaining and how many were handed out already
+func (s *Server) Status() Status {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return Status{Remaining: len(s.testFilePaths), HandedOut: s.handedOut}
+}
+
+func (s *Server) nextBatch(worker string) Batch {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	size := min(s.batchSize, len(s.testFilePaths))
+	batch := Batch{TestFilePaths: s.testFilePaths[:size:size]}
+	s.testFilePaths = s.testFilePaths[size:]
+	s.handedOut += size
+
+	if worker == "" {
+		worker = "unknown worker"
+	}
+	s.log.Infof("Handed out %d test files to %s, %d remaining", size, worker, len(s.testFilePaths))
+
+	return batch
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.log.Warnf("Unable to write the queue's response: %s", err.Error())
+	}
+}
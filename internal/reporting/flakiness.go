@@ -0,0 +1,147 @@
+package reporting
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// testFlakiness summarizes all attempts of a single test
+type testFlakiness struct {
+	name     string
+	runs     int
+	passes   int
+	failures []failureCount
+}
+
+// failureCount counts how often a distinct failure message was observed
+type failureCount struct {
+	message string
+	count   int
+}
+
+func (f testFlakiness) passRate() float64 {
+	if f.runs == 0 {
+		return 0
+	}
+
+	return float64(f.passes) / float64(f.runs)
+}
+
+// WriteFlakinessReport writes the pass rate of every test that did not pass in all of its attempts, together with the
+// distinct failure messages that were observed. It is meant for test results that contain repeated runs of the same
+// tests.
+func WriteFlakinessReport(w io.Writer, testResults v1.TestResults) error {
+	unstableTests := make([]testFlakiness, 0)
+	stableTests := 0
+
+	for _, test := range testResults.Tests {
+		flakiness := summarizeFlakiness(test)
+		if flakiness.runs == 0 {
+			continue
+		}
+
+		if flakiness.passes == flakiness.runs {
+			stableTests++
+			continue
+		}
+
+		unstableTests = append(unstableTests, flakiness)
+	}
+
+	sort.SliceStable(unstableTests, func(i, j int) bool {
+		return unstableTests[i].passRate() < unstableTests[j].passRate()
+	})
+
+	if _, err := fmt.Fprintf(w, "\nFlakiness report:\n"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, test := range unstableTests {
+		_, err := fmt.Fprintf(
+			w,
+			"- %s: passed %d of %d runs (%.0f%%)\n",
+			test.name,
+			test.passes,
+			test.runs,
+			test.passRate()*100,
+		)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for _, failure := range test.failures {
+			if _, err := fmt.Fprintf(w, "    %dx %s\n", failure.count, failure.message); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+
+	pluralizeTests := "tests"
+	if stableTests+len(unstableTests) == 1 {
+		pluralizeTests = "test"
+	}
+
+	_, err := fmt.Fprintf(
+		w,
+		"\n%d of %d %s passed in every run, %d did not\n",
+		stableTests,
+		stableTests+len(unstableTests),
+		pluralizeTests,
+		len(unstableTests),
+	)
+	return errors.WithStack(err)
+}
+
+func summarizeFlakiness(test v1.Test) testFlakiness {
+	flakiness := testFlakiness{name: test.Name}
+	failureIndices := make(map[string]int)
+
+	attempts := append([]v1.TestAttempt{test.Attempt}, test.PastAttempts...)
+	for _, attempt := range attempts {
+		status := attempt.Status
+		if status.Kind == v1.TestStatusQuarantined && status.OriginalStatus != nil {
+			status = *status.OriginalStatus
+		}
+
+		if status.ImpliesSkipped() {
+			continue
+		}
+
+		flakiness.runs++
+		if !status.ImpliesFailure() {
+			flakiness.passes++
+			continue
+		}
+
+		message := failureMessage(status)
+		if i, ok := failureIndices[message]; ok {
+			flakiness.failures[i].count++
+			continue
+		}
+
+		failureIndices[message] = len(flakiness.failures)
+		flakiness.failures = append(flakiness.failures, failureCount{message: message, count: 1})
+	}
+
+	return flakiness
+}
+
+// failureMessage returns the first line of the most descriptive part of a failed status
+func failureMessage(status v1.TestStatus) string {
+	message := testStatusKindToString(status.Kind)
+
+	switch {
+	case status.Message != nil && strings.TrimSpace(*status.Message) != "":
+		message = *status.Message
+	case status.Exception != nil && strings.TrimSpace(*status.Exception) != "":
+		message = *status.Exception
+	}
+
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return firstLine
+}
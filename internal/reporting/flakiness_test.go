@@ -0,0 +1,80 @@
+package reporting_test
+
+import (
+	"strings"
+
+	"github.com/rwx-research/captain-cli/internal/mocks"
+	"github.com/rwx-research/captain-cli/internal/reporting"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Flakiness Report", func() {
+	var (
+		mockFile    *mocks.File
+		testResults v1.TestResults
+	)
+
+	BeforeEach(func() {
+		mockFile = new(mocks.File)
+		mockFile.Builder = new(strings.Builder)
+
+		timeout := "Net::ReadTimeout\n  from net/protocol.rb:219"
+		assertion := "expected 1 to eq 2"
+
+		testResults = v1.TestResults{
+			Tests: []v1.Test{
+				{
+					Name:    "stable test",
+					Attempt: v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()},
+					PastAttempts: []v1.TestAttempt{
+						{Status: v1.NewSuccessfulTestStatus()},
+						{Status: v1.NewSuccessfulTestStatus()},
+					},
+				},
+				{
+					Name:    "flaky test",
+					Attempt: v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()},
+					PastAttempts: []v1.TestAttempt{
+						{Status: v1.NewFailedTestStatus(&timeout, nil, nil)},
+						{Status: v1.NewFailedTestStatus(&assertion, nil, nil)},
+						{Status: v1.NewFailedTestStatus(&timeout, nil, nil)},
+					},
+				},
+				{
+					Name:    "mostly passing test",
+					Attempt: v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()},
+					PastAttempts: []v1.TestAttempt{
+						{Status: v1.NewSuccessfulTestStatus()},
+						{Status: v1.NewTimedOutTestStatus(nil, nil, nil)},
+					},
+				},
+				{
+					Name:    "skipped test",
+					Attempt: v1.TestAttempt{Status: v1.NewSkippedTestStatus(nil)},
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		Expect(reporting.WriteFlakinessReport(mockFile, testResults)).To(Succeed())
+	})
+
+	It("lists the tests that did not pass in every run, least stable first", func() {
+		Expect(mockFile.Builder.String()).To(Equal(strings.Join([]string{
+			"",
+			"Flakiness report:",
+			"- flaky test: passed 1 of 4 runs (25%)",
+			"    2x Net::ReadTimeout",
+			"    1x expected 1 to eq 2",
+			"- mostly passing test: passed 2 of 3 runs (67%)",
+			"    1x timed out",
+			"",
+			"1 of 3 tests passed in every run, 2 did not",
+			"",
+		}, "\n")))
+	})
+})
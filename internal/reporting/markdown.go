@@ -2,6 +2,7 @@ package reporting
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"text/template"
 
@@ -31,6 +32,13 @@ type markdownTest struct {
 	Message   *string
 	Backtrace string
 	Retries   int
+	Logs      []markdownLog
+}
+
+// markdownLog links the log of a single attempt of a test
+type markdownLog struct {
+	Status v1.TestStatusKind
+	Path   string
 }
 
 const (
@@ -43,6 +51,10 @@ const (
 {{ if .Retries }}<dd>Retried {{ .Retries}} time{{ if ne .Retries 1 }}s{{end}}</dd>{{ end }}
 {{ if .Location }}<dd>Defined at <code>{{ .Location }}</code></dd>{{ end }}
 {{ if .Command }}<dd>Retry with <code>{{ .Command }}</code></dd>{{ end }}
+{{ if .Logs }}<dd>Logs of each attempt:
+{{- range $i, $log := .Logs }}{{ if $i }},{{ end }} <a href="{{ $log.Path }}">{{ $log.Status }}</a>
+{{- end }}</dd>
+{{ end -}}
 {{ if or .Message .Backtrace }}
 <dd>
 <details>
@@ -383,6 +395,7 @@ func writeMarkdownSection(
 			Location: location,
 			Command:  retryCommand,
 			Retries:  len(test.PastAttempts),
+			Logs:     markdownLogsFor(test),
 		}
 		if failedStatus != nil {
 			markdownTest.Backtrace = stripansi.Strip(strings.Join(failedStatus.Backtrace, "\n"))
@@ -409,6 +422,20 @@ func writeMarkdownSection(
 	return false, nil
 }
 
+// markdownLogsFor returns the logs of the test's attempts, starting with the past attempts
+func markdownLogsFor(test v1.Test) []markdownLog {
+	logs := make([]markdownLog, 0)
+	for _, attempt := range append(slices.Clone(test.PastAttempts), test.Attempt) {
+		if path, ok := attempt.Tagged(v1.LogPathTag); ok {
+			if path, ok := path.(string); ok {
+				logs = append(logs, markdownLog{Status: attempt.Status.Kind, Path: path})
+			}
+		}
+	}
+
+	return logs
+}
+
 func retryTemplateAndSubstitutionFor(
 	framework v1.Framework,
 	retryCommandTemplate string,
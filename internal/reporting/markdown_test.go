@@ -172,6 +172,18 @@ var _ = Describe("Markdown Report", func() {
 		cupaloy.SnapshotT(GinkgoT(), summary)
 	})
 
+	It("links the log of each attempt", func() {
+		testResults.Tests[3].PastAttempts[0] = v1.Test{Attempt: testResults.Tests[3].PastAttempts[0]}.
+			Tag(v1.LogPathTag, "artifacts/original-attempt/logs/command.log").Attempt
+		testResults.Tests[3] = testResults.Tests[3].Tag(v1.LogPathTag, "artifacts/retry-1/command-1/logs/command.log")
+
+		Expect(reporting.WriteMarkdownSummary(mockFile, testResults, reporting.Configuration{})).To(Succeed())
+		Expect(mockFile.String()).To(ContainSubstring(
+			`<dd>Logs of each attempt: <a href="artifacts/original-attempt/logs/command.log">failed</a>, ` +
+				`<a href="artifacts/retry-1/command-1/logs/command.log">successful</a></dd>`,
+		))
+	})
+
 	It("produces a truncated summary <= 1MB", func() {
 		cfg := reporting.Configuration{
 			SuiteID:      "some-suite-id",
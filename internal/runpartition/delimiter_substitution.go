@@ -2,15 +2,26 @@ package runpartition
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/fs"
 	"github.com/rwx-research/captain-cli/internal/templating"
 )
 
+const (
+	testFilesKeyword     = "testFiles"
+	testFilesFileKeyword = "testFilesFile"
+)
+
+// DelimiterSubstitution substitutes the 'testFiles' keyword with the test files, separated by the delimiter. The
+// 'testFilesFile' keyword is substituted with the path to a temporary file listing the test files instead, one per
+// line, which avoids hitting the maximum argument length with many test files. The file is removed by `CleanUp`.
 type DelimiterSubstitution struct {
-	Delimiter string
+	Delimiter  string
+	FileSystem fs.FileSystem
 }
 
 func (s DelimiterSubstitution) Example() string {
@@ -19,7 +30,7 @@ func (s DelimiterSubstitution) Example() string {
 
 func (s DelimiterSubstitution) ValidateTemplate(compiledTemplate templating.CompiledTemplate) error {
 	keywords := compiledTemplate.Keywords()
-	message := "Partitioning with a delimiter requires a template with only the 'testFiles' keyword"
+	message := "Partitioning requires a template with only the 'testFiles' or 'testFilesFile' keyword"
 
 	if len(keywords) == 0 {
 		return errors.NewInputError("%v; no keywords were found", message)
@@ -30,7 +41,7 @@ func (s DelimiterSubstitution) ValidateTemplate(compiledTemplate templating.Comp
 		return errors.NewInputError("%v; these were found: %v", message, strings.Join(keywords, ", "))
 	}
 
-	if keywords[0] != "testFiles" {
+	if keywords[0] != testFilesKeyword && keywords[0] != testFilesFileKeyword {
 		return errors.NewInputError("%v; '%v' was found instead", message, keywords[0])
 	}
 
@@ -38,9 +49,13 @@ func (s DelimiterSubstitution) ValidateTemplate(compiledTemplate templating.Comp
 }
 
 func (s DelimiterSubstitution) SubstitutionLookupFor(
-	_ templating.CompiledTemplate,
+	compiledTemplate templating.CompiledTemplate,
 	testFilePaths []string,
 ) (map[string]string, error) {
+	if slices.Contains(compiledTemplate.Keywords(), testFilesFileKeyword) {
+		return s.testFilesFileLookupFor(testFilePaths)
+	}
+
 	escapedTestFilePaths := make([]string, 0, len(testFilePaths))
 
 	for _, testFilePath := range testFilePaths {
@@ -49,3 +64,35 @@ func (s DelimiterSubstitution) SubstitutionLookupFor(
 
 	return map[string]string{"testFiles": strings.Join(escapedTestFilePaths, s.Delimiter)}, nil
 }
+
+func (s DelimiterSubstitution) testFilesFileLookupFor(testFilePaths []string) (map[string]string, error) {
+	if s.FileSystem == nil {
+		return nil, errors.NewInternalError("The 'testFilesFile' keyword requires a file system")
+	}
+
+	file, err := s.FileSystem.CreateTemp("", "test-files")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer file.Close()
+
+	for _, testFilePath := range testFilePaths {
+		if _, err := fmt.Fprintln(file, testFilePath); err != nil {
+			_ = s.FileSystem.Remove(file.Name())
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return map[string]string{testFilesFileKeyword: file.Name()}, nil
+}
+
+// CleanUp removes the temporary file created for the 'testFilesFile' keyword, if any
+func (s DelimiterSubstitution) CleanUp(substitutionLookup map[string]string) error {
+	filePath, ok := substitutionLookup[testFilesFileKeyword]
+	if !ok {
+		return nil
+	}
+
+	err := s.FileSystem.Remove(filePath)
+	return errors.Wrapf(err, "Unable to clean up %q", filePath)
+}
@@ -1,6 +1,10 @@
 package runpartition_test
 
 import (
+	"strings"
+
+	"github.com/rwx-research/captain-cli/internal/fs"
+	"github.com/rwx-research/captain-cli/internal/mocks"
 	"github.com/rwx-research/captain-cli/internal/runpartition"
 	"github.com/rwx-research/captain-cli/internal/templating"
 
@@ -64,6 +68,15 @@ var _ = Describe("DelimiterSubstitution", func() {
 			err := substitution.ValidateTemplate(compiledTemplate)
 			Expect(err).NotTo(HaveOccurred())
 		})
+
+		It("is valid exactly testFilesFile is provided", func() {
+			substitution := runpartition.DelimiterSubstitution{}
+			compiledTemplate, compileErr := templating.CompileTemplate("some-command @{{ testFilesFile }}")
+			Expect(compileErr).NotTo(HaveOccurred())
+
+			err := substitution.ValidateTemplate(compiledTemplate)
+			Expect(err).NotTo(HaveOccurred())
+		})
 	})
 
 	Describe("SubstitutionLookupFor", func() {
@@ -100,5 +113,47 @@ var _ = Describe("DelimiterSubstitution", func() {
 				Expect(lookup["testFiles"]).To(Equal("'a spec','b spec'"))
 			})
 		})
+
+		Context("when using testFilesFile", func() {
+			var (
+				mockFileSystem *mocks.FileSystem
+				mockFile       *mocks.File
+				removedFiles   []string
+			)
+
+			BeforeEach(func() {
+				removedFiles = make([]string, 0)
+				mockFileSystem = new(mocks.FileSystem)
+				mockFile = new(mocks.File)
+				mockFile.Builder = new(strings.Builder)
+				mockFile.MockName = func() string {
+					return "test-files-temp-file"
+				}
+				mockFileSystem.MockCreateTemp = func(_, _ string) (fs.File, error) {
+					return mockFile, nil
+				}
+				mockFileSystem.MockRemove = func(name string) error {
+					removedFiles = append(removedFiles, name)
+					return nil
+				}
+			})
+
+			It("writes the files to a temporary file, one per line", func() {
+				substitution := runpartition.DelimiterSubstitution{Delimiter: ",", FileSystem: mockFileSystem}
+				compiledTemplate, _ := templating.CompileTemplate("some-command @{{ testFilesFile }}")
+				lookup, err := substitution.SubstitutionLookupFor(compiledTemplate, []string{"a spec", "b spec"})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lookup).To(Equal(map[string]string{"testFilesFile": "test-files-temp-file"}))
+				Expect(mockFile.String()).To(Equal("a spec\nb spec\n"))
+			})
+
+			It("removes the temporary file on clean up", func() {
+				substitution := runpartition.DelimiterSubstitution{FileSystem: mockFileSystem}
+				Expect(substitution.CleanUp(map[string]string{"testFilesFile": "test-files-temp-file"})).To(Succeed())
+				Expect(substitution.CleanUp(map[string]string{"testFiles": "'a'"})).To(Succeed())
+				Expect(removedFiles).To(Equal([]string{"test-files-temp-file"}))
+			})
+		})
 	})
 })
@@ -12,3 +12,9 @@ type Substitution interface {
 		testFilePaths []string,
 	) (map[string]string, error)
 }
+
+// CleanableSubstitution is implemented by substitutions that write temporary files, which need to be removed once the
+// command using them finished
+type CleanableSubstitution interface {
+	CleanUp(substitutionLookup map[string]string) error
+}
@@ -0,0 +1,132 @@
+package runpartition
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/targetedretries"
+	"github.com/rwx-research/captain-cli/internal/templating"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// TestSubstitution substitutes the test files and the individual tests of a partition that splits slow test files
+// into their tests. The individual tests are selected exactly like targeted retries select them, e.g. by their RSpec
+// IDs or pytest node IDs, which is why the partition command uses the keywords of the framework's retry command.
+type TestSubstitution struct {
+	Framework v1.Framework
+	// Tests are the individual tests of the partition, in addition to the test files
+	Tests []v1.Test
+}
+
+// splitTestFrameworks are the frameworks whose test selectors can be combined with whole test files in one command
+var splitTestFrameworks = []v1.Framework{
+	v1.JavaScriptJestFramework,
+	v1.PythonPytestFramework,
+	v1.RubyRSpecFramework,
+}
+
+func (s TestSubstitution) Example() string {
+	substitution, err := s.retrySubstitution()
+	if err != nil {
+		return targetedretries.RubyRSpecSubstitution{}.Example()
+	}
+
+	return substitution.Example()
+}
+
+func (s TestSubstitution) ValidateTemplate(compiledTemplate templating.CompiledTemplate) error {
+	substitution, err := s.retrySubstitution()
+	if err != nil {
+		return err
+	}
+
+	return errors.WithStack(substitution.ValidateTemplate(compiledTemplate))
+}
+
+func (s TestSubstitution) SubstitutionLookupFor(
+	compiledTemplate templating.CompiledTemplate,
+	testFilePaths []string,
+) (map[string]string, error) {
+	substitution, err := s.retrySubstitution()
+	if err != nil {
+		return nil, err
+	}
+
+	selectors, err := substitution.SubstitutionsFor(
+		compiledTemplate,
+		v1.TestResults{Framework: s.Framework, Tests: s.Tests},
+		func(_ v1.Test) bool { return true },
+	)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if s.Framework.Equal(v1.JavaScriptJestFramework) {
+		return jestLookupFor(testFilePaths, selectors), nil
+	}
+
+	tests := make([]string, 0, len(testFilePaths)+1)
+	for _, testFilePath := range testFilePaths {
+		tests = append(tests, fmt.Sprintf("'%v'", templating.ShellEscape(testFilePath)))
+	}
+	for _, selector := range selectors {
+		tests = append(tests, selector["tests"])
+	}
+
+	return map[string]string{"tests": strings.Join(tests, " ")}, nil
+}
+
+// jestLookupFor combines the per-file patterns of Jest's targeted retries into a single command. Jest applies the
+// name pattern to every test file, so it can only narrow down the tests when the partition has no whole test files.
+// Otherwise, the split test files assigned to the partition run in full, which takes longer but never skips a test.
+// The path pattern is a regular expression, so the test file paths are quoted before they're combined.
+func jestLookupFor(testFilePaths []string, selectors []map[string]string) map[string]string {
+	testPathPatterns := make([]string, 0, len(testFilePaths)+len(selectors))
+	for _, testFilePath := range testFilePaths {
+		testPathPatterns = append(testPathPatterns, templating.ShellEscape(templating.RegexpEscape(testFilePath)))
+	}
+
+	testNamePatterns := make([]string, 0, len(selectors))
+	for _, selector := range selectors {
+		// The retry substitution only escapes the path for the shell, which doesn't interfere with regexp quoting
+		testPathPatterns = append(testPathPatterns, templating.RegexpEscape(selector["testPathPattern"]))
+		testNamePatterns = append(
+			testNamePatterns,
+			strings.TrimSuffix(strings.TrimPrefix(selector["testNamePattern"], "^"), "$"),
+		)
+	}
+
+	testNamePattern := ""
+	if len(testFilePaths) == 0 && len(testNamePatterns) > 0 {
+		testNamePattern = fmt.Sprintf("^%v$", strings.Join(testNamePatterns, "|"))
+	}
+
+	return map[string]string{
+		"testPathPattern": strings.Join(testPathPatterns, "|"),
+		"testNamePattern": testNamePattern,
+	}
+}
+
+func (s TestSubstitution) retrySubstitution() (targetedretries.Substitution, error) {
+	for _, framework := range splitTestFrameworks {
+		if s.Framework.Equal(framework) {
+			return targetedretries.SubstitutionsByFramework[framework], nil
+		}
+	}
+
+	supportedFrameworks := make([]string, len(splitTestFrameworks))
+	for i, framework := range splitTestFrameworks {
+		supportedFrameworks[i] = framework.String()
+	}
+
+	return nil, errors.NewConfigurationError(
+		"Unsupported framework for splitting test files",
+		fmt.Sprintf(
+			"Captain can only split test files into individual tests for %v, but the framework is %v.",
+			strings.Join(supportedFrameworks, ", "),
+			s.Framework,
+		),
+		"Please set the --language and --framework of your test suite, or partition whole test files instead.",
+	)
+}
@@ -0,0 +1,136 @@
+package runpartition_test
+
+import (
+	"github.com/rwx-research/captain-cli/internal/runpartition"
+	"github.com/rwx-research/captain-cli/internal/templating"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TestSubstitution", func() {
+	newTest := func(id, file string, lineage ...string) v1.Test {
+		test := v1.Test{Name: id, Lineage: lineage, Location: &v1.Location{File: file}}
+		if id != "" {
+			test.ID = &id
+		}
+		return test
+	}
+
+	It("adheres to the Substitution interface", func() {
+		var substitution runpartition.Substitution = runpartition.TestSubstitution{}
+		Expect(substitution).NotTo(BeNil())
+	})
+
+	Describe("Example", func() {
+		It("compiles and is valid", func() {
+			substitution := runpartition.TestSubstitution{Framework: v1.PythonPytestFramework}
+			compiledTemplate, compileErr := templating.CompileTemplate(substitution.Example())
+			Expect(compileErr).NotTo(HaveOccurred())
+
+			err := substitution.ValidateTemplate(compiledTemplate)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("ValidateTemplate", func() {
+		It("uses the keywords of the framework's retry command", func() {
+			substitution := runpartition.TestSubstitution{Framework: v1.RubyRSpecFramework}
+			compiledTemplate, compileErr := templating.CompileTemplate("bundle exec rspec {{ testFiles }}")
+			Expect(compileErr).NotTo(HaveOccurred())
+
+			err := substitution.ValidateTemplate(compiledTemplate)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("'testFiles' was found instead"))
+		})
+
+		It("is invalid for frameworks that can't select individual tests", func() {
+			substitution := runpartition.TestSubstitution{Framework: v1.RubyMinitestFramework}
+			compiledTemplate, compileErr := templating.CompileTemplate("bin/rails test {{ tests }}")
+			Expect(compileErr).NotTo(HaveOccurred())
+
+			err := substitution.ValidateTemplate(compiledTemplate)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unsupported framework for splitting test files"))
+		})
+	})
+
+	Describe("SubstitutionLookupFor", func() {
+		It("passes the test files together with the IDs of the individual tests", func() {
+			substitution := runpartition.TestSubstitution{
+				Framework: v1.RubyRSpecFramework,
+				Tests: []v1.Test{
+					newTest("./spec/slow_spec.rb[1:1]", "spec/slow_spec.rb"),
+					newTest("./spec/slow_spec.rb[1:3]", "spec/slow_spec.rb"),
+				},
+			}
+			compiledTemplate, compileErr := templating.CompileTemplate("bundle exec rspec {{ tests }}")
+			Expect(compileErr).NotTo(HaveOccurred())
+
+			lookup, err := substitution.SubstitutionLookupFor(compiledTemplate, []string{"spec/a_spec.rb"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lookup).To(Equal(map[string]string{
+				"tests": "'spec/a_spec.rb' './spec/slow_spec.rb[1:1]' './spec/slow_spec.rb[1:3]'",
+			}))
+		})
+
+		It("passes only the test files without individual tests", func() {
+			substitution := runpartition.TestSubstitution{Framework: v1.PythonPytestFramework}
+			compiledTemplate, compileErr := templating.CompileTemplate("pytest {{ tests }}")
+			Expect(compileErr).NotTo(HaveOccurred())
+
+			lookup, err := substitution.SubstitutionLookupFor(compiledTemplate, []string{"test_a.py", "test_b.py"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lookup).To(Equal(map[string]string{"tests": "'test_a.py' 'test_b.py'"}))
+		})
+
+		Context("with Jest", func() {
+			var (
+				substitution     runpartition.TestSubstitution
+				compiledTemplate templating.CompiledTemplate
+			)
+
+			BeforeEach(func() {
+				substitution = runpartition.TestSubstitution{
+					Framework: v1.JavaScriptJestFramework,
+					Tests: []v1.Test{
+						newTest("", "slow.test.js", "slow", "first"),
+						newTest("", "slow.test.js", "slow", "second"),
+					},
+				}
+
+				var compileErr error
+				compiledTemplate, compileErr = templating.CompileTemplate(substitution.Example())
+				Expect(compileErr).NotTo(HaveOccurred())
+			})
+
+			It("selects the individual tests by their name", func() {
+				lookup, err := substitution.SubstitutionLookupFor(compiledTemplate, []string{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lookup).To(Equal(map[string]string{
+					"testPathPattern": `slow\.test\.js`,
+					"testNamePattern": "^slow first|slow second$",
+				}))
+			})
+
+			It("runs the split test files in full alongside whole test files", func() {
+				lookup, err := substitution.SubstitutionLookupFor(compiledTemplate, []string{"a.test.js"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lookup).To(Equal(map[string]string{
+					"testPathPattern": `a\.test\.js|slow\.test\.js`,
+					"testNamePattern": "",
+				}))
+			})
+
+			It("quotes regular expression characters in the test file paths", func() {
+				lookup, err := substitution.SubstitutionLookupFor(
+					compiledTemplate,
+					[]string{"app/(admin)/[id]+page.test.js"},
+				)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lookup["testPathPattern"]).To(Equal(`app/\(admin\)/\[id\]\+page\.test\.js|slow\.test\.js`))
+			})
+		})
+	})
+})
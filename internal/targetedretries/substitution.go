@@ -15,6 +15,12 @@ type Substitution interface {
 	) ([]map[string]string, error)
 }
 
+// CleanableSubstitution is implemented by substitutions that write temporary files, which need to be removed once the
+// commands using them finished
+type CleanableSubstitution interface {
+	CleanUp(allSubstitutions []map[string]string) error
+}
+
 var SubstitutionsByFramework = map[v1.Framework]Substitution{
 	v1.DotNetxUnitFramework:          new(DotNetxUnitSubstitution),
 	v1.ElixirExUnitFramework:         new(ElixirExUnitSubstitution),
@@ -0,0 +1,144 @@
+package targetedretries
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-shellwords"
+
+	"github.com/rwx-research/captain-cli/internal/errors"
+	"github.com/rwx-research/captain-cli/internal/fs"
+	"github.com/rwx-research/captain-cli/internal/templating"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// testsFileKeyword is the keyword that is substituted with the path to the file listing the tests to retry
+const testsFileKeyword = "testsFile"
+
+// testListKeywords are the keywords that framework substitutions use for a list of command line arguments, one per
+// test. Frameworks that use one of them on its own can receive their tests through a file instead.
+var testListKeywords = []string{"tests", "scenarios"}
+
+// TestsFileSubstitution writes the tests to retry to a temporary file instead of putting them onto the command line,
+// which avoids hitting the maximum argument length when retrying many tests.
+//
+// The file has the same format for every framework: it contains the arguments that the framework substitution would
+// pass on the command line (`{{ tests }}` or `{{ scenarios }}`), one per line and without any shell quoting.
+// pytest (`pytest @{{ testsFile }}`) as well as Cucumber for Ruby and JavaScript (`cucumber @{{ testsFile }}`) read
+// this format natively. Other frameworks need a wrapper script that passes the lines of the file on as arguments.
+type TestsFileSubstitution struct {
+	FileSystem   fs.FileSystem
+	Substitution Substitution
+}
+
+func (s TestsFileSubstitution) Example() string {
+	return "pytest @{{ testsFile }}"
+}
+
+func (s TestsFileSubstitution) ValidateTemplate(compiledTemplate templating.CompiledTemplate) error {
+	keywords := compiledTemplate.Keywords()
+
+	if len(keywords) == 0 {
+		return errors.NewInputError(
+			"Retrying with a tests file requires a template with the 'testsFile' keyword; no keywords were found",
+		)
+	}
+
+	if len(keywords) > 1 {
+		return errors.NewInputError(
+			"Retrying with a tests file requires a template with only the 'testsFile' keyword; these were found: %v",
+			strings.Join(keywords, ", "),
+		)
+	}
+
+	if keywords[0] != testsFileKeyword {
+		return errors.NewInputError(
+			"Retrying with a tests file requires a template with only the 'testsFile' keyword; '%v' was found instead",
+			keywords[0],
+		)
+	}
+
+	if _, _, err := s.listTemplate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s TestsFileSubstitution) SubstitutionsFor(
+	_ templating.CompiledTemplate,
+	testResults v1.TestResults,
+	filter func(test v1.Test) bool,
+) ([]map[string]string, error) {
+	listTemplate, listKeyword, err := s.listTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	listSubstitutions, err := s.Substitution.SubstitutionsFor(listTemplate, testResults, filter)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	substitutions := make([]map[string]string, 0, len(listSubstitutions))
+	for _, listSubstitution := range listSubstitutions {
+		args, err := shellwords.Parse(listSubstitution[listKeyword])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to parse %q into shell arguments", listSubstitution[listKeyword])
+		}
+
+		file, err := s.FileSystem.CreateTemp("", "tests-to-retry")
+		if err != nil {
+			_ = s.CleanUp(substitutions)
+			return nil, errors.WithStack(err)
+		}
+
+		substitutions = append(substitutions, map[string]string{testsFileKeyword: file.Name()})
+
+		_, err = fmt.Fprintln(file, strings.Join(args, "\n"))
+		file.Close()
+		if err != nil {
+			_ = s.CleanUp(substitutions)
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return substitutions, nil
+}
+
+func (s TestsFileSubstitution) CleanUp(allSubstitutions []map[string]string) error {
+	for _, substitutions := range allSubstitutions {
+		filePath, ok := substitutions[testsFileKeyword]
+		if !ok {
+			return errors.NewInternalError(
+				"Expected TestsFileSubstitution to expose the file path in the substitution, but it did not",
+			)
+		}
+
+		if err := s.FileSystem.Remove(filePath); err != nil {
+			return errors.Wrapf(err, "Unable to clean up %q", filePath)
+		}
+	}
+
+	return nil
+}
+
+// listTemplate returns a template consisting of the keyword that the framework substitution uses for its list of
+// tests, which is used to determine the contents of the tests file
+func (s TestsFileSubstitution) listTemplate() (templating.CompiledTemplate, string, error) {
+	for _, keyword := range testListKeywords {
+		compiledTemplate, err := templating.CompileTemplate(fmt.Sprintf("{{ %v }}", keyword))
+		if err != nil {
+			return templating.CompiledTemplate{}, "", errors.WithStack(err)
+		}
+
+		if s.Substitution.ValidateTemplate(compiledTemplate) == nil {
+			return compiledTemplate, keyword, nil
+		}
+	}
+
+	return templating.CompiledTemplate{}, "", errors.NewInputError(
+		"The 'testsFile' keyword is not supported by this test framework. Please use the keywords of its retry " +
+			"command instead.",
+	)
+}
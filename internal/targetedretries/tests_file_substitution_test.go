@@ -0,0 +1,147 @@
+package targetedretries_test
+
+import (
+	"strings"
+
+	"github.com/rwx-research/captain-cli/internal/fs"
+	"github.com/rwx-research/captain-cli/internal/mocks"
+	"github.com/rwx-research/captain-cli/internal/targetedretries"
+	"github.com/rwx-research/captain-cli/internal/templating"
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TestsFileSubstitution", func() {
+	var (
+		mockFileSystem *mocks.FileSystem
+		mockFile       *mocks.File
+		removedFiles   []string
+	)
+
+	BeforeEach(func() {
+		removedFiles = make([]string, 0)
+		mockFileSystem = new(mocks.FileSystem)
+		mockFile = new(mocks.File)
+		mockFile.Builder = new(strings.Builder)
+		mockFile.MockName = func() string {
+			return "tests-temp-file"
+		}
+		mockFileSystem.MockCreateTemp = func(_, _ string) (fs.File, error) {
+			return mockFile, nil
+		}
+		mockFileSystem.MockRemove = func(name string) error {
+			removedFiles = append(removedFiles, name)
+			return nil
+		}
+	})
+
+	It("adheres to the Substitution interface", func() {
+		var substitution targetedretries.Substitution = targetedretries.TestsFileSubstitution{
+			FileSystem:   mockFileSystem,
+			Substitution: targetedretries.PythonPytestSubstitution{},
+		}
+		Expect(substitution).NotTo(BeNil())
+	})
+
+	Describe("Example", func() {
+		It("compiles and is valid", func() {
+			substitution := targetedretries.TestsFileSubstitution{
+				FileSystem:   mockFileSystem,
+				Substitution: targetedretries.PythonPytestSubstitution{},
+			}
+			compiledTemplate, compileErr := templating.CompileTemplate(substitution.Example())
+			Expect(compileErr).NotTo(HaveOccurred())
+
+			err := substitution.ValidateTemplate(compiledTemplate)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("ValidateTemplate", func() {
+		It("is invalid for a template without placeholders", func() {
+			substitution := targetedretries.TestsFileSubstitution{
+				FileSystem:   mockFileSystem,
+				Substitution: targetedretries.PythonPytestSubstitution{},
+			}
+			compiledTemplate, compileErr := templating.CompileTemplate("pytest")
+			Expect(compileErr).NotTo(HaveOccurred())
+
+			err := substitution.ValidateTemplate(compiledTemplate)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("no keywords were found"))
+		})
+
+		It("is invalid for a template with additional placeholders", func() {
+			substitution := targetedretries.TestsFileSubstitution{
+				FileSystem:   mockFileSystem,
+				Substitution: targetedretries.PythonPytestSubstitution{},
+			}
+			compiledTemplate, compileErr := templating.CompileTemplate("pytest @{{ testsFile }} {{ tests }}")
+			Expect(compileErr).NotTo(HaveOccurred())
+
+			err := substitution.ValidateTemplate(compiledTemplate)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("only the 'testsFile' keyword; these were found"))
+		})
+
+		It("is invalid for a framework without a list of tests", func() {
+			substitution := targetedretries.TestsFileSubstitution{
+				FileSystem:   mockFileSystem,
+				Substitution: targetedretries.JSONSubstitution{FileSystem: mockFileSystem},
+			}
+			compiledTemplate, compileErr := templating.CompileTemplate("bin/your-script {{ testsFile }}")
+			Expect(compileErr).NotTo(HaveOccurred())
+
+			err := substitution.ValidateTemplate(compiledTemplate)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not supported by this test framework"))
+		})
+	})
+
+	Describe("SubstitutionsFor", func() {
+		It("writes the tests to a temporary file, one per line", func() {
+			compiledTemplate, compileErr := templating.CompileTemplate("pytest @{{ testsFile }}")
+			Expect(compileErr).NotTo(HaveOccurred())
+
+			id1 := "tests/test_file with spaces.py::test_a"
+			id2 := "tests/test_other.py::test_b"
+			id3 := "tests/test_other.py::test_c"
+			testResults := v1.TestResults{
+				Tests: []v1.Test{
+					{ID: &id1, Attempt: v1.TestAttempt{Status: v1.NewFailedTestStatus(nil, nil, nil)}},
+					{ID: &id2, Attempt: v1.TestAttempt{Status: v1.NewFailedTestStatus(nil, nil, nil)}},
+					{ID: &id3, Attempt: v1.TestAttempt{Status: v1.NewSuccessfulTestStatus()}},
+				},
+			}
+
+			substitution := targetedretries.TestsFileSubstitution{
+				FileSystem:   mockFileSystem,
+				Substitution: targetedretries.PythonPytestSubstitution{},
+			}
+			substitutions, err := substitution.SubstitutionsFor(
+				compiledTemplate,
+				testResults,
+				func(t v1.Test) bool { return t.Attempt.Status.ImpliesFailure() },
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(substitutions).To(Equal([]map[string]string{{"testsFile": "tests-temp-file"}}))
+			Expect(mockFile.String()).To(Equal(
+				"tests/test_file with spaces.py::test_a\ntests/test_other.py::test_b\n",
+			))
+		})
+	})
+
+	Describe("CleanUp", func() {
+		It("removes the temporary files", func() {
+			substitution := targetedretries.TestsFileSubstitution{
+				FileSystem:   mockFileSystem,
+				Substitution: targetedretries.PythonPytestSubstitution{},
+			}
+			err := substitution.CleanUp([]map[string]string{{"testsFile": "a"}, {"testsFile": "b"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removedFiles).To(Equal([]string{"a", "b"}))
+		})
+	})
+})
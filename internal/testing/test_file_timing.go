@@ -2,7 +2,10 @@ package testing
 
 import (
 	"fmt"
+	"strings"
 	"time"
+
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
 )
 
 // TestFileTiming is an estimated runtime duration for a test file based off of historical runs recorded by Captain
@@ -15,15 +18,37 @@ func (t TestFileTiming) String() string {
 	return fmt.Sprintf("'%s' (%s)", t.Filepath, t.Duration)
 }
 
+// TimingPath returns the path that the timing of a test counts towards. This is the file of the test or, for `go test`
+// results without a location, the import path of its package. Subtests are skipped in the latter case, since their
+// durations are already part of the duration of their parent test.
+func TimingPath(test v1.Test) (string, bool) {
+	if test.Location != nil {
+		return test.Location.File, true
+	}
+
+	importPath, ok := test.Attempt.Meta["package"].(string)
+	if !ok || strings.Contains(test.Name, "/") {
+		return "", false
+	}
+
+	return importPath, true
+}
+
 // FileTimingMatch represents the client file path matching the server test file timing.
 // We store the client file path alongside the timing so we can ensure to only run the file paths
 // originally provided by the client.
 type FileTimingMatch struct {
 	FileTiming     TestFileTiming
 	ClientFilepath string
+	// Estimated is set when there is no historical timing for the test file and its duration is only an estimate
+	Estimated bool
 }
 
 func (m FileTimingMatch) String() string {
+	if m.Estimated {
+		return fmt.Sprintf("'%s' (%s, estimated)", m.ClientFilepath, m.FileTiming.Duration)
+	}
+
 	return fmt.Sprintf("'%s' (%s)", m.ClientFilepath, m.FileTiming.Duration)
 }
 
@@ -9,6 +9,8 @@ type TestPartition struct {
 	Index         int
 	Runtime       time.Duration
 	TestFilePaths []string
+	// Tests are the individual tests of split test files that are assigned to this partition
+	Tests []TestTimingMatch
 }
 
 func (p TestPartition) Add(matchedTiming FileTimingMatch) TestPartition {
@@ -22,6 +24,17 @@ func (p TestPartition) AddFilePath(filepath string) TestPartition {
 	return p
 }
 
+func (p TestPartition) AddTest(matchedTiming TestTimingMatch) TestPartition {
+	p.Tests = append(p.Tests, matchedTiming)
+	p.Runtime += matchedTiming.Duration()
+	return p
+}
+
+// IsEmpty returns whether neither test files nor individual tests are assigned to the partition
+func (p TestPartition) IsEmpty() bool {
+	return len(p.TestFilePaths) == 0 && len(p.Tests) == 0
+}
+
 func (p TestPartition) String() string {
 	return fmt.Sprintf("[PART %d (%0.2fs)]", p.Index, p.Runtime.Seconds())
 }
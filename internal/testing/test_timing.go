@@ -0,0 +1,80 @@
+package testing
+
+import (
+	"fmt"
+	"time"
+
+	v1 "github.com/rwx-research/captain-cli/internal/testingschema/v1"
+)
+
+// TestTiming is an estimated runtime duration for a single test based off of historical runs recorded by Captain. It
+// keeps enough of the test's identity to select the test again, e.g. by its RSpec ID or pytest node ID.
+type TestTiming struct {
+	ID       string        `json:"id,omitempty" yaml:"id,omitempty"`
+	Name     string        `json:"name" yaml:"name"`
+	Lineage  []string      `json:"lineage,omitempty" yaml:"lineage,omitempty"`
+	Filepath string        `json:"file_path" yaml:"file"`
+	Duration time.Duration `json:"duration_in_nanoseconds" yaml:"duration"`
+}
+
+// NewTestTiming returns the timing of a test, or false if the test doesn't have a location or duration
+func NewTestTiming(test v1.Test) (TestTiming, bool) {
+	if test.Location == nil || test.Attempt.Duration == nil {
+		return TestTiming{}, false
+	}
+
+	testTiming := TestTiming{
+		Name:     test.Name,
+		Lineage:  test.Lineage,
+		Filepath: test.Location.File,
+		Duration: *test.Attempt.Duration,
+	}
+	if test.ID != nil {
+		testTiming.ID = *test.ID
+	}
+
+	return testTiming, true
+}
+
+// Key identifies the test within the test suite
+func (t TestTiming) Key() string {
+	if t.ID != "" {
+		return fmt.Sprintf("%s\x00%s", t.Filepath, t.ID)
+	}
+
+	return fmt.Sprintf("%s\x00%s", t.Filepath, t.Name)
+}
+
+func (t TestTiming) String() string {
+	return fmt.Sprintf("'%s' in '%s' (%s)", t.Name, t.Filepath, t.Duration)
+}
+
+// TestTimingMatch represents a test timing of a test file that the client provided.
+type TestTimingMatch struct {
+	TestTiming     TestTiming
+	ClientFilepath string
+}
+
+func (m TestTimingMatch) String() string {
+	return fmt.Sprintf("'%s' in '%s' (%s)", m.TestTiming.Name, m.ClientFilepath, m.TestTiming.Duration)
+}
+
+func (m TestTimingMatch) Duration() time.Duration {
+	return m.TestTiming.Duration
+}
+
+// Test returns the test as if it was reported in test results, so that it can be selected by the substitutions of
+// targeted retries.
+func (m TestTimingMatch) Test() v1.Test {
+	test := v1.Test{
+		Name:     m.TestTiming.Name,
+		Lineage:  m.TestTiming.Lineage,
+		Location: &v1.Location{File: m.ClientFilepath},
+	}
+	if m.TestTiming.ID != "" {
+		id := m.TestTiming.ID
+		test.ID = &id
+	}
+
+	return test
+}
@@ -96,6 +96,9 @@ func (s TestStatus) PotentiallyFlaky() bool {
 	return s.Kind == TestStatusFailed || s.Kind == TestStatusTimedOut
 }
 
+// LogPathTag is the tag that refers to the log file of the command that ran a test attempt
+const LogPathTag = "logPath"
+
 type TestAttempt struct {
 	Duration   *time.Duration `json:"durationInNanoseconds"`
 	Meta       map[string]any `json:"meta,omitempty"`
@@ -170,6 +173,17 @@ func (t Test) Tag(key string, value any) Test {
 	return t
 }
 
+// Tagged returns the value of a tag that was set on the attempt through `Test.Tag`
+func (a TestAttempt) Tagged(key string) (any, bool) {
+	rwxMeta, ok := a.Meta["__rwx"].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	value, ok := rwxMeta[key]
+	return value, ok
+}
+
 func (t Test) Matches(other Test) bool {
 	return t.IdentityForMatching() == other.IdentityForMatching()
 }
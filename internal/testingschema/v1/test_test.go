@@ -342,6 +342,24 @@ var _ = Describe("Test", func() {
 		})
 	})
 
+	Describe("Tagged", func() {
+		It("returns the value of a tag", func() {
+			test := v1.Test{Attempt: v1.TestAttempt{Meta: map[string]any{"foo": "bar"}}}.Tag("some-key", "value")
+
+			value, ok := test.Attempt.Tagged("some-key")
+			Expect(ok).To(BeTrue())
+			Expect(value).To(Equal("value"))
+		})
+
+		It("does not return a value for missing tags", func() {
+			_, ok := v1.TestAttempt{}.Tagged("some-key")
+			Expect(ok).To(BeFalse())
+
+			_, ok = v1.TestAttempt{Meta: map[string]any{"__rwx": true}}.Tagged("some-key")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
 	Describe("Matches", func() {
 		It("matches when the top-level fields are the same", func() {
 			scope1_1 := "scope1"